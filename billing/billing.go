@@ -0,0 +1,179 @@
+// Package billing works out what a subscription costs month by month,
+// honouring its trial and promotional phases, its billing period and the
+// price changes scheduled for it.
+package billing
+
+import (
+	"testTaskEffectiveMobile/models"
+	"time"
+)
+
+type Phase string
+
+const (
+	PhaseTrial   Phase = "trial"
+	PhasePromo   Phase = "promo"
+	PhaseRegular Phase = "regular"
+)
+
+// Plan is the pricing of one subscription: TrialMonths at TrialPrice, then
+// PromoMonths at PromoPrice, then Price until the last month. Trial and promo
+// months are charged one by one; the regular price is charged every
+// PeriodMonths months from the first regular month on.
+type Plan struct {
+	Start models.MonthYearDate
+	// End is the last paid month, nil for an open-ended subscription.
+	End         *models.MonthYearDate
+	Price       int
+	TrialMonths int
+	TrialPrice  int
+	PromoMonths int
+	PromoPrice  int
+	// PeriodMonths is how many months one regular charge pays for; 0 means 1.
+	PeriodMonths int
+	// PriceChanges replace Price from their month on, in any order. A charge
+	// is made at the price in effect in the month it falls in.
+	PriceChanges []models.PriceChange
+}
+
+func PlanOf(s models.Subscription) Plan {
+	return Plan{
+		Start:       s.StartDate,
+		End:         s.EndDate,
+		Price:       s.Price,
+		TrialMonths: s.TrialMonths,
+		TrialPrice:  s.TrialPrice,
+		PromoMonths: s.PromoMonths,
+		PromoPrice:  s.PromoPrice,
+		// An unknown period is rejected on input; 0 falls back to monthly.
+		PeriodMonths: models.BillingPeriodMonths(s.BillingPeriod),
+		PriceChanges: s.PriceChanges,
+	}
+}
+
+// Month numbers months consecutively so that phases are plain integer ranges.
+// Months are stored as their first instant in UTC, so t is read in UTC too.
+func Month(t time.Time) int {
+	t = t.UTC()
+	return t.Year()*12 + int(t.Month()) - 1
+}
+
+// FirstDay is the first day of the month numbered m, in UTC.
+func FirstDay(m int) time.Time {
+	return time.Date(m/12, time.Month(m%12+1), 1, 0, 0, 0, 0, time.UTC)
+}
+
+type phaseRange struct {
+	phase       Phase
+	first, last int
+	price       int
+}
+
+// phases returns the non-empty phases of the plan in order; the regular one
+// is open-ended up to the subscription's last month.
+func (p Plan) phases() []phaseRange {
+	start := Month(p.Start.Time)
+	last := int(^uint(0) >> 1)
+	if p.End != nil {
+		last = Month(p.End.Time)
+	}
+	trialEnd := start + p.TrialMonths
+	promoEnd := trialEnd + p.PromoMonths
+	all := []phaseRange{
+		{PhaseTrial, start, trialEnd - 1, p.TrialPrice},
+		{PhasePromo, trialEnd, promoEnd - 1, p.PromoPrice},
+		{PhaseRegular, promoEnd, last, p.Price},
+	}
+	var phases []phaseRange
+	for _, ph := range all {
+		ph.last = min(ph.last, last)
+		if ph.first <= ph.last {
+			phases = append(phases, ph)
+		}
+	}
+	return phases
+}
+
+// PriceAt returns the price charged in month and the phase it falls in; ok
+// is false when nothing is charged then, because the subscription is not
+// active or an earlier charge of its billing period covers the month.
+func (p Plan) PriceAt(month time.Time) (price int, phase Phase, ok bool) {
+	p.each(month, month, func(c Charge) {
+		price, phase, ok = c.Price, c.Phase, true
+	})
+	return price, phase, ok
+}
+
+// Cost sums the charges falling in the months from..to, both included.
+func (p Plan) Cost(from, to time.Time) int64 {
+	var total int64
+	p.each(from, to, func(c Charge) {
+		total += int64(c.Price)
+	})
+	return total
+}
+
+// Charge is one payment, made on the first day of Month for Months months.
+type Charge struct {
+	Month  models.MonthYearDate
+	Price  int
+	Phase  Phase
+	Months int
+}
+
+// PaidThrough is the last month the charge pays for.
+func (c Charge) PaidThrough() models.MonthYearDate {
+	return models.MonthYearDate{Time: FirstDay(Month(c.Month.Time) + c.Months - 1)}
+}
+
+// Charges lists the payments falling in the months from..to in order; they
+// add up to Cost over the same months.
+func (p Plan) Charges(from, to time.Time) []Charge {
+	var charges []Charge
+	p.each(from, to, func(c Charge) {
+		charges = append(charges, c)
+	})
+	return charges
+}
+
+// each calls fn with every charge falling in the months from..to, in order.
+func (p Plan) each(from, to time.Time, fn func(Charge)) {
+	lo, hi := Month(from), Month(to)
+	for _, ph := range p.phases() {
+		step := 1
+		if ph.phase == PhaseRegular {
+			step = max(p.PeriodMonths, 1)
+		}
+		m := ph.first
+		if m < lo {
+			// Skip to the first charge on or after lo.
+			m += (lo - m + step - 1) / step * step
+		}
+		for ; m <= min(ph.last, hi); m += step {
+			price := ph.price
+			if ph.phase == PhaseRegular {
+				price = p.regularPrice(m)
+			}
+			fn(Charge{Month: models.MonthYearDate{Time: FirstDay(m)}, Price: price, Phase: ph.phase, Months: step})
+		}
+	}
+}
+
+// regularPrice is Price as changed by the latest change made by month m.
+func (p Plan) regularPrice(m int) int {
+	price, changed := p.Price, -1
+	for _, c := range p.PriceChanges {
+		if cm := Month(c.Month.Time); cm <= m && cm > changed {
+			price, changed = c.Price, cm
+		}
+	}
+	return price
+}
+
+// TrialEnd returns the last month of the trial; ok is false without one.
+func (p Plan) TrialEnd() (month models.MonthYearDate, ok bool) {
+	if p.TrialMonths <= 0 {
+		return models.MonthYearDate{}, false
+	}
+	return models.MonthYearDate{Time: FirstDay(Month(p.Start.Time) + p.TrialMonths - 1)}, true
+}
@@ -0,0 +1,203 @@
+package billing
+
+import (
+	"fmt"
+	"slices"
+	"testTaskEffectiveMobile/models"
+	"testing"
+	"time"
+)
+
+func month(s string) models.MonthYearDate {
+	m, err := models.ParseMonthYearDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func monthPtr(s string) *models.MonthYearDate {
+	m := month(s)
+	return &m
+}
+
+// trialPromo is a plan starting in January 2025 with two free trial months,
+// three promo months at 299 and 999 from June on.
+var trialPromo = Plan{Start: month("01-2025"), Price: 999, TrialMonths: 2, PromoMonths: 3, PromoPrice: 299}
+
+func TestPriceAt(t *testing.T) {
+	ended := trialPromo
+	ended.End = monthPtr("07-2025")
+	tests := []struct {
+		plan      Plan
+		month     string
+		wantPrice int
+		wantPhase Phase
+		wantOK    bool
+	}{
+		{trialPromo, "12-2024", 0, "", false},
+		{trialPromo, "01-2025", 0, PhaseTrial, true},
+		{trialPromo, "02-2025", 0, PhaseTrial, true},
+		{trialPromo, "03-2025", 299, PhasePromo, true},
+		{trialPromo, "05-2025", 299, PhasePromo, true},
+		{trialPromo, "06-2025", 999, PhaseRegular, true},
+		{trialPromo, "06-2035", 999, PhaseRegular, true},
+		{ended, "07-2025", 999, PhaseRegular, true},
+		{ended, "08-2025", 0, "", false},
+		// A subscription ending during its trial never reaches the later phases.
+		{Plan{Start: month("01-2025"), End: monthPtr("01-2025"), Price: 999, TrialMonths: 2, PromoMonths: 1, PromoPrice: 1}, "02-2025", 0, "", false},
+		{Plan{Start: month("01-2025"), Price: 999, PromoMonths: 1, PromoPrice: 499}, "01-2025", 499, PhasePromo, true},
+		{Plan{Start: month("01-2025"), Price: 999, TrialMonths: 1, TrialPrice: 1}, "01-2025", 1, PhaseTrial, true},
+		// Between the charges of a longer billing period nothing is charged.
+		{quarterly, "06-2025", 2997, PhaseRegular, true},
+		{quarterly, "07-2025", 0, "", false},
+		{quarterly, "09-2025", 3297, PhaseRegular, true},
+	}
+	for _, tt := range tests {
+		price, phase, ok := tt.plan.PriceAt(month(tt.month).Time)
+		if price != tt.wantPrice || phase != tt.wantPhase || ok != tt.wantOK {
+			t.Errorf("%+v PriceAt(%s) = %d, %q, %v; want %d, %q, %v",
+				tt.plan, tt.month, price, phase, ok, tt.wantPrice, tt.wantPhase, tt.wantOK)
+		}
+	}
+}
+
+// quarterly follows the trial and promo of trialPromo with a quarterly
+// regular price of 2997, raised to 3297 in August 2025.
+var quarterly = Plan{Start: month("01-2025"), Price: 2997, TrialMonths: 2, PromoMonths: 3, PromoPrice: 299,
+	PeriodMonths: 3, PriceChanges: []models.PriceChange{{Month: month("08-2025"), Price: 3297}}}
+
+func TestCost(t *testing.T) {
+	flat := Plan{Start: month("01-2025"), Price: 500}
+	ended := trialPromo
+	ended.End = monthPtr("07-2025")
+	tests := []struct {
+		name     string
+		plan     Plan
+		from, to string
+		want     int64
+	}{
+		{"flat open-ended", flat, "01-2025", "12-2025", 12 * 500},
+		{"flat before start", flat, "06-2024", "02-2025", 2 * 500},
+		{"flat far ahead", flat, "01-2030", "03-2030", 3 * 500},
+		{"whole trial", trialPromo, "01-2025", "02-2025", 0},
+		{"trial into promo", trialPromo, "02-2025", "03-2025", 299},
+		{"all phases", trialPromo, "01-2025", "12-2025", 3*299 + 7*999},
+		{"from mid-promo", trialPromo, "04-2025", "07-2025", 2*299 + 2*999},
+		{"last promo month only", trialPromo, "05-2025", "05-2025", 299},
+		{"first regular month only", trialPromo, "06-2025", "06-2025", 999},
+		{"ended within range", ended, "04-2025", "12-2025", 2*299 + 2*999},
+		{"range after end", ended, "08-2025", "12-2025", 0},
+		{"range before start", trialPromo, "01-2024", "12-2024", 0},
+		{"reversed range", flat, "06-2025", "01-2025", 0},
+		{"quarterly charge month", quarterly, "06-2025", "06-2025", 2997},
+		{"between quarterly charges", quarterly, "07-2025", "08-2025", 0},
+		{"quarterly from the start", quarterly, "01-2025", "12-2025", 3*299 + 2997 + 3297 + 3297},
+		{"quarterly ended mid-period", endedQuarterly, "06-2025", "12-2025", 2997 + 3297},
+		{"yearly", Plan{Start: month("03-2025"), Price: 9999, PeriodMonths: 12}, "01-2025", "12-2026", 2 * 9999},
+		{"yearly not yet renewed", Plan{Start: month("03-2025"), Price: 9999, PeriodMonths: 12}, "04-2025", "02-2026", 0},
+		{"price change", flat.withChanges("04-2025", 600), "01-2025", "06-2025", 3*500 + 3*600},
+		{"changes in any order", flat.withChanges("06-2025", 700, "03-2025", 600), "01-2025", "07-2025", 2*500 + 3*600 + 2*700},
+		{"change before the range", flat.withChanges("03-2024", 400), "01-2025", "02-2025", 2 * 400},
+		{"change during promo", trialPromo.withChanges("04-2025", 1099), "05-2025", "07-2025", 299 + 2*1099},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := month(tt.from).Time, month(tt.to).Time
+			if got := tt.plan.Cost(from, to); got != tt.want {
+				t.Errorf("Cost(%s, %s) = %d, want %d", tt.from, tt.to, got, tt.want)
+			}
+			var sum int64
+			for _, c := range tt.plan.Charges(from, to) {
+				sum += int64(c.Price)
+			}
+			if sum != tt.want {
+				t.Errorf("Charges(%s, %s) add up to %d, want %d", tt.from, tt.to, sum, tt.want)
+			}
+		})
+	}
+}
+
+// endedQuarterly is quarterly ending in October 2025, a month into the
+// quarter it has already paid for.
+var endedQuarterly = func() Plan {
+	p := quarterly
+	p.End = monthPtr("10-2025")
+	return p
+}()
+
+// withChanges returns the plan with price changes given as month, price pairs.
+func (p Plan) withChanges(changes ...any) Plan {
+	p.PriceChanges = nil
+	for i := 0; i < len(changes); i += 2 {
+		p.PriceChanges = append(p.PriceChanges, models.PriceChange{Month: month(changes[i].(string)), Price: changes[i+1].(int)})
+	}
+	return p
+}
+
+func TestCharges(t *testing.T) {
+	got := trialPromo.Charges(month("02-2025").Time, month("07-2025").Time)
+	want := []string{
+		"02-2025 0 trial",
+		"03-2025 299 promo",
+		"04-2025 299 promo",
+		"05-2025 299 promo",
+		"06-2025 999 regular",
+		"07-2025 999 regular",
+	}
+	var charges []string
+	for _, c := range got {
+		charges = append(charges, fmt.Sprintf("%s %d %s", c.Month.MonthYear(), c.Price, c.Phase))
+		if c.Month.Day() != 1 || c.Month.Location() != time.UTC {
+			t.Errorf("charge month %s is not the first day in UTC", c.Month.Time)
+		}
+	}
+	if !slices.Equal(charges, want) {
+		t.Errorf("Charges = %q, want %q", charges, want)
+	}
+}
+
+func TestChargesOfALongerPeriod(t *testing.T) {
+	got := endedQuarterly.Charges(month("04-2025").Time, month("12-2025").Time)
+	want := []string{
+		"04-2025 299 promo 1 through 04-2025",
+		"05-2025 299 promo 1 through 05-2025",
+		"06-2025 2997 regular 3 through 08-2025",
+		"09-2025 3297 regular 3 through 11-2025",
+	}
+	var charges []string
+	for _, c := range got {
+		charges = append(charges, fmt.Sprintf("%s %d %s %d through %s", c.Month.MonthYear(), c.Price, c.Phase, c.Months, c.PaidThrough().MonthYear()))
+	}
+	if !slices.Equal(charges, want) {
+		t.Errorf("Charges = %q, want %q", charges, want)
+	}
+}
+
+func TestTrialEnd(t *testing.T) {
+	tests := []struct {
+		plan   Plan
+		want   string
+		wantOK bool
+	}{
+		{trialPromo, "02-2025", true},
+		{Plan{Start: month("11-2024"), Price: 999, TrialMonths: 3}, "01-2025", true},
+		{Plan{Start: month("01-2025"), Price: 999, TrialMonths: 1}, "01-2025", true},
+		{Plan{Start: month("01-2025"), Price: 999}, "", false},
+		{Plan{Start: month("01-2025"), Price: 999, PromoMonths: 2, PromoPrice: 1}, "", false},
+	}
+	for _, tt := range tests {
+		end, ok := tt.plan.TrialEnd()
+		if ok != tt.wantOK || (ok && end.MonthYear() != tt.want) {
+			t.Errorf("%+v TrialEnd = %s, %v; want %s, %v", tt.plan, end.MonthYear(), ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestMonthIgnoresTimeZone(t *testing.T) {
+	// Midnight of February 1 in Moscow is still January in UTC.
+	msk := time.Date(2025, 2, 1, 0, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	if got := FirstDay(Month(msk)); !got.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("FirstDay(Month(%s)) = %s, want 2025-01-01", msk, got)
+	}
+}
@@ -0,0 +1,36 @@
+package billing
+
+import (
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"time"
+)
+
+// Schedule expands the subscriptions into their charges over the months
+// from..to. Month and period totals equal Cost over the same months.
+func Schedule(subs []dto.SubscriptionDTO, from, to models.MonthYearDate) dto.ScheduleDTO {
+	first, last := Month(from.Time), Month(to.Time)
+	schedule := dto.ScheduleDTO{From: from, To: to, Months: make([]dto.ScheduleMonth, 0, max(last-first+1, 0))}
+	for m := first; m <= last; m++ {
+		schedule.Months = append(schedule.Months, dto.ScheduleMonth{
+			Month:   models.MonthYearDate{Time: FirstDay(m)},
+			Charges: []dto.ChargeDTO{},
+		})
+	}
+	for _, s := range subs {
+		for _, c := range PlanOf(s.Subscription).Charges(from.Time, to.Time) {
+			month := &schedule.Months[Month(c.Month.Time)-first]
+			month.Charges = append(month.Charges, dto.ChargeDTO{
+				SubscriptionID: s.Id,
+				ServiceName:    s.ServiceName,
+				Date:           c.Month.Format(time.DateOnly),
+				Price:          c.Price,
+				Phase:          string(c.Phase),
+				PaidThrough:    c.PaidThrough(),
+			})
+			month.Total += int64(c.Price)
+			schedule.Total += int64(c.Price)
+		}
+	}
+	return schedule
+}
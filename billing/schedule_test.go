@@ -0,0 +1,80 @@
+package billing
+
+import (
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"testing"
+)
+
+func TestSchedule(t *testing.T) {
+	subs := []dto.SubscriptionDTO{
+		{Id: 1, Subscription: models.Subscription{ServiceName: "Netflix", StartDate: month("01-2025"), Price: 999,
+			TrialMonths: 2, PromoMonths: 3, PromoPrice: 299, PriceChanges: []models.PriceChange{{Month: month("07-2025"), Price: 1099}}}},
+		{Id: 2, Subscription: models.Subscription{ServiceName: "Spotify", StartDate: month("05-2025"), Price: 1990,
+			BillingPeriod: models.BillingYearly}},
+		{Id: 3, Subscription: models.Subscription{ServiceName: "Okko", StartDate: month("05-2025"), EndDate: monthPtr("05-2025"), Price: 199}},
+	}
+	from, to := month("04-2025"), month("07-2025")
+	s := Schedule(subs, from, to)
+
+	wantMonths := []struct {
+		month   string
+		total   int64
+		charges []dto.ChargeDTO
+	}{
+		{"04-2025", 299, []dto.ChargeDTO{
+			{SubscriptionID: 1, ServiceName: "Netflix", Date: "2025-04-01", Price: 299, Phase: "promo", PaidThrough: month("04-2025")},
+		}},
+		{"05-2025", 299 + 1990 + 199, []dto.ChargeDTO{
+			{SubscriptionID: 1, ServiceName: "Netflix", Date: "2025-05-01", Price: 299, Phase: "promo", PaidThrough: month("05-2025")},
+			{SubscriptionID: 2, ServiceName: "Spotify", Date: "2025-05-01", Price: 1990, Phase: "regular", PaidThrough: month("04-2026")},
+			{SubscriptionID: 3, ServiceName: "Okko", Date: "2025-05-01", Price: 199, Phase: "regular", PaidThrough: month("05-2025")},
+		}},
+		{"06-2025", 999, []dto.ChargeDTO{
+			{SubscriptionID: 1, ServiceName: "Netflix", Date: "2025-06-01", Price: 999, Phase: "regular", PaidThrough: month("06-2025")},
+		}},
+		{"07-2025", 1099, []dto.ChargeDTO{
+			{SubscriptionID: 1, ServiceName: "Netflix", Date: "2025-07-01", Price: 1099, Phase: "regular", PaidThrough: month("07-2025")},
+		}},
+	}
+	if len(s.Months) != len(wantMonths) {
+		t.Fatalf("got %d months, want %d", len(s.Months), len(wantMonths))
+	}
+	for i, want := range wantMonths {
+		got := s.Months[i]
+		if got.Month.MonthYear() != want.month || got.Total != want.total {
+			t.Errorf("month %d = %s total %d, want %s total %d", i, got.Month.MonthYear(), got.Total, want.month, want.total)
+		}
+		if len(got.Charges) != len(want.charges) {
+			t.Errorf("%s charges = %+v, want %+v", want.month, got.Charges, want.charges)
+			continue
+		}
+		for j := range want.charges {
+			if got.Charges[j] != want.charges[j] {
+				t.Errorf("%s charge %d = %+v, want %+v", want.month, j, got.Charges[j], want.charges[j])
+			}
+		}
+	}
+
+	// The schedule agrees with the cost calculation of /calculate.
+	var cost int64
+	for _, sub := range subs {
+		cost += PlanOf(sub.Subscription).Cost(from.Time, to.Time)
+	}
+	if s.Total != cost || s.Total != 299+299+1990+199+999+1099 {
+		t.Errorf("total = %d, want %d, the cost of the same months", s.Total, cost)
+	}
+}
+
+func TestScheduleKeepsEmptyMonths(t *testing.T) {
+	s := Schedule(nil, month("11-2025"), month("02-2026"))
+	want := []string{"11-2025", "12-2025", "01-2026", "02-2026"}
+	if len(s.Months) != len(want) || s.Total != 0 {
+		t.Fatalf("schedule = %+v, want %d empty months", s, len(want))
+	}
+	for i, m := range s.Months {
+		if m.Month.MonthYear() != want[i] || m.Total != 0 || m.Charges == nil || len(m.Charges) != 0 {
+			t.Errorf("month %d = %+v, want empty %s with a non-nil charge list", i, m, want[i])
+		}
+	}
+}
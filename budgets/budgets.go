@@ -0,0 +1,162 @@
+// Package budgets compares monthly budgets with what the subscriptions cost
+// and raises an alert the first time spending crosses a threshold in a month.
+package budgets
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Thresholds are the alert levels in percent of the monthly limit.
+var Thresholds = []int{80, 100}
+
+// Alert reports that a budget reached Threshold percent of its limit in Month.
+type Alert struct {
+	BudgetID    int                  `json:"budget_id"`
+	UserID      uuid.UUID            `json:"user_id"`
+	Month       models.MonthYearDate `json:"month"`
+	Threshold   int                  `json:"threshold"`
+	Limit       int                  `json:"monthly_limit"`
+	Spent       int64                `json:"spent"`
+	Category    *string              `json:"category,omitempty"`
+	ServiceName *string              `json:"service_name,omitempty"`
+}
+
+// Notifier delivers alerts. An error makes the evaluator raise the alert
+// again on its next run.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// LogNotifier writes alerts to the log.
+type LogNotifier struct {
+	Logger *slog.Logger
+}
+
+func (n LogNotifier) Notify(ctx context.Context, a Alert) error {
+	n.Logger.WarnContext(ctx, "budget threshold reached",
+		"budget_id", a.BudgetID,
+		"user_id", a.UserID,
+		"month", a.Month.MonthYear(),
+		"threshold", a.Threshold,
+		"monthly_limit", a.Limit,
+		"spent", a.Spent)
+	return nil
+}
+
+// Store is the budget persistence; repositories.BudgetsRepository implements it.
+type Store interface {
+	List(ctx context.Context, userID *uuid.UUID) ([]dto.BudgetDTO, error)
+	RecordAlert(ctx context.Context, budgetID int, month models.MonthYearDate, threshold int) (bool, error)
+	ForgetAlert(ctx context.Context, budgetID int, month models.MonthYearDate, threshold int) error
+}
+
+// Calculator returns what subscriptions cost, as SubscriptionsRepository.CalculateSum.
+type Calculator func(ctx context.Context, calcDto dto.CalculationRequestDTO) (int64, error)
+
+type Evaluator struct {
+	Store     Store
+	Calculate Calculator
+	Notifier  Notifier
+	Logger    *slog.Logger
+}
+
+// Run evaluates the current month right away and then every interval until
+// ctx is done.
+func (e *Evaluator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now().UTC()
+		month := models.MonthYearDate{Time: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)}
+		if n, err := e.Evaluate(ctx, month); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			e.Logger.Error("budget evaluation failed", "error", err, "alerts", n)
+		} else if n > 0 {
+			e.Logger.Info("budgets evaluated", "alerts", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Spent is what the subscriptions within the budget cost in month.
+func (e *Evaluator) Spent(ctx context.Context, b dto.BudgetDTO, month models.MonthYearDate) (int64, error) {
+	return e.Calculate(ctx, dto.CalculationRequestDTO{
+		UserID:      &b.UserId,
+		ServiceName: b.ServiceName,
+		Category:    b.Category,
+		StartDate:   month,
+		EndDate:     month,
+	})
+}
+
+// Evaluate checks every budget for month and returns how many alerts were
+// delivered. A failing budget does not stop the others; their errors are
+// joined.
+func (e *Evaluator) Evaluate(ctx context.Context, month models.MonthYearDate) (delivered int, err error) {
+	all, err := e.Store.List(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	for _, b := range all {
+		n, err := e.evaluate(ctx, b, month)
+		delivered += n
+		if err != nil {
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	return delivered, errors.Join(errs...)
+}
+
+func (e *Evaluator) evaluate(ctx context.Context, b dto.BudgetDTO, month models.MonthYearDate) (delivered int, err error) {
+	spent, err := e.Spent(ctx, b, month)
+	if err != nil {
+		return 0, err
+	}
+	for _, threshold := range Thresholds {
+		if spent*100 < int64(b.MonthlyLimit)*int64(threshold) {
+			break
+		}
+		recorded, err := e.Store.RecordAlert(ctx, b.Id, month, threshold)
+		if err != nil {
+			return delivered, err
+		}
+		if !recorded {
+			continue
+		}
+		alert := Alert{
+			BudgetID:    b.Id,
+			UserID:      b.UserId,
+			Month:       month,
+			Threshold:   threshold,
+			Limit:       b.MonthlyLimit,
+			Spent:       spent,
+			Category:    b.Category,
+			ServiceName: b.ServiceName,
+		}
+		if err = e.Notifier.Notify(ctx, alert); err != nil {
+			if forgetErr := e.Store.ForgetAlert(context.WithoutCancel(ctx), b.Id, month, threshold); forgetErr != nil {
+				err = errors.Join(err, forgetErr)
+			}
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
@@ -0,0 +1,205 @@
+package budgets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// memStore keeps budgets and recorded alerts in memory.
+type memStore struct {
+	budgets   []dto.BudgetDTO
+	recorded  map[string]bool
+	forgotten []string
+	forgetErr error
+}
+
+func alertKey(budgetID int, month models.MonthYearDate, threshold int) string {
+	return fmt.Sprintf("%d/%s/%d", budgetID, month.MonthYear(), threshold)
+}
+
+func (s *memStore) List(context.Context, *uuid.UUID) ([]dto.BudgetDTO, error) {
+	return s.budgets, nil
+}
+
+func (s *memStore) RecordAlert(_ context.Context, budgetID int, month models.MonthYearDate, threshold int) (bool, error) {
+	key := alertKey(budgetID, month, threshold)
+	if s.recorded[key] {
+		return false, nil
+	}
+	s.recorded[key] = true
+	return true, nil
+}
+
+func (s *memStore) ForgetAlert(_ context.Context, budgetID int, month models.MonthYearDate, threshold int) error {
+	key := alertKey(budgetID, month, threshold)
+	s.forgotten = append(s.forgotten, key)
+	if s.forgetErr != nil {
+		return s.forgetErr
+	}
+	delete(s.recorded, key)
+	return nil
+}
+
+// recorder remembers delivered alerts and fails for the budgets in fail.
+type recorder struct {
+	alerts []string
+	fail   map[int]error
+}
+
+func (r *recorder) Notify(_ context.Context, a Alert) error {
+	if err := r.fail[a.BudgetID]; err != nil {
+		return err
+	}
+	r.alerts = append(r.alerts, alertKey(a.BudgetID, a.Month, a.Threshold))
+	return nil
+}
+
+func month(s string) models.MonthYearDate {
+	m, err := models.ParseMonthYearDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func budget(id, limit int) dto.BudgetDTO {
+	return dto.BudgetDTO{Id: id, Budget: models.Budget{UserId: uuid.New(), MonthlyLimit: limit}}
+}
+
+// spending makes a Calculator charging the budget user of each id the amount
+// given for it; a negative amount fails the calculation.
+func spending(budgets []dto.BudgetDTO, spent map[int]int64) Calculator {
+	return func(_ context.Context, calc dto.CalculationRequestDTO) (int64, error) {
+		for _, b := range budgets {
+			if b.UserId == *calc.UserID {
+				if spent[b.Id] < 0 {
+					return 0, fmt.Errorf("budget %d: database is down", b.Id)
+				}
+				return spent[b.Id], nil
+			}
+		}
+		return 0, nil
+	}
+}
+
+func TestEvaluateThresholds(t *testing.T) {
+	tests := []struct {
+		name  string
+		spent int64
+		want  []string
+	}{
+		{"well below", 500, nil},
+		{"just below 80%", 799, nil},
+		{"exactly 80%", 800, []string{"1/03-2025/80"}},
+		{"between", 999, []string{"1/03-2025/80"}},
+		{"exactly 100%", 1000, []string{"1/03-2025/80", "1/03-2025/100"}},
+		{"over", 5000, []string{"1/03-2025/80", "1/03-2025/100"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budgets := []dto.BudgetDTO{budget(1, 1000)}
+			notifier := &recorder{}
+			e := &Evaluator{
+				Store:     &memStore{budgets: budgets, recorded: map[string]bool{}},
+				Calculate: spending(budgets, map[int]int64{1: tt.spent}),
+				Notifier:  notifier,
+			}
+			n, err := e.Evaluate(context.Background(), month("03-2025"))
+			if err != nil || n != len(tt.want) || !slices.Equal(notifier.alerts, tt.want) {
+				t.Errorf("Evaluate = %d, %v, alerts %q; want %d, nil, %q", n, err, notifier.alerts, len(tt.want), tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateAlertsOncePerMonth(t *testing.T) {
+	budgets := []dto.BudgetDTO{budget(1, 1000)}
+	spent := map[int]int64{1: 850}
+	notifier := &recorder{}
+	e := &Evaluator{
+		Store:     &memStore{budgets: budgets, recorded: map[string]bool{}},
+		Calculate: spending(budgets, spent),
+		Notifier:  notifier,
+	}
+	runs := []struct {
+		month string
+		spent int64
+		want  int
+	}{
+		{"03-2025", 850, 1},
+		{"03-2025", 850, 0},  // already alerted at 80%
+		{"03-2025", 1200, 1}, // crosses 100%
+		{"03-2025", 1200, 0},
+		{"04-2025", 1200, 2}, // a new month alerts again
+	}
+	for i, run := range runs {
+		spent[1] = run.spent
+		n, err := e.Evaluate(context.Background(), month(run.month))
+		if err != nil || n != run.want {
+			t.Errorf("run %d (%s, spent %d) = %d, %v; want %d, nil", i+1, run.month, run.spent, n, err, run.want)
+		}
+	}
+	want := []string{"1/03-2025/80", "1/03-2025/100", "1/04-2025/80", "1/04-2025/100"}
+	if !slices.Equal(notifier.alerts, want) {
+		t.Errorf("alerts = %q, want %q", notifier.alerts, want)
+	}
+}
+
+func TestEvaluateRetriesFailedNotifications(t *testing.T) {
+	budgets := []dto.BudgetDTO{budget(1, 1000), budget(2, 1000)}
+	errSMTP := errors.New("smtp: connection refused")
+	store := &memStore{budgets: budgets, recorded: map[string]bool{}}
+	notifier := &recorder{fail: map[int]error{1: errSMTP}}
+	e := &Evaluator{Store: store, Calculate: spending(budgets, map[int]int64{1: 900, 2: 900}), Notifier: notifier}
+
+	n, err := e.Evaluate(context.Background(), month("03-2025"))
+	if !errors.Is(err, errSMTP) || n != 1 {
+		t.Fatalf("Evaluate = %d, %v; want 1 delivered and the notifier error", n, err)
+	}
+	if !slices.Equal(store.forgotten, []string{"1/03-2025/80"}) {
+		t.Errorf("forgotten = %q, want the undelivered alert", store.forgotten)
+	}
+
+	// The alert was forgotten, so the next run delivers it.
+	delete(notifier.fail, 1)
+	if n, err = e.Evaluate(context.Background(), month("03-2025")); err != nil || n != 1 {
+		t.Errorf("retry = %d, %v; want 1, nil", n, err)
+	}
+	want := []string{"2/03-2025/80", "1/03-2025/80"}
+	if !slices.Equal(notifier.alerts, want) {
+		t.Errorf("alerts = %q, want %q", notifier.alerts, want)
+	}
+}
+
+func TestEvaluateJoinsErrors(t *testing.T) {
+	budgets := []dto.BudgetDTO{budget(1, 1000), budget(2, 1000), budget(3, 1000)}
+	errSMTP := errors.New("smtp: connection refused")
+	errForget := errors.New("delete alert: connection reset")
+	store := &memStore{budgets: budgets, recorded: map[string]bool{}, forgetErr: errForget}
+	e := &Evaluator{
+		Store:     store,
+		Calculate: spending(budgets, map[int]int64{1: -1, 2: 1000, 3: 900}),
+		Notifier:  &recorder{fail: map[int]error{3: errSMTP}},
+	}
+
+	n, err := e.Evaluate(context.Background(), month("03-2025"))
+	if n != 2 {
+		t.Errorf("delivered = %d, want the two alerts of budget 2", n)
+	}
+	for _, want := range []error{errSMTP, errForget} {
+		if !errors.Is(err, want) {
+			t.Errorf("error %v does not wrap %v", err, want)
+		}
+	}
+	if err == nil || !strings.Contains(err.Error(), "budget 1: database is down") {
+		t.Errorf("error %v does not report the failed calculation of budget 1", err)
+	}
+}
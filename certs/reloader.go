@@ -0,0 +1,148 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves the server certificate and, for mTLS, the client CA bundle
+// from files, picking up changes on disk without a restart.
+type Reloader struct {
+	certFile, keyFile, caFile string
+	logger                    *slog.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes [3]time.Time
+}
+
+// NewReloader loads the files once; caFile may be empty when client certificates are not verified.
+func NewReloader(certFile, keyFile, caFile string, logger *slog.Logger) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile, logger: logger}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err = r.load(modTimes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) stat() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func (r *Reloader) load(modTimes [3]time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("load client CA bundle: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("load client CA bundle: no certificates found in " + r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// Run polls the files every interval and reloads them when any of them
+// changed. A broken update is logged and the previous certificates stay in use.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		modTimes, err := r.stat()
+		if err != nil {
+			r.logger.Error("stat TLS files: " + err.Error())
+			continue
+		}
+		r.mu.RLock()
+		changed := modTimes != r.modTimes
+		r.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err = r.load(modTimes); err != nil {
+			r.logger.Error("reload TLS files, keeping previous certificates: " + err.Error())
+			continue
+		}
+		r.logger.Info("TLS certificates reloaded")
+	}
+}
+
+// TLSConfig returns a server configuration that always uses the latest
+// certificates. With a client CA bundle every client must present a
+// certificate signed by it.
+func (r *Reloader) TLSConfig(minVersion uint16) *tls.Config {
+	base := &tls.Config{
+		MinVersion: minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+	}
+	if r.caFile == "" {
+		return base
+	}
+	base.ClientAuth = tls.RequireAndVerifyClientCert
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.ClientCAs = r.clientCA
+		return cfg, nil
+	}
+	return base
+}
+
+// ParseVersion maps "1.2" and "1.3" to their crypto/tls constants.
+func ParseVersion(v string) (uint16, error) {
+	switch v {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q, want 1.2 or 1.3", v)
+	}
+}
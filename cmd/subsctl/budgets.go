@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testTaskEffectiveMobile/budgets"
+	"testTaskEffectiveMobile/notifications"
+	"testTaskEffectiveMobile/postgres_db/repositories"
+	"time"
+)
+
+func runCheckBudgets(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("check-budgets", "")
+	month := fs.String("month", time.Now().UTC().Format("01-2006"), "month to check, MM-YYYY")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	m, err := parseMonth("month", *month)
+	if err != nil {
+		return err
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	logger := slog.New(slog.NewTextHandler(c.stderr, nil))
+	var notifier budgets.Notifier = budgets.LogNotifier{Logger: logger}
+	if smtp := c.smtpSender(); smtp != nil {
+		notifier = &notifications.BudgetNotifier{
+			Settings: (&repositories.NotificationsRepository{Db: c.db}).Get,
+			Sender:   smtp,
+			Logger:   logger,
+		}
+	}
+	evaluator := &budgets.Evaluator{
+		Store:     c.budgets,
+		Calculate: c.subscriptions.CalculateSum,
+		Notifier:  notifier,
+		Logger:    logger,
+	}
+	n, err := evaluator.Evaluate(ctx, m)
+	fmt.Fprintf(c.stdout, "%d alert(s) raised\n", n)
+	return err
+}
@@ -0,0 +1,602 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"testTaskEffectiveMobile/billing"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"testTaskEffectiveMobile/postgres_db/migrations"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errFlags is returned once the flag package has already reported the problem.
+var errFlags = errors.New("invalid flags")
+
+// usageError marks invalid arguments; the command exits with exitUsage.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+func usageErrorf(format string, args ...any) error {
+	return usageError(fmt.Sprintf(format, args...))
+}
+
+func (c *cli) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: subsctl %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parse(fs *flag.FlagSet, args []string, positional int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errFlags
+	}
+	if fs.NArg() != positional {
+		fs.Usage()
+		return errFlags
+	}
+	return nil
+}
+
+func runMigrate(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("migrate", "")
+	status := fs.Bool("status", false, "print the applied and expected schema versions without migrating")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+
+	if !*status {
+		if err := migrations.Apply(ctx, c.db); err != nil {
+			return err
+		}
+	}
+	current, err := migrations.Current(ctx, c.db)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "schema version %d, latest %d\n", current, migrations.Latest())
+	if *status && current < migrations.Latest() {
+		return fmt.Errorf("%d migration(s) pending", migrations.Latest()-current)
+	}
+	return nil
+}
+
+func runList(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("list", "USER_ID")
+	output := outputFlag(fs)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(fs.Arg(0))
+	if err != nil {
+		return usageErrorf("invalid user id %q", fs.Arg(0))
+	}
+	return c.search(ctx, *output, dto.SubscriptionFilter{UserID: &userID})
+}
+
+func runSearch(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("search", "")
+	output := outputFlag(fs)
+	var filter dto.SubscriptionFilter
+	filterFlags(fs, &filter)
+	fs.IntVar(&filter.Limit, "limit", 0, "return at most this many subscriptions, 0 is unlimited")
+	fs.IntVar(&filter.Offset, "offset", 0, "skip this many subscriptions")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	return c.search(ctx, *output, filter)
+}
+
+func (c *cli) search(ctx context.Context, output string, filter dto.SubscriptionFilter) error {
+	out, err := newPrinter(c.stdout, output)
+	if err != nil {
+		return err
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	var subs []dto.SubscriptionDTO
+	err = c.subscriptions.Search(ctx, filter, func(s dto.SubscriptionDTO) error {
+		subs = append(subs, s)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return out.subscriptions(subs)
+}
+
+func runShow(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("show", "ID")
+	output := outputFlag(fs)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	out, err := newPrinter(c.stdout, *output)
+	if err != nil {
+		return err
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	s, err := c.subscriptions.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return out.subscription(s)
+}
+
+func runCreate(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("create", "")
+	var f subscriptionFlags
+	f.register(fs)
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	var s models.Subscription
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	for _, required := range []string{"user", "service", "price", "start"} {
+		if !set[required] {
+			return usageErrorf("-%s is required", required)
+		}
+	}
+	if err := f.apply(&s, set); err != nil {
+		return err
+	}
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+	id, err := c.subscriptions.Insert(ctx, s)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, id)
+	return nil
+}
+
+func runUpdate(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("update", "ID")
+	var f subscriptionFlags
+	f.register(fs)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	if len(set) == 0 {
+		return usageErrorf("nothing to update")
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	current, err := c.subscriptions.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	s := current.Subscription
+	if err = f.apply(&s, set); err != nil {
+		return err
+	}
+	return c.subscriptions.Update(ctx, id, s)
+}
+
+func runDelete(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("delete", "ID")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	return c.subscriptions.Delete(ctx, id)
+}
+
+func runCalc(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("calc", "")
+	output := outputFlag(fs)
+	user := fs.String("user", "", "only subscriptions of this user id")
+	service := fs.String("service", "", "only subscriptions of this service (exact name)")
+	from := fs.String("from", "", "first month of the period, MM-YYYY (required)")
+	to := fs.String("to", "", "last month of the period, MM-YYYY (required)")
+	category := fs.String("category", "", "only subscriptions in this category, case-insensitive")
+	tags := fs.String("tags", "", "only subscriptions with any of these comma-separated tags")
+	allTags := fs.Bool("all-tags", false, "require all of the -tags instead of any")
+	groupBy := fs.String("group-by", "", "break the total down by category or tag")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *groupBy != "" && *groupBy != "category" && *groupBy != "tag" {
+		return usageErrorf("-group-by must be category or tag")
+	}
+
+	var req dto.CalculationRequestDTO
+	var err error
+	if *user != "" {
+		userID, err := uuid.Parse(*user)
+		if err != nil {
+			return usageErrorf("invalid -user %q", *user)
+		}
+		req.UserID = &userID
+	}
+	if *service != "" {
+		req.ServiceName = service
+	}
+	if *category != "" {
+		req.Category = category
+	}
+	req.Tags = splitTags(*tags)
+	if *allTags {
+		req.TagMode = "all"
+	}
+	req.GroupBy = *groupBy
+	if req.StartDate, err = parseMonth("from", *from); err != nil {
+		return err
+	}
+	if req.EndDate, err = parseMonth("to", *to); err != nil {
+		return err
+	}
+	if req.EndDate.Before(req.StartDate.Time) {
+		return usageErrorf("-to is before -from")
+	}
+	out, err := newPrinter(c.stdout, *output)
+	if err != nil {
+		return err
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	result := dto.CalculationResultDTO{}
+	if result.Price, err = c.subscriptions.CalculateSum(ctx, req); err != nil {
+		return err
+	}
+	if req.GroupBy != "" {
+		if result.Groups, err = c.subscriptions.CalculateGroups(ctx, req); err != nil {
+			return err
+		}
+	}
+	return out.total(result)
+}
+
+func runSchedule(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("schedule", "USER_ID")
+	output := outputFlag(fs)
+	from := fs.String("from", time.Now().UTC().Format("01-2006"), "first month, MM-YYYY")
+	to := fs.String("to", "", "last month, MM-YYYY (default 11 months after -from)")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(fs.Arg(0))
+	if err != nil {
+		return usageErrorf("invalid user id %q", fs.Arg(0))
+	}
+	first, err := parseMonth("from", *from)
+	if err != nil {
+		return err
+	}
+	last := models.MonthYearDate{Time: first.AddDate(0, 11, 0)}
+	if *to != "" {
+		if last, err = parseMonth("to", *to); err != nil {
+			return err
+		}
+	}
+	if last.Before(first.Time) {
+		return usageErrorf("-to is before -from")
+	}
+	out, err := newPrinter(c.stdout, *output)
+	if err != nil {
+		return err
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	subs, err := c.subscriptions.ActiveBetween(ctx, userID, first, last)
+	if err != nil {
+		return err
+	}
+	return out.schedule(billing.Schedule(subs, first, last))
+}
+
+func runTrials(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("trials", "")
+	output := outputFlag(fs)
+	user := fs.String("user", "", "only subscriptions of this user id")
+	month := fs.String("month", time.Now().UTC().Format("01-2006"), "last trial month, MM-YYYY")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	m, err := parseMonth("month", *month)
+	if err != nil {
+		return err
+	}
+	var userID *uuid.UUID
+	if *user != "" {
+		id, err := uuid.Parse(*user)
+		if err != nil {
+			return usageErrorf("invalid -user %q", *user)
+		}
+		userID = &id
+	}
+	out, err := newPrinter(c.stdout, *output)
+	if err != nil {
+		return err
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	var subs []dto.SubscriptionDTO
+	err = c.subscriptions.TrialsEnding(ctx, m, userID, func(s dto.SubscriptionDTO) error {
+		subs = append(subs, s)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return out.subscriptions(subs)
+}
+
+func runPurge(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("purge", "")
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "remove subscriptions soft-deleted longer ago than this")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *olderThan < 0 {
+		return usageErrorf("-older-than must not be negative")
+	}
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+	n, err := c.subscriptions.Purge(ctx, time.Now().Add(-*olderThan))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "purged %d subscription(s)\n", n)
+	return nil
+}
+
+// subscriptionFlags are the fields accepted by create and update.
+type subscriptionFlags struct {
+	user, service, start, end string
+	category, tags            string
+	billingPeriod             string
+	priceChanges              string
+	price                     int
+	trialMonths, trialPrice   int
+	promoMonths, promoPrice   int
+}
+
+func (f *subscriptionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.user, "user", "", "user id")
+	fs.StringVar(&f.service, "service", "", "service name")
+	fs.IntVar(&f.price, "price", 0, "monthly price")
+	fs.StringVar(&f.start, "start", "", "first month, MM-YYYY")
+	fs.StringVar(&f.end, "end", "", `last month, MM-YYYY; "" makes the subscription open-ended`)
+	fs.StringVar(&f.category, "category", "", `category overriding the catalogue one; "" removes the override`)
+	fs.StringVar(&f.tags, "tags", "", `comma-separated tags replacing the current ones; "" removes them`)
+	fs.IntVar(&f.trialMonths, "trial-months", 0, "length of the trial at the start, 0 for none")
+	fs.IntVar(&f.trialPrice, "trial-price", 0, "monthly price during the trial")
+	fs.IntVar(&f.promoMonths, "promo-months", 0, "length of the promo phase after the trial, 0 for none")
+	fs.IntVar(&f.promoPrice, "promo-price", 0, "monthly price during the promo phase")
+	fs.StringVar(&f.billingPeriod, "billing-period", "", "how often the price is charged: monthly, quarterly or yearly")
+	fs.StringVar(&f.priceChanges, "price-changes", "", `comma-separated MM-YYYY:price changes replacing the current ones; "" removes them`)
+}
+
+// apply copies the flags that were set on the command line into s.
+func (f *subscriptionFlags) apply(s *models.Subscription, set map[string]bool) error {
+	if set["user"] {
+		userID, err := uuid.Parse(f.user)
+		if err != nil {
+			return usageErrorf("invalid -user %q", f.user)
+		}
+		s.UserId = userID
+	}
+	if set["service"] {
+		if f.service == "" {
+			return usageErrorf("-service must not be empty")
+		}
+		s.ServiceName = f.service
+	}
+	if set["price"] {
+		if f.price < 0 {
+			return usageErrorf("-price must not be negative")
+		}
+		s.Price = f.price
+	}
+	if set["start"] {
+		start, err := parseMonth("start", f.start)
+		if err != nil {
+			return err
+		}
+		s.StartDate = start
+	}
+	if set["end"] {
+		s.EndDate = nil
+		if f.end != "" {
+			end, err := parseMonth("end", f.end)
+			if err != nil {
+				return err
+			}
+			s.EndDate = &end
+		}
+	}
+	phases := []struct {
+		name     string
+		from, to *int
+	}{
+		{"trial-months", &f.trialMonths, &s.TrialMonths},
+		{"trial-price", &f.trialPrice, &s.TrialPrice},
+		{"promo-months", &f.promoMonths, &s.PromoMonths},
+		{"promo-price", &f.promoPrice, &s.PromoPrice},
+	}
+	for _, p := range phases {
+		if set[p.name] {
+			if *p.from < 0 {
+				return usageErrorf("-%s must not be negative", p.name)
+			}
+			*p.to = *p.from
+		}
+	}
+	if set["billing-period"] {
+		if models.BillingPeriodMonths(f.billingPeriod) == 0 {
+			return usageErrorf("invalid -billing-period %q, expected monthly, quarterly or yearly", f.billingPeriod)
+		}
+		s.BillingPeriod = f.billingPeriod
+	}
+	if set["price-changes"] {
+		changes, err := parsePriceChanges(f.priceChanges, ",")
+		if err != nil {
+			return usageErrorf("invalid -price-changes: %v", err)
+		}
+		s.PriceChanges = changes
+		if s.PriceChanges == nil {
+			s.PriceChanges = []models.PriceChange{}
+		}
+	}
+	if set["category"] {
+		s.Category = nil
+		if f.category != "" {
+			s.Category = &f.category
+		}
+	}
+	if set["tags"] {
+		s.Tags = splitTags(f.tags)
+		if s.Tags == nil {
+			s.Tags = []string{}
+		}
+	}
+	if s.EndDate != nil && s.EndDate.Before(s.StartDate.Time) {
+		return usageErrorf("end month %s is before start month %s", s.EndDate.MonthYear(), s.StartDate.MonthYear())
+	}
+	if err := checkPriceChanges(*s); err != nil {
+		return usageErrorf("%v", err)
+	}
+	return nil
+}
+
+// parsePriceChanges parses MM-YYYY:price pairs separated by sep.
+func parsePriceChanges(v, sep string) ([]models.PriceChange, error) {
+	var changes []models.PriceChange
+	for _, pair := range strings.Split(v, sep) {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		month, price, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not MM-YYYY:price", pair)
+		}
+		var c models.PriceChange
+		var err error
+		if c.Month, err = models.ParseMonthYearDate(strings.TrimSpace(month)); err != nil {
+			return nil, fmt.Errorf("%q is not MM-YYYY:price", pair)
+		}
+		if c.Price, err = strconv.Atoi(strings.TrimSpace(price)); err != nil || c.Price < 0 {
+			return nil, fmt.Errorf("invalid price in %q", pair)
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// checkPriceChanges rejects price changes outside the subscription and more
+// than one change in a month.
+func checkPriceChanges(s models.Subscription) error {
+	seen := map[string]bool{}
+	for _, c := range s.PriceChanges {
+		month := c.Month.MonthYear()
+		if c.Month.Before(s.StartDate.Time) || (s.EndDate != nil && c.Month.After(s.EndDate.Time)) {
+			return fmt.Errorf("price change in %s is outside the subscription", month)
+		}
+		if seen[month] {
+			return fmt.Errorf("more than one price change in %s", month)
+		}
+		seen[month] = true
+	}
+	return nil
+}
+
+// filterFlags binds the search filter flags shared by search and export.
+func filterFlags(fs *flag.FlagSet, filter *dto.SubscriptionFilter) {
+	fs.Func("user", "only subscriptions of this user id", func(v string) error {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			return err
+		}
+		filter.UserID = &userID
+		return nil
+	})
+	fs.StringVar(&filter.ServiceName, "service", "", "service name substring, case-insensitive")
+	fs.Func("active", "only subscriptions active in this month, MM-YYYY", func(v string) error {
+		month, err := models.ParseMonthYearDate(v)
+		if err != nil {
+			return errors.New("expected MM-YYYY")
+		}
+		filter.ActiveIn = &month
+		return nil
+	})
+	fs.StringVar(&filter.Category, "category", "", "only subscriptions in this category, case-insensitive")
+	fs.Func("tags", "only subscriptions with any of these comma-separated tags", func(v string) error {
+		filter.Tags = splitTags(v)
+		return nil
+	})
+	fs.BoolVar(&filter.MatchAllTags, "all-tags", false, "require all of the -tags instead of any")
+}
+
+// splitTags splits a comma-separated list, dropping blank entries.
+func splitTags(v string) []string {
+	var tags []string
+	for _, tag := range strings.Split(v, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, usageErrorf("invalid subscription id %q", s)
+	}
+	return id, nil
+}
+
+func parseMonth(name, v string) (models.MonthYearDate, error) {
+	if v == "" {
+		return models.MonthYearDate{}, usageErrorf("-%s is required", name)
+	}
+	month, err := models.ParseMonthYearDate(v)
+	if err != nil {
+		return models.MonthYearDate{}, usageErrorf("invalid -%s %q, expected MM-YYYY", name, v)
+	}
+	return month, nil
+}
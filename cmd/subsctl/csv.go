@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+
+	"github.com/google/uuid"
+)
+
+// csvHeader is the export layout; import accepts the same columns in any
+// order, with only user_id, service_name, price and start_date required.
+// Tags and MM-YYYY:price changes are separated by semicolons within their field.
+var csvHeader = []string{"id", "user_id", "service_name", "price", "start_date", "end_date", "category", "tags",
+	"trial_months", "trial_price", "promo_months", "promo_price", "billing_period", "price_changes"}
+
+// phaseColumns are the integer columns describing trial and promo phases.
+var phaseColumns = []string{"trial_months", "trial_price", "promo_months", "promo_price"}
+
+func runImport(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("import", "FILE|-")
+	dryRun := fs.Bool("dry-run", false, "validate the file without inserting anything")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	in := c.stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	subs, err := readCSV(in)
+	if err != nil {
+		return err
+	}
+	if !*dryRun {
+		if err = c.connect(ctx); err != nil {
+			return err
+		}
+		if err = c.subscriptions.InsertBatch(ctx, subs); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(c.stdout, "imported %d subscription(s)\n", len(subs))
+	return nil
+}
+
+func runExport(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("export", "")
+	var filter dto.SubscriptionFilter
+	filterFlags(fs, &filter)
+	outPath := fs.String("out", "-", "output file, - for stdout")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+
+	out := c.stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := csv.NewWriter(out)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	err := c.subscriptions.Search(ctx, filter, func(s dto.SubscriptionDTO) error {
+		return w.Write(csvRecord(strconv.Itoa(s.Id), s.Subscription))
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return err
+	}
+	if f, ok := out.(*os.File); ok && f != os.Stdout {
+		return f.Close()
+	}
+	return nil
+}
+
+func csvRecord(id string, s models.Subscription) []string {
+	end := ""
+	if s.EndDate != nil {
+		end = s.EndDate.MonthYear()
+	}
+	category := ""
+	if s.Category != nil {
+		category = *s.Category
+	}
+	changes := make([]string, len(s.PriceChanges))
+	for i, c := range s.PriceChanges {
+		changes[i] = c.Month.MonthYear() + ":" + strconv.Itoa(c.Price)
+	}
+	return []string{id, s.UserId.String(), s.ServiceName, strconv.Itoa(s.Price), s.StartDate.MonthYear(), end, category, strings.Join(s.Tags, ";"),
+		strconv.Itoa(s.TrialMonths), strconv.Itoa(s.TrialPrice), strconv.Itoa(s.PromoMonths), strconv.Itoa(s.PromoPrice),
+		s.BillingPeriod, strings.Join(changes, ";")}
+}
+
+// readCSV parses and validates the whole file up front so that a bad row
+// aborts the import before anything is written.
+func readCSV(in io.Reader) ([]models.Subscription, error) {
+	r := csv.NewReader(in)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, usageErrorf("empty CSV file")
+		}
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"user_id", "service_name", "price", "start_date"} {
+		if _, ok := columns[required]; !ok {
+			return nil, usageErrorf("CSV header has no %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var subs []models.Subscription
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		s, err := subscriptionFromRecord(func(name string) string { return field(record, name) })
+		if err != nil {
+			return nil, usageErrorf("line %d: %v", line, err)
+		}
+		subs = append(subs, s)
+	}
+	return subs, nil
+}
+
+func subscriptionFromRecord(field func(string) string) (models.Subscription, error) {
+	var s models.Subscription
+	var err error
+	if s.UserId, err = uuid.Parse(field("user_id")); err != nil {
+		return s, fmt.Errorf("invalid user_id %q", field("user_id"))
+	}
+	if s.ServiceName = field("service_name"); s.ServiceName == "" {
+		return s, errors.New("empty service_name")
+	}
+	if s.Price, err = strconv.Atoi(field("price")); err != nil || s.Price < 0 {
+		return s, fmt.Errorf("invalid price %q", field("price"))
+	}
+	if s.StartDate, err = models.ParseMonthYearDate(field("start_date")); err != nil {
+		return s, fmt.Errorf("invalid start_date %q, expected MM-YYYY", field("start_date"))
+	}
+	if v := field("end_date"); v != "" {
+		end, err := models.ParseMonthYearDate(v)
+		if err != nil {
+			return s, fmt.Errorf("invalid end_date %q, expected MM-YYYY", v)
+		}
+		if end.Before(s.StartDate.Time) {
+			return s, fmt.Errorf("end_date %s is before start_date %s", v, field("start_date"))
+		}
+		s.EndDate = &end
+	}
+	if v := field("category"); v != "" {
+		s.Category = &v
+	}
+	for _, tag := range strings.Split(field("tags"), ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			s.Tags = append(s.Tags, tag)
+		}
+	}
+	phases := []*int{&s.TrialMonths, &s.TrialPrice, &s.PromoMonths, &s.PromoPrice}
+	for i, name := range phaseColumns {
+		v := field(name)
+		if v == "" {
+			continue
+		}
+		if *phases[i], err = strconv.Atoi(v); err != nil || *phases[i] < 0 {
+			return s, fmt.Errorf("invalid %s %q", name, v)
+		}
+	}
+	if s.BillingPeriod = field("billing_period"); models.BillingPeriodMonths(s.BillingPeriod) == 0 {
+		return s, fmt.Errorf("invalid billing_period %q", s.BillingPeriod)
+	}
+	if s.PriceChanges, err = parsePriceChanges(field("price_changes"), ";"); err != nil {
+		return s, fmt.Errorf("invalid price_changes: %v", err)
+	}
+	return s, checkPriceChanges(s)
+}
@@ -0,0 +1,156 @@
+// Command subsctl is the administrative CLI for the subscriptions database:
+// schema migrations, subscription CRUD, totals, CSV import/export and purging
+// of soft-deleted rows. It reads the same configuration as the API server.
+//
+// Exit codes: 0 success, 1 failure, 2 usage error, 3 subscription not found.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"testTaskEffectiveMobile/config"
+	"testTaskEffectiveMobile/postgres_db"
+	"testTaskEffectiveMobile/postgres_db/repositories"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+)
+
+type cli struct {
+	cfg           config.Config
+	db            *sql.DB
+	closeDB       func()
+	subscriptions *repositories.SubscriptionsRepository
+	services      *repositories.ServicesRepository
+	budgets       *repositories.BudgetsRepository
+	stdin         io.Reader
+	stdout        io.Writer
+	stderr        io.Writer
+}
+
+// connect opens the database; commands call it after parsing their flags so
+// that -h and usage errors do not need a reachable postgres.
+func (c *cli) connect(ctx context.Context) error {
+	db, closer, err := postgres_db.ConnectPostgres(ctx, c.cfg.DB, slog.Default())
+	if err != nil {
+		return err
+	}
+	c.db, c.closeDB = db, closer
+	c.subscriptions = &repositories.SubscriptionsRepository{Db: db}
+	c.services = &repositories.ServicesRepository{Db: db}
+	c.budgets = &repositories.BudgetsRepository{Db: db}
+	return nil
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = []command{
+	{"migrate", "apply pending schema migrations (-status shows the version)", runMigrate},
+	{"list", "list subscriptions of a user", runList},
+	{"search", "search subscriptions by user, service and active month", runSearch},
+	{"show", "show a subscription by id", runShow},
+	{"create", "create a subscription", runCreate},
+	{"update", "change fields of a subscription", runUpdate},
+	{"delete", "soft-delete a subscription", runDelete},
+	{"calc", "total cost of subscriptions for a period, as /calculate", runCalc},
+	{"schedule", "monthly charges of a user over a period", runSchedule},
+	{"trials", "subscriptions whose trial ends in a month", runTrials},
+	{"import", "insert subscriptions from CSV", runImport},
+	{"export", "write subscriptions as CSV", runExport},
+	{"purge", "permanently remove soft-deleted subscriptions", runPurge},
+	{"seed", "generate deterministic demo subscriptions", runSeed},
+	{"normalize-services", "link free-text service names to the service catalogue", runNormalizeServices},
+	{"check-budgets", "raise the budget alerts due for a month, as the server does periodically", runCheckBudgets},
+	{"send-reminders", "email the charge and trial reminders due on a day, as the server does periodically", runSendReminders},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, rest, err := config.LoadCommand("subsctl", args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			usage(stderr)
+			return exitOK
+		}
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if len(rest) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+	cmd, ok := findCommand(rest[0])
+	if !ok {
+		fmt.Fprintf(stderr, "subsctl: unknown command %q\n", rest[0])
+		usage(stderr)
+		return exitUsage
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := &cli{cfg: cfg, stdin: stdin, stdout: stdout, stderr: stderr}
+	err = cmd.run(ctx, c, rest[1:])
+	var usageErr usageError
+	if c.closeDB != nil {
+		c.closeDB()
+	}
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errFlags):
+		return exitUsage
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "subsctl %s: %v\n", cmd.name, err)
+		return exitUsage
+	case errors.Is(err, sql.ErrNoRows):
+		fmt.Fprintf(stderr, "subsctl %s: subscription not found\n", cmd.name)
+		return exitNotFound
+	default:
+		fmt.Fprintf(stderr, "subsctl %s: %v\n", cmd.name, err)
+		return exitError
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: subsctl [config flags] <command> [command flags] [args]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nConfig flags are the API server's (subsctl -h lists them); run subsctl <command> -h for command flags.")
+}
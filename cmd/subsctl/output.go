@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testTaskEffectiveMobile/dto"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("o", outputTable, "output format: table or json")
+}
+
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	if format != outputTable && format != outputJSON {
+		return nil, usageErrorf("unknown output format %q, expected table or json", format)
+	}
+	return &printer{w: w, format: format}, nil
+}
+
+func (p *printer) subscriptions(subs []dto.SubscriptionDTO) error {
+	if p.format == outputJSON {
+		if subs == nil {
+			subs = []dto.SubscriptionDTO{}
+		}
+		return p.json(subs)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSER\tSERVICE\tPRICE\tSTART\tEND\tCATEGORY\tTAGS")
+	for _, s := range subs {
+		end := "-"
+		if s.EndDate != nil {
+			end = s.EndDate.MonthYear()
+		}
+		category := "-"
+		if s.EffectiveCategory != nil {
+			category = *s.EffectiveCategory
+		}
+		tags := "-"
+		if len(s.Tags) > 0 {
+			tags = strings.Join(s.Tags, ",")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", s.Id, s.UserId, s.ServiceName, s.Price, s.StartDate.MonthYear(), end, category, tags)
+	}
+	return tw.Flush()
+}
+
+func (p *printer) subscription(s dto.SubscriptionDTO) error {
+	if p.format == outputJSON {
+		return p.json(&s)
+	}
+	return p.subscriptions([]dto.SubscriptionDTO{s})
+}
+
+func (p *printer) total(result dto.CalculationResultDTO) error {
+	if p.format == outputJSON {
+		return p.json(result)
+	}
+	if result.Groups == nil {
+		_, err := fmt.Fprintln(p.w, strconv.FormatInt(result.Price, 10))
+		return err
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tPRICE")
+	for _, g := range result.Groups {
+		key := "-"
+		if g.Key != nil {
+			key = *g.Key
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", key, g.Price)
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\n", result.Price)
+	return tw.Flush()
+}
+
+func (p *printer) schedule(schedule dto.ScheduleDTO) error {
+	if p.format == outputJSON {
+		return p.json(&schedule)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MONTH\tID\tSERVICE\tPHASE\tPAID THROUGH\tPRICE")
+	for _, m := range schedule.Months {
+		for _, c := range m.Charges {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\n", m.Month.MonthYear(), c.SubscriptionID, c.ServiceName, c.Phase, c.PaidThrough.MonthYear(), c.Price)
+		}
+		fmt.Fprintf(tw, "%s\t\tmonth total\t\t\t%d\n", m.Month.MonthYear(), m.Total)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t\t\t\t%d\n", schedule.Total)
+	return tw.Flush()
+}
+
+func (p *printer) json(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testTaskEffectiveMobile/notifications"
+	"testTaskEffectiveMobile/postgres_db/repositories"
+	"time"
+)
+
+func runSendReminders(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("send-reminders", "")
+	date := fs.String("date", time.Now().UTC().Format(time.DateOnly), "day to send the reminders of, YYYY-MM-DD")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	day, err := time.Parse(time.DateOnly, *date)
+	if err != nil {
+		return usageErrorf("invalid -date %q, expected YYYY-MM-DD", *date)
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	logger := slog.New(slog.NewTextHandler(c.stderr, nil))
+	var sender notifications.Sender = notifications.LogSender{Logger: logger}
+	if smtp := c.smtpSender(); smtp != nil {
+		sender = smtp
+	}
+	scheduler := &notifications.Scheduler{
+		Store:         &repositories.NotificationsRepository{Db: c.db},
+		Subscriptions: c.subscriptions.ActiveBetween,
+		Sender:        sender,
+		Logger:        logger,
+	}
+	n, err := scheduler.SendDue(ctx, day)
+	fmt.Fprintf(c.stdout, "%d reminder(s) sent\n", n)
+	return err
+}
+
+// smtpSender returns the configured SMTP server, or nil without one.
+func (c *cli) smtpSender() *notifications.SMTPSender {
+	smtp := c.cfg.Notifications.SMTP
+	if smtp.Addr == "" {
+		return nil
+	}
+	return &notifications.SMTPSender{
+		Addr:     smtp.Addr,
+		Username: smtp.Username,
+		Password: smtp.Password,
+		From:     smtp.From,
+		Timeout:  smtp.Timeout,
+	}
+}
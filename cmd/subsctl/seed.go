@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"testTaskEffectiveMobile/models"
+	"testTaskEffectiveMobile/seed"
+	"time"
+)
+
+func runSeed(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("seed", "")
+	users := c.cfg.Seed.Users
+	if users == 0 {
+		users = 100
+	}
+	fs.IntVar(&users, "users", users, "number of users to generate")
+	perUser := fs.Int("per-user", c.cfg.Seed.PerUser, "subscriptions per user")
+	seedValue := fs.Uint64("seed", uint64(c.cfg.Seed.Seed), "random seed; the same seed and -until give the same data")
+	catalogue := fs.String("catalogue", c.cfg.Seed.Catalogue, "YAML/JSON file with services and price tiers, empty for the built-in one")
+	until := fs.String("until", time.Now().Format("01-2006"), "latest start month, MM-YYYY")
+	span := fs.Int("span", seed.DefaultSpanMonths, "start months are spread over this many months before -until")
+	openEnded := fs.Float64("open-ended", seed.DefaultOpenEnded, "share of subscriptions without an end month, 0..1")
+	dryRun := fs.Bool("dry-run", false, "print the generated subscriptions as CSV instead of inserting them")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	opts := seed.Options{
+		Users:      users,
+		PerUser:    *perUser,
+		Seed:       *seedValue,
+		SpanMonths: *span,
+		OpenEnded:  *openEnded,
+	}
+	var err error
+	if opts.Until, err = parseMonth("until", *until); err != nil {
+		return err
+	}
+	if *catalogue != "" {
+		if opts.Catalogue, err = seed.LoadCatalogue(*catalogue); err != nil {
+			return err
+		}
+	}
+	if err = opts.Validate(); err != nil {
+		return usageErrorf("%v", err)
+	}
+
+	if *dryRun {
+		w := csv.NewWriter(c.stdout)
+		if err = w.Write(csvHeader); err != nil {
+			return err
+		}
+		err = seed.Generate(opts, func(s models.Subscription) error {
+			return w.Write(csvRecord("", s))
+		})
+		if err != nil {
+			return err
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	n, err := c.subscriptions.CopyFrom(ctx, func(add func(models.Subscription) error) error {
+		return seed.Generate(opts, add)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "seeded %d subscription(s) for %d user(s)\n", n, users)
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func runNormalizeServices(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("normalize-services", "")
+	output := outputFlag(fs)
+	createMissing := fs.Bool("create-missing", false, "add a catalogue entry for every unknown name, using its most frequent spelling")
+	dryRun := fs.Bool("dry-run", false, "report what would change without writing")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	out, err := newPrinter(c.stdout, *output)
+	if err != nil {
+		return err
+	}
+	if err = c.connect(ctx); err != nil {
+		return err
+	}
+	report, err := c.services.Normalize(ctx, *createMissing, *dryRun)
+	if err != nil {
+		return err
+	}
+	if out.format == outputJSON {
+		return out.json(report)
+	}
+
+	prefix := ""
+	if *dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Fprintf(c.stdout, "%screated %d service(s), linked %d subscription(s)\n", prefix, report.Created, report.Linked)
+	if len(report.Conflicts) > 0 {
+		ids := make([]string, len(report.Conflicts))
+		for i, id := range report.Conflicts {
+			ids[i] = fmt.Sprint(id)
+		}
+		fmt.Fprintf(c.stdout, "left unlinked, would overlap another subscription: %s\n", strings.Join(ids, ", "))
+	}
+	if len(report.Unmatched) > 0 {
+		fmt.Fprintln(c.stdout, "names missing from the catalogue:")
+		for _, nc := range report.Unmatched {
+			fmt.Fprintf(c.stdout, "  %q (%d)\n", nc.Name, nc.Count)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// compressible lists the media types worth compressing; everything else
+// (images, archives, event streams) is passed through untouched.
+var compressible = []string{
+	"text/plain",
+	"text/html",
+	"text/csv",
+	"text/calendar",
+	"application/json",
+	"application/problem+json",
+	"application/x-ndjson",
+	"application/xml",
+	"application/javascript",
+	"image/svg+xml",
+}
+
+var (
+	gzipPool   = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	brotliPool = sync.Pool{New: func() any { return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression) }}
+)
+
+// Middleware compresses responses with brotli or gzip, as negotiated through
+// Accept-Encoding. Bodies are buffered up to minSize bytes: a response that
+// ends below the threshold is sent as is. A handler flush commits to
+// compression early, so streamed responses stay streamed.
+func Middleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, status: http.StatusOK}
+			// Not deferred: a handler that aborts with http.ErrAbortHandler
+			// must not get its truncated stream finished with a valid
+			// trailer, nor a buffered partial body sent as complete.
+			next.ServeHTTP(cw, r)
+			cw.Close()
+		})
+	}
+}
+
+// negotiate picks br or gzip by q-value, preferring br on a tie; "" means identity.
+func negotiate(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 || (name != encodingBrotli && name != encodingGzip) {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == encodingBrotli) {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status      int
+	wroteHeader bool // WriteHeader was called by the handler
+	committed   bool // headers were sent downstream
+	passthrough bool
+	buf         []byte
+	enc         io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified || !cw.compressible() {
+		cw.commit(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.commit(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// commit sends the headers and whatever is buffered, compressed or not.
+func (cw *compressWriter) commit(compress bool) error {
+	if cw.committed {
+		return nil
+	}
+	cw.committed = true
+	h := cw.Header()
+	if compress {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.enc = cw.newEncoder()
+	} else {
+		cw.passthrough = true
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if compress {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		// Untyped bodies are left for net/http to sniff.
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, t := range compressible {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (cw *compressWriter) newEncoder() io.WriteCloser {
+	if cw.encoding == encodingBrotli {
+		bw := brotliPool.Get().(*brotli.Writer)
+		bw.Reset(cw.ResponseWriter)
+		return &pooled{WriteCloser: bw, put: func() { brotliPool.Put(bw) }, flush: bw.Flush}
+	}
+	gw := gzipPool.Get().(*gzip.Writer)
+	gw.Reset(cw.ResponseWriter)
+	return &pooled{WriteCloser: gw, put: func() { gzipPool.Put(gw) }, flush: gw.Flush}
+}
+
+// Flush commits to compression (when the content type allows it) and pushes
+// everything written so far to the client.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.committed {
+		// An uncommitted writer has a compressible content type, see WriteHeader.
+		cw.commit(true)
+	}
+	if p, ok := cw.enc.(*pooled); ok {
+		p.flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close finishes the response: a body that never reached minSize is sent uncompressed.
+func (cw *compressWriter) Close() error {
+	if !cw.committed {
+		if !cw.wroteHeader && len(cw.buf) == 0 {
+			// Nothing was written at all; let net/http send its implicit 200.
+			return nil
+		}
+		if err := cw.commit(false); err != nil {
+			return err
+		}
+	}
+	if cw.enc != nil {
+		return cw.enc.Close()
+	}
+	return nil
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+type pooled struct {
+	io.WriteCloser
+	put   func()
+	flush func() error
+}
+
+func (p *pooled) Close() error {
+	err := p.WriteCloser.Close()
+	p.put()
+	return err
+}
@@ -0,0 +1,135 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"GZIP;q=0.8, br;q=0", "gzip"},
+		{"br;q=bad, gzip;q=0.1", "gzip"},
+		{"*", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.header); got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func decode(t *testing.T, encoding string, body []byte) ([]byte, error) {
+	t.Helper()
+	switch encoding {
+	case encodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(zr)
+	case encodingBrotli:
+		return io.ReadAll(brotli.NewReader(bytes.NewReader(body)))
+	}
+	return body, nil
+}
+
+// serve runs handler behind the middleware, letting a handler abort through.
+func serve(encoding string, handler http.HandlerFunc) (rec *httptest.ResponseRecorder, aborted bool) {
+	rec = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", encoding)
+	defer func() {
+		aborted = recover() == http.ErrAbortHandler
+	}()
+	Middleware(64)(handler).ServeHTTP(rec, r)
+	return rec, false
+}
+
+func TestMiddleware(t *testing.T) {
+	long := strings.Repeat(`{"service_name":"Netflix"}`+"\n", 20)
+	for _, encoding := range []string{encodingGzip, encodingBrotli} {
+		t.Run(encoding, func(t *testing.T) {
+			rec, _ := serve(encoding, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				io.WriteString(w, long)
+			})
+			if rec.Header().Get("Content-Encoding") != encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", rec.Header().Get("Content-Encoding"), encoding)
+			}
+			body, err := decode(t, encoding, rec.Body.Bytes())
+			if err != nil || string(body) != long {
+				t.Errorf("decoded body = %q, %v", body, err)
+			}
+
+			rec, _ = serve(encoding, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{}`)
+			})
+			if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{}` {
+				t.Errorf("small body: encoding %q, body %q; want it sent as is", rec.Header().Get("Content-Encoding"), rec.Body)
+			}
+
+			rec, _ = serve(encoding, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				io.WriteString(w, long)
+			})
+			if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != long {
+				t.Error("incompressible content type was compressed")
+			}
+		})
+	}
+}
+
+func TestAbortedStreamIsNotFinished(t *testing.T) {
+	for _, encoding := range []string{encodingGzip, encodingBrotli} {
+		t.Run(encoding, func(t *testing.T) {
+			rec, aborted := serve(encoding, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				io.WriteString(w, strings.Repeat("{}\n", 40))
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			})
+			if !aborted {
+				t.Fatal("the abort did not reach the server")
+			}
+			if rec.Header().Get("Content-Encoding") != encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", rec.Header().Get("Content-Encoding"), encoding)
+			}
+			// The flushed part decodes, but the stream must end unexpectedly.
+			body, err := decode(t, encoding, rec.Body.Bytes())
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("decoding the aborted stream = %d bytes, %v; want io.ErrUnexpectedEOF", len(body), err)
+			}
+		})
+	}
+}
+
+func TestAbortBeforeThresholdSendsNothing(t *testing.T) {
+	rec, aborted := serve(encodingGzip, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `[{"id":1},`)
+		panic(http.ErrAbortHandler)
+	})
+	if !aborted {
+		t.Fatal("the abort did not reach the server")
+	}
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("aborted response sent %q with encoding %q, want nothing", rec.Body, rec.Header().Get("Content-Encoding"))
+	}
+}
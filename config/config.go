@@ -0,0 +1,527 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the complete service configuration. Values are layered from
+// defaults, an optional YAML/JSON file, environment variables and command-line
+// flags, each source overriding the previous one.
+type Config struct {
+	HTTP          HTTP          `yaml:"http"`
+	TLS           TLS           `yaml:"tls"`
+	DB            DB            `yaml:"db"`
+	Log           Log           `yaml:"log"`
+	Health        Health        `yaml:"health"`
+	Metrics       Metrics       `yaml:"metrics"`
+	Tracing       Tracing       `yaml:"tracing"`
+	CORS          CORS          `yaml:"cors"`
+	Features      Features      `yaml:"features"`
+	Seed          Seed          `yaml:"seed"`
+	Budgets       Budgets       `yaml:"budgets"`
+	Webhooks      Webhooks      `yaml:"webhooks"`
+	Outbox        Outbox        `yaml:"outbox"`
+	Events        Events        `yaml:"events"`
+	Notifications Notifications `yaml:"notifications"`
+}
+
+type HTTP struct {
+	Addr            string        `yaml:"addr"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// CrashReportDir, when set, receives one file per recovered handler panic.
+	CrashReportDir string `yaml:"crash_report_dir"`
+	// CompressionMinSize is the smallest response body worth compressing, in bytes.
+	CompressionMinSize int `yaml:"compression_min_size"`
+	// PublicURL is where clients reach the service, e.g. https://subscriptions.example.com;
+	// links handed out to clients are built from it, never from the request's Host.
+	PublicURL string `yaml:"public_url"`
+}
+
+// TLS is enabled when both CertFile and KeyFile are set. HTTP/2 is then negotiated via ALPN.
+type TLS struct {
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	MinVersion string `yaml:"min_version"`
+	// ClientCAFile enables mTLS: clients must present a certificate signed by one of these CAs.
+	ClientCAFile   string        `yaml:"client_ca_file"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	// RedirectAddr, when set, runs a plaintext listener that redirects every request to HTTPS.
+	RedirectAddr string `yaml:"redirect_addr"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+type DB struct {
+	// DSN, when set, is used as is and the discrete connection fields are ignored.
+	DSN          string `yaml:"dsn"`
+	Host         string `yaml:"host"`
+	Port         int    `yaml:"port"`
+	User         string `yaml:"user"`
+	Password     string `yaml:"password"`
+	Name         string `yaml:"name"`
+	SSLMode      string `yaml:"sslmode"`
+	MaxOpenConns int    `yaml:"max_open_conns"`
+	MaxIdleConns int    `yaml:"max_idle_conns"`
+	// ConnMaxLifetime and ConnMaxIdleTime recycle pooled connections; 0 keeps them forever.
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// ConnectTimeout bounds the total time spent retrying the first connection.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// StatsInterval is how often pool stats are logged; 0 disables it.
+	StatsInterval time.Duration `yaml:"stats_interval"`
+	// ReplicaDSNs are read-only replicas serving read queries; the primary
+	// takes over whenever none of them passes its health check.
+	ReplicaDSNs          []string      `yaml:"replica_dsns"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval"`
+}
+
+// Seed fills an empty database with generated demo data at startup; Users = 0 disables it.
+type Seed struct {
+	Users   int `yaml:"users"`
+	PerUser int `yaml:"per_user"`
+	Seed    int `yaml:"seed"`
+	// Catalogue is an optional YAML/JSON file with services and price tiers.
+	Catalogue string `yaml:"catalogue"`
+}
+
+type Budgets struct {
+	// CheckInterval is how often budgets are compared with the month's cost; 0 disables alerts.
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
+
+// Webhooks configures the delivery of subscription events to registered URLs.
+type Webhooks struct {
+	// PollInterval is how often the delivery queue is checked; 0 disables sending.
+	PollInterval time.Duration `yaml:"poll_interval"`
+	Timeout      time.Duration `yaml:"timeout"`
+	// MaxAttempts is how many attempts a delivery gets before it goes to the dead-letter queue.
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is the delay after the first failed attempt, doubled after every further one.
+	Backoff time.Duration `yaml:"backoff"`
+	// AllowPrivate lets webhooks reach loopback, private and link-local
+	// addresses, e.g. a receiver on the developer's machine.
+	AllowPrivate bool `yaml:"allow_private"`
+}
+
+// Outbox configures the relay that publishes the domain events recorded in
+// the outbox table.
+type Outbox struct {
+	// PollInterval is how often the outbox is checked; 0 disables the relay.
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Publishers is a subset of webhook, log and nats.
+	Publishers []string `yaml:"publishers"`
+	// Retention is how long published events are kept.
+	Retention time.Duration `yaml:"retention"`
+	NATS      NATS          `yaml:"nats"`
+}
+
+type NATS struct {
+	URL string `yaml:"url"`
+	// SubjectPrefix is followed by the event type, e.g. events.subscription.created.
+	SubjectPrefix string `yaml:"subject_prefix"`
+	// JetStream waits for the stream's acknowledgement of every event instead
+	// of only the server's.
+	JetStream bool          `yaml:"jetstream"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// Events configures the server-sent event streams of subscription changes.
+type Events struct {
+	// Source is postgres, where every replica listens for the notifications
+	// sent with each recorded event, or local, where the outbox relay of this
+	// process feeds its streams; local only suits a single replica.
+	Source string `yaml:"source"`
+	// Heartbeat is how often an idle stream gets a comment line.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// History is how many recent events are kept for resuming streams.
+	History int `yaml:"history"`
+}
+
+// Notifications configures the email reminders of upcoming charges and trial ends.
+type Notifications struct {
+	// CheckInterval is how often due reminders are looked for; 0 disables them.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	SMTP          SMTP          `yaml:"smtp"`
+}
+
+// SMTP is the mail server of the reminders; without Addr they are only logged.
+type SMTP struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From is the sender, e.g. "Subscriptions <noreply@example.com>".
+	From    string        `yaml:"from"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type Log struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+type Health struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+type Metrics struct {
+	// BusinessInterval is how often the subscription gauges are recomputed from the database.
+	BusinessInterval time.Duration `yaml:"business_interval"`
+}
+
+type Tracing struct {
+	// Exporter is one of none, stdout or otlp.
+	Exporter    string `yaml:"exporter"`
+	ServiceName string `yaml:"service_name"`
+	// OTLPEndpoint is the full OTLP/HTTP traces URL, e.g. http://localhost:4318/v1/traces.
+	// When empty the standard OTEL_EXPORTER_OTLP_* variables apply.
+	OTLPEndpoint string  `yaml:"otlp_endpoint"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+}
+
+// CORS is disabled while AllowedOrigins is empty.
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+	// Swagger also applies the policy to the /swagger/ UI.
+	Swagger bool `yaml:"swagger"`
+}
+
+type Features struct {
+	Swagger     bool `yaml:"swagger"`
+	Metrics     bool `yaml:"metrics"`
+	Compression bool `yaml:"compression"`
+}
+
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Addr:               ":8080",
+			ReadTimeout:        5 * time.Second,
+			WriteTimeout:       5 * time.Second,
+			IdleTimeout:        120 * time.Second,
+			ShutdownTimeout:    10 * time.Second,
+			CompressionMinSize: 1024,
+			PublicURL:          "http://localhost:8080",
+		},
+		TLS: TLS{
+			MinVersion:     "1.2",
+			ReloadInterval: 30 * time.Second,
+		},
+		DB: DB{
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  30 * time.Second,
+			StatsInterval:   time.Minute,
+
+			ReplicaCheckInterval: 5 * time.Second,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "text",
+		},
+		Health: Health{
+			CheckTimeout: 2 * time.Second,
+		},
+		Metrics: Metrics{
+			BusinessInterval: time.Minute,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "subscriptions",
+			SampleRatio: 1,
+		},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "X-Request-ID", "X-Read-Your-Writes"},
+			ExposedHeaders: []string{"X-Request-ID"},
+			MaxAge:         10 * time.Minute,
+		},
+		Features: Features{
+			Swagger:     true,
+			Metrics:     true,
+			Compression: true,
+		},
+		Seed: Seed{
+			PerUser: 3,
+			Seed:    1,
+		},
+		Budgets: Budgets{
+			CheckInterval: 15 * time.Minute,
+		},
+		Webhooks: Webhooks{
+			PollInterval: 5 * time.Second,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			Backoff:      30 * time.Second,
+		},
+		Outbox: Outbox{
+			PollInterval: time.Second,
+			Publishers:   []string{"webhook"},
+			Retention:    7 * 24 * time.Hour,
+			NATS: NATS{
+				URL:           "nats://localhost:4222",
+				SubjectPrefix: "events",
+				Timeout:       5 * time.Second,
+			},
+		},
+		Events: Events{
+			Source:    "postgres",
+			Heartbeat: 15 * time.Second,
+			History:   1000,
+		},
+		Notifications: Notifications{
+			CheckInterval: time.Hour,
+			SMTP: SMTP{
+				From:    "Subscriptions <noreply@localhost>",
+				Timeout: 10 * time.Second,
+			},
+		},
+	}
+}
+
+var (
+	sslModes     = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logLevels    = []string{"debug", "info", "warn", "error"}
+	logFormats   = []string{"text", "json"}
+	exporters    = []string{"none", "stdout", "otlp"}
+	publishers   = []string{"webhook", "log", "nats"}
+	eventSources = []string{"postgres", "local"}
+)
+
+// Validate checks the whole configuration and reports every problem found, not just the first one.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("http.addr: %w", err))
+	}
+	check(c.HTTP.ReadTimeout > 0, "http.read_timeout: must be positive, got %s", c.HTTP.ReadTimeout)
+	check(c.HTTP.WriteTimeout > 0, "http.write_timeout: must be positive, got %s", c.HTTP.WriteTimeout)
+	check(c.HTTP.IdleTimeout > 0, "http.idle_timeout: must be positive, got %s", c.HTTP.IdleTimeout)
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout: must be positive, got %s", c.HTTP.ShutdownTimeout)
+	check(c.HTTP.CompressionMinSize >= 0, "http.compression_min_size: must not be negative, got %d", c.HTTP.CompressionMinSize)
+	u, err := url.Parse(c.HTTP.PublicURL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.RawQuery == "" && u.Fragment == "",
+		"http.public_url: must be an http(s) URL without query, got %q", c.HTTP.PublicURL)
+
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls: cert_file and key_file must be set together")
+	check(c.TLS.MinVersion == "1.2" || c.TLS.MinVersion == "1.3", "tls.min_version: must be 1.2 or 1.3, got %q", c.TLS.MinVersion)
+	check(c.TLS.ReloadInterval > 0, "tls.reload_interval: must be positive, got %s", c.TLS.ReloadInterval)
+	if !c.TLS.Enabled() {
+		check(c.TLS.ClientCAFile == "", "tls.client_ca_file: requires tls.cert_file and tls.key_file")
+		check(c.TLS.RedirectAddr == "", "tls.redirect_addr: requires tls.cert_file and tls.key_file")
+	} else if c.TLS.RedirectAddr != "" {
+		if _, _, err := net.SplitHostPort(c.TLS.RedirectAddr); err != nil {
+			errs = append(errs, fmt.Errorf("tls.redirect_addr: %w", err))
+		}
+		check(c.TLS.RedirectAddr != c.HTTP.Addr, "tls.redirect_addr: must differ from http.addr")
+	}
+
+	if c.DB.DSN == "" {
+		check(c.DB.Host != "", "db.host: required when db.dsn is not set")
+		check(c.DB.User != "", "db.user: required when db.dsn is not set")
+		check(c.DB.Name != "", "db.name: required when db.dsn is not set")
+		check(c.DB.Port > 0 && c.DB.Port < 65536, "db.port: must be in 1..65535, got %d", c.DB.Port)
+		check(slices.Contains(sslModes, c.DB.SSLMode), "db.sslmode: must be one of %s, got %q",
+			strings.Join(sslModes, ", "), c.DB.SSLMode)
+	}
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns: must not be negative, got %d", c.DB.MaxOpenConns)
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns: must not be negative, got %d", c.DB.MaxIdleConns)
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns,
+		"db.max_idle_conns: must not exceed db.max_open_conns (%d > %d)", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime: must not be negative, got %s", c.DB.ConnMaxLifetime)
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time: must not be negative, got %s", c.DB.ConnMaxIdleTime)
+	check(c.DB.ConnectTimeout > 0, "db.connect_timeout: must be positive, got %s", c.DB.ConnectTimeout)
+	check(c.DB.StatsInterval >= 0, "db.stats_interval: must not be negative, got %s", c.DB.StatsInterval)
+	check(len(c.DB.ReplicaDSNs) == 0 || c.DB.ReplicaCheckInterval > 0,
+		"db.replica_check_interval: must be positive, got %s", c.DB.ReplicaCheckInterval)
+
+	check(slices.Contains(logLevels, c.Log.Level), "log.level: must be one of %s, got %q",
+		strings.Join(logLevels, ", "), c.Log.Level)
+	check(slices.Contains(logFormats, c.Log.Format), "log.format: must be one of %s, got %q",
+		strings.Join(logFormats, ", "), c.Log.Format)
+
+	check(c.Health.CheckTimeout > 0, "health.check_timeout: must be positive, got %s", c.Health.CheckTimeout)
+	check(c.Metrics.BusinessInterval > 0, "metrics.business_interval: must be positive, got %s", c.Metrics.BusinessInterval)
+
+	check(slices.Contains(exporters, c.Tracing.Exporter), "tracing.exporter: must be one of %s, got %q",
+		strings.Join(exporters, ", "), c.Tracing.Exporter)
+	check(c.Tracing.ServiceName != "", "tracing.service_name: required")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sample_ratio: must be in 0..1, got %g", c.Tracing.SampleRatio)
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || validOrigin(origin), "cors.allowed_origins: %q is not an origin or a https://*.domain pattern", origin)
+	}
+	check(len(c.CORS.AllowedOrigins) == 0 || len(c.CORS.AllowedMethods) > 0, "cors.allowed_methods: required when CORS is enabled")
+	check(c.CORS.MaxAge >= 0, "cors.max_age: must not be negative, got %s", c.CORS.MaxAge)
+
+	if c.Tracing.OTLPEndpoint != "" {
+		u, err := url.Parse(c.Tracing.OTLPEndpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"tracing.otlp_endpoint: must be an http(s) URL, got %q", c.Tracing.OTLPEndpoint)
+	}
+
+	check(c.Seed.Users >= 0, "seed.users: must not be negative, got %d", c.Seed.Users)
+	check(c.Seed.Users == 0 || c.Seed.PerUser > 0, "seed.per_user: must be positive, got %d", c.Seed.PerUser)
+	check(c.Budgets.CheckInterval >= 0, "budgets.check_interval: must not be negative, got %s", c.Budgets.CheckInterval)
+	check(c.Webhooks.PollInterval >= 0, "webhooks.poll_interval: must not be negative, got %s", c.Webhooks.PollInterval)
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout: must be positive, got %s", c.Webhooks.Timeout)
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts: must be positive, got %d", c.Webhooks.MaxAttempts)
+	check(c.Webhooks.Backoff > 0, "webhooks.backoff: must be positive, got %s", c.Webhooks.Backoff)
+	check(c.Outbox.PollInterval >= 0, "outbox.poll_interval: must not be negative, got %s", c.Outbox.PollInterval)
+	check(c.Outbox.Retention > 0, "outbox.retention: must be positive, got %s", c.Outbox.Retention)
+	for _, p := range c.Outbox.Publishers {
+		check(slices.Contains(publishers, p), "outbox.publishers: must be a subset of %s, got %q", strings.Join(publishers, ", "), p)
+	}
+	if slices.Contains(c.Outbox.Publishers, "nats") {
+		u, err := url.Parse(c.Outbox.NATS.URL)
+		check(err == nil && u.Scheme == "nats" && u.Host != "", "outbox.nats.url: must be a nats://host:port URL, got %q", redactDSN(c.Outbox.NATS.URL))
+		check(c.Outbox.NATS.SubjectPrefix != "" && !strings.ContainsAny(c.Outbox.NATS.SubjectPrefix, " \t*>"),
+			"outbox.nats.subject_prefix: must be a non-empty subject without spaces or wildcards, got %q", c.Outbox.NATS.SubjectPrefix)
+		check(c.Outbox.NATS.Timeout > 0, "outbox.nats.timeout: must be positive, got %s", c.Outbox.NATS.Timeout)
+	}
+	check(slices.Contains(eventSources, c.Events.Source), "events.source: must be one of %s, got %q", strings.Join(eventSources, ", "), c.Events.Source)
+	check(c.Events.Source != "local" || c.Outbox.PollInterval > 0, "events.source: local requires the outbox relay, outbox.poll_interval is 0")
+	check(c.Events.Heartbeat > 0, "events.heartbeat: must be positive, got %s", c.Events.Heartbeat)
+	check(c.Events.History > 0, "events.history: must be positive, got %d", c.Events.History)
+	check(c.Notifications.CheckInterval >= 0, "notifications.check_interval: must not be negative, got %s", c.Notifications.CheckInterval)
+	if c.Notifications.SMTP.Addr != "" {
+		_, _, err := net.SplitHostPort(c.Notifications.SMTP.Addr)
+		check(err == nil, "notifications.smtp.addr: must be host:port, got %q", c.Notifications.SMTP.Addr)
+		_, err = mail.ParseAddress(c.Notifications.SMTP.From)
+		check(err == nil, "notifications.smtp.from: must be an email address, got %q", c.Notifications.SMTP.From)
+		check(c.Notifications.SMTP.Timeout > 0, "notifications.smtp.timeout: must be positive, got %s", c.Notifications.SMTP.Timeout)
+	}
+
+	return errors.Join(errs...)
+}
+
+func validOrigin(origin string) bool {
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		(u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.User == nil
+}
+
+// ConnectionString returns the DSN to pass to the postgres driver.
+func (d DB) ConnectionString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	parts := []string{
+		"host=" + quoteDSNValue(d.Host),
+		"port=" + strconv.Itoa(d.Port),
+		"user=" + quoteDSNValue(d.User),
+		"password=" + quoteDSNValue(d.Password),
+		"dbname=" + quoteDSNValue(d.Name),
+		"sslmode=" + quoteDSNValue(d.SSLMode),
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteDSNValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+const redacted = "*****"
+
+var dsnPasswordRe = regexp.MustCompile(`(password=)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Redacted returns a copy of the configuration with secrets masked.
+func (c Config) Redacted() Config {
+	for _, opt := range c.options() {
+		if !opt.secret {
+			continue
+		}
+		if p, ok := opt.target.(*string); ok && *p != "" {
+			*p = redacted
+		}
+	}
+	c.DB.DSN = redactDSN(c.DB.DSN)
+	replicas := make([]string, len(c.DB.ReplicaDSNs))
+	for i, dsn := range c.DB.ReplicaDSNs {
+		replicas[i] = redactDSN(dsn)
+	}
+	c.DB.ReplicaDSNs = replicas
+	c.Outbox.NATS.URL = redactDSN(c.Outbox.NATS.URL)
+	return c
+}
+
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	return dsnPasswordRe.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+// String renders the effective configuration as YAML with secrets masked.
+func (c Config) String() string {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("<unprintable config: %v>", err)
+	}
+	return string(out)
+}
+
+// LogValue makes the redacted configuration loggable as nested slog groups.
+func (c Config) LogValue() slog.Value {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return slog.StringValue(fmt.Sprintf("<unprintable config: %v>", err))
+	}
+	var tree yaml.MapSlice
+	if err = yaml.Unmarshal(out, &tree); err != nil {
+		return slog.StringValue(fmt.Sprintf("<unprintable config: %v>", err))
+	}
+	return mapSliceValue(tree)
+}
+
+func mapSliceValue(ms yaml.MapSlice) slog.Value {
+	attrs := make([]slog.Attr, 0, len(ms))
+	for _, item := range ms {
+		key := fmt.Sprint(item.Key)
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			attrs = append(attrs, slog.Attr{Key: key, Value: mapSliceValue(nested)})
+			continue
+		}
+		attrs = append(attrs, slog.Any(key, item.Value))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// option binds a single config field to its environment variable and command-line flag.
+type option struct {
+	env    string
+	flag   string
+	usage  string
+	target any
+	secret bool
+}
+
+func (c *Config) options() []option {
+	return []option{
+		{env: "HTTP_ADDR", flag: "http.addr", usage: "HTTP listen address", target: &c.HTTP.Addr},
+		{env: "HTTP_READ_TIMEOUT", flag: "http.read-timeout", usage: "HTTP read timeout", target: &c.HTTP.ReadTimeout},
+		{env: "HTTP_WRITE_TIMEOUT", flag: "http.write-timeout", usage: "HTTP write timeout", target: &c.HTTP.WriteTimeout},
+		{env: "HTTP_IDLE_TIMEOUT", flag: "http.idle-timeout", usage: "HTTP keep-alive idle timeout", target: &c.HTTP.IdleTimeout},
+		{env: "SHUTDOWN_TIMEOUT", flag: "http.shutdown-timeout", usage: "graceful shutdown deadline", target: &c.HTTP.ShutdownTimeout},
+		{env: "HTTP_COMPRESSION_MIN_SIZE", flag: "http.compression-min-size", usage: "smallest response body to compress, bytes", target: &c.HTTP.CompressionMinSize},
+		{env: "CRASH_REPORT_DIR", flag: "http.crash-report-dir", usage: "directory for handler panic reports, empty disables them", target: &c.HTTP.CrashReportDir},
+		{env: "PUBLIC_URL", flag: "http.public-url", usage: "URL clients reach the service at, used in the links it hands out", target: &c.HTTP.PublicURL},
+
+		{env: "TLS_CERT_FILE", flag: "tls.cert-file", usage: "PEM server certificate; enables TLS together with tls.key-file", target: &c.TLS.CertFile},
+		{env: "TLS_KEY_FILE", flag: "tls.key-file", usage: "PEM server private key", target: &c.TLS.KeyFile},
+		{env: "TLS_MIN_VERSION", flag: "tls.min-version", usage: "minimum TLS version: 1.2 or 1.3", target: &c.TLS.MinVersion},
+		{env: "TLS_CLIENT_CA_FILE", flag: "tls.client-ca-file", usage: "PEM CA bundle; requires and verifies client certificates (mTLS)", target: &c.TLS.ClientCAFile},
+		{env: "TLS_RELOAD_INTERVAL", flag: "tls.reload-interval", usage: "how often certificate files are checked for changes", target: &c.TLS.ReloadInterval},
+		{env: "TLS_REDIRECT_ADDR", flag: "tls.redirect-addr", usage: "plaintext listen address redirecting to HTTPS, empty disables it", target: &c.TLS.RedirectAddr},
+
+		{env: "POSTGRES_DSN", flag: "db.dsn", usage: "full postgres DSN, overrides the discrete db.* connection options", target: &c.DB.DSN},
+		{env: "POSTGRES_HOST", flag: "db.host", usage: "postgres host", target: &c.DB.Host},
+		{env: "POSTGRES_PORT", flag: "db.port", usage: "postgres port", target: &c.DB.Port},
+		{env: "POSTGRES_USER", flag: "db.user", usage: "postgres user", target: &c.DB.User},
+		{env: "POSTGRES_PASSWORD", flag: "db.password", usage: "postgres password", target: &c.DB.Password, secret: true},
+		{env: "POSTGRES_DB", flag: "db.name", usage: "postgres database name", target: &c.DB.Name},
+		{env: "POSTGRES_SSLMODE", flag: "db.sslmode", usage: "postgres sslmode", target: &c.DB.SSLMode},
+		{env: "POSTGRES_MAX_OPEN_CONNS", flag: "db.max-open-conns", usage: "max open connections, 0 is unlimited", target: &c.DB.MaxOpenConns},
+		{env: "POSTGRES_MAX_IDLE_CONNS", flag: "db.max-idle-conns", usage: "max idle connections", target: &c.DB.MaxIdleConns},
+		{env: "POSTGRES_CONN_MAX_LIFETIME", flag: "db.conn-max-lifetime", usage: "close connections older than this, 0 keeps them", target: &c.DB.ConnMaxLifetime},
+		{env: "POSTGRES_CONN_MAX_IDLE_TIME", flag: "db.conn-max-idle-time", usage: "close connections idle longer than this, 0 keeps them", target: &c.DB.ConnMaxIdleTime},
+		{env: "POSTGRES_CONNECT_TIMEOUT", flag: "db.connect-timeout", usage: "how long to retry the initial connection", target: &c.DB.ConnectTimeout},
+		{env: "POSTGRES_STATS_INTERVAL", flag: "db.stats-interval", usage: "how often connection pool stats are logged, 0 disables it", target: &c.DB.StatsInterval},
+		{env: "POSTGRES_REPLICA_DSNS", flag: "db.replica-dsns", usage: "comma-separated read replica DSNs", target: &c.DB.ReplicaDSNs},
+		{env: "POSTGRES_REPLICA_CHECK_INTERVAL", flag: "db.replica-check-interval", usage: "how often replica health is checked", target: &c.DB.ReplicaCheckInterval},
+
+		{env: "LOG_LEVEL", flag: "log.level", usage: "log level: debug, info, warn, error", target: &c.Log.Level},
+		{env: "LOG_FORMAT", flag: "log.format", usage: "log format: text, json", target: &c.Log.Format},
+
+		{env: "HEALTH_CHECK_TIMEOUT", flag: "health.check-timeout", usage: "timeout of a single readiness check", target: &c.Health.CheckTimeout},
+
+		{env: "METRICS_BUSINESS_INTERVAL", flag: "metrics.business-interval", usage: "refresh interval of the business gauges", target: &c.Metrics.BusinessInterval},
+
+		{env: "TRACING_EXPORTER", flag: "tracing.exporter", usage: "trace exporter: none, stdout, otlp", target: &c.Tracing.Exporter},
+		{env: "TRACING_SERVICE_NAME", flag: "tracing.service-name", usage: "service.name resource attribute", target: &c.Tracing.ServiceName},
+		{env: "TRACING_OTLP_ENDPOINT", flag: "tracing.otlp-endpoint", usage: "OTLP/HTTP traces URL", target: &c.Tracing.OTLPEndpoint},
+		{env: "TRACING_SAMPLE_RATIO", flag: "tracing.sample-ratio", usage: "fraction of new traces to sample, 0..1", target: &c.Tracing.SampleRatio},
+
+		{env: "CORS_ALLOWED_ORIGINS", flag: "cors.allowed-origins", usage: "comma-separated origins, https://*.domain patterns or *; empty disables CORS", target: &c.CORS.AllowedOrigins},
+		{env: "CORS_ALLOWED_METHODS", flag: "cors.allowed-methods", usage: "comma-separated methods allowed cross-origin", target: &c.CORS.AllowedMethods},
+		{env: "CORS_ALLOWED_HEADERS", flag: "cors.allowed-headers", usage: "comma-separated request headers allowed cross-origin, * for any", target: &c.CORS.AllowedHeaders},
+		{env: "CORS_EXPOSED_HEADERS", flag: "cors.exposed-headers", usage: "comma-separated response headers exposed to scripts", target: &c.CORS.ExposedHeaders},
+		{env: "CORS_ALLOW_CREDENTIALS", flag: "cors.allow-credentials", usage: "allow cookies and authorization headers", target: &c.CORS.AllowCredentials},
+		{env: "CORS_MAX_AGE", flag: "cors.max-age", usage: "how long browsers may cache a preflight", target: &c.CORS.MaxAge},
+		{env: "CORS_SWAGGER", flag: "cors.swagger", usage: "apply CORS to /swagger/ as well", target: &c.CORS.Swagger},
+
+		{env: "FEATURE_SWAGGER", flag: "features.swagger", usage: "serve the swagger UI", target: &c.Features.Swagger},
+		{env: "FEATURE_METRICS", flag: "features.metrics", usage: "serve Prometheus metrics on /metrics", target: &c.Features.Metrics},
+		{env: "FEATURE_COMPRESSION", flag: "features.compression", usage: "gzip/brotli compression of API responses", target: &c.Features.Compression},
+
+		{env: "SEED_USERS", flag: "seed.users", usage: "generate this many users with demo subscriptions when the database is empty, 0 disables it", target: &c.Seed.Users},
+		{env: "SEED_PER_USER", flag: "seed.per-user", usage: "demo subscriptions per generated user", target: &c.Seed.PerUser},
+		{env: "SEED_SEED", flag: "seed.seed", usage: "random seed of the demo data", target: &c.Seed.Seed},
+		{env: "SEED_CATALOGUE", flag: "seed.catalogue", usage: "YAML/JSON file with the services and prices to draw from", target: &c.Seed.Catalogue},
+
+		{env: "BUDGETS_CHECK_INTERVAL", flag: "budgets.check-interval", usage: "how often budgets are checked for threshold alerts, 0 disables it", target: &c.Budgets.CheckInterval},
+
+		{env: "WEBHOOKS_POLL_INTERVAL", flag: "webhooks.poll-interval", usage: "how often queued webhook deliveries are sent, 0 disables sending", target: &c.Webhooks.PollInterval},
+		{env: "WEBHOOKS_TIMEOUT", flag: "webhooks.timeout", usage: "timeout of a single webhook request", target: &c.Webhooks.Timeout},
+		{env: "WEBHOOKS_MAX_ATTEMPTS", flag: "webhooks.max-attempts", usage: "attempts per delivery before it goes to the dead-letter queue", target: &c.Webhooks.MaxAttempts},
+		{env: "WEBHOOKS_BACKOFF", flag: "webhooks.backoff", usage: "delay after the first failed attempt, doubled after each further one", target: &c.Webhooks.Backoff},
+		{env: "WEBHOOKS_ALLOW_PRIVATE", flag: "webhooks.allow-private", usage: "allow webhook URLs on loopback, private and link-local addresses", target: &c.Webhooks.AllowPrivate},
+
+		{env: "OUTBOX_POLL_INTERVAL", flag: "outbox.poll-interval", usage: "how often the outbox relay looks for new events, 0 disables it", target: &c.Outbox.PollInterval},
+		{env: "OUTBOX_PUBLISHERS", flag: "outbox.publishers", usage: "comma-separated event publishers: webhook, log, nats", target: &c.Outbox.Publishers},
+		{env: "OUTBOX_RETENTION", flag: "outbox.retention", usage: "how long published events stay in the outbox", target: &c.Outbox.Retention},
+		{env: "NATS_URL", flag: "outbox.nats.url", usage: "nats://[user:password@]host:port of the NATS server", target: &c.Outbox.NATS.URL},
+		{env: "NATS_SUBJECT_PREFIX", flag: "outbox.nats.subject-prefix", usage: "subject prefix of published events", target: &c.Outbox.NATS.SubjectPrefix},
+		{env: "NATS_JETSTREAM", flag: "outbox.nats.jetstream", usage: "wait for JetStream acknowledgements", target: &c.Outbox.NATS.JetStream},
+		{env: "NATS_TIMEOUT", flag: "outbox.nats.timeout", usage: "timeout of connecting and publishing to NATS", target: &c.Outbox.NATS.Timeout},
+
+		{env: "EVENTS_SOURCE", flag: "events.source", usage: "what feeds the event streams: postgres or local", target: &c.Events.Source},
+		{env: "EVENTS_HEARTBEAT", flag: "events.heartbeat", usage: "how often an idle event stream gets a heartbeat", target: &c.Events.Heartbeat},
+		{env: "EVENTS_HISTORY", flag: "events.history", usage: "how many recent events are kept for resuming event streams", target: &c.Events.History},
+
+		{env: "NOTIFICATIONS_CHECK_INTERVAL", flag: "notifications.check-interval", usage: "how often due email reminders are sent, 0 disables them", target: &c.Notifications.CheckInterval},
+		{env: "SMTP_ADDR", flag: "notifications.smtp.addr", usage: "host:port of the SMTP server, empty to only log reminders", target: &c.Notifications.SMTP.Addr},
+		{env: "SMTP_USERNAME", flag: "notifications.smtp.username", usage: "SMTP user, empty for no authentication", target: &c.Notifications.SMTP.Username},
+		{env: "SMTP_PASSWORD", flag: "notifications.smtp.password", usage: "SMTP password", target: &c.Notifications.SMTP.Password, secret: true},
+		{env: "SMTP_FROM", flag: "notifications.smtp.from", usage: "sender address of reminders", target: &c.Notifications.SMTP.From},
+		{env: "SMTP_TIMEOUT", flag: "notifications.smtp.timeout", usage: "timeout of sending one email", target: &c.Notifications.SMTP.Timeout},
+	}
+}
+
+// Load builds the configuration for a program invoked with args (without the
+// program name). The config file is taken from the -config flag or the
+// CONFIG_FILE environment variable. flag.ErrHelp is returned for -h.
+func Load(name string, args []string) (Config, error) {
+	cfg, rest, err := LoadCommand(name, args)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(rest) > 0 {
+		return Config{}, fmt.Errorf("unexpected argument %q", rest[0])
+	}
+	return cfg, nil
+}
+
+// LoadCommand is Load for programs with subcommands: flag parsing stops at the
+// first non-flag argument, which is returned with everything after it.
+func LoadCommand(name string, args []string) (Config, []string, error) {
+	cfg := Default()
+	opts := cfg.options()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or JSON config file")
+
+	type flagValue struct {
+		opt   option
+		value string
+	}
+	var flagValues []flagValue
+	for _, opt := range opts {
+		record := func(v string) error {
+			flagValues = append(flagValues, flagValue{opt: opt, value: v})
+			return nil
+		}
+		usage := fmt.Sprintf("%s (env %s)", opt.usage, opt.env)
+		if _, ok := opt.target.(*bool); ok {
+			fs.BoolFunc(opt.flag, usage, record)
+		} else {
+			fs.Func(opt.flag, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configPath != "" {
+		if err := loadFile(*configPath, &cfg); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	for _, opt := range opts {
+		v, ok := os.LookupEnv(opt.env)
+		if !ok || v == "" {
+			continue
+		}
+		if err := set(opt.target, v); err != nil {
+			return Config{}, nil, fmt.Errorf("env %s: %w", opt.env, err)
+		}
+	}
+
+	for _, fv := range flagValues {
+		if err := set(fv.opt.target, fv.value); err != nil {
+			return Config{}, nil, fmt.Errorf("flag -%s: %w", fv.opt.flag, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile decodes a YAML or JSON file over cfg. JSON is re-encoded as YAML
+// first so both formats share the same field names and duration syntax ("5s").
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var raw any
+		if err = json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+		if data, err = yaml.Marshal(raw); err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+	if err = yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func set(target any, v string) error {
+	switch p := target.(type) {
+	case *string:
+		*p = v
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		*p = f
+	case *bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*p = d
+	case *[]string:
+		*p = nil
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported option type %T", target)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate clears every configuration variable, so that the environment the
+// tests run in does not leak into them; empty variables are ignored by Load.
+func isolate(t *testing.T) {
+	t.Helper()
+	var c Config
+	for _, opt := range c.options() {
+		t.Setenv(opt.env, "")
+	}
+	t.Setenv("CONFIG_FILE", "")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const baseYAML = `
+http:
+  addr: ":9000"
+log:
+  level: warn
+  format: json
+db:
+  host: file-host
+  user: file-user
+  name: file-db
+`
+
+func TestLoadPrecedence(t *testing.T) {
+	isolate(t)
+	path := writeFile(t, "config.yaml", baseYAML)
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("POSTGRES_HOST", "env-host")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+
+	cfg, err := Load("test", []string{"-config", path, "-db.host", "flag-host", "-features.swagger=false"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"default", cfg.HTTP.ReadTimeout, 5 * time.Second},
+		{"default untouched by file", cfg.DB.Port, 5432},
+		{"file over default", cfg.HTTP.Addr, ":9000"},
+		{"file only", cfg.Log.Format, "json"},
+		{"env over file", cfg.Log.Level, "error"},
+		{"flag over env and file", cfg.DB.Host, "flag-host"},
+		{"boolean flag", cfg.Features.Swagger, false},
+		{"list from env", strings.Join(cfg.CORS.AllowedOrigins, " "), "https://a.example.com https://b.example.com"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	isolate(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.json", `{"db": {"host": "json-host", "user": "u", "name": "n", "connect_timeout": "1m"}}`))
+
+	cfg, err := Load("test", nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.Host != "json-host" || cfg.DB.ConnectTimeout != time.Minute {
+		t.Errorf("db = %+v, want host json-host and connect_timeout 1m from the JSON file", cfg.DB)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want []string
+	}{
+		{
+			name: "unknown key",
+			file: baseYAML + "  hostname: typo\n",
+			want: []string{"hostname"},
+		},
+		{
+			name: "unknown section",
+			file: baseYAML + "metrix:\n  business_interval: 1m\n",
+			want: []string{"metrix"},
+		},
+		{
+			name: "invalid env value",
+			file: baseYAML,
+			env:  map[string]string{"POSTGRES_PORT": "five"},
+			want: []string{"env POSTGRES_PORT", `invalid integer "five"`},
+		},
+		{
+			name: "invalid flag value",
+			file: baseYAML,
+			args: []string{"-http.read-timeout", "soon"},
+			want: []string{"flag -http.read-timeout", `invalid duration "soon"`},
+		},
+		{
+			name: "every validation error at once",
+			file: baseYAML,
+			env:  map[string]string{"LOG_LEVEL": "loud", "POSTGRES_PORT": "0"},
+			args: []string{"-http.read-timeout", "0s", "-http.public-url", "ftp://example.com"},
+			want: []string{
+				"invalid configuration",
+				"log.level: must be one of",
+				`got "loud"`,
+				"db.port: must be in 1..65535, got 0",
+				"http.read_timeout: must be positive, got 0s",
+				"http.public_url",
+			},
+		},
+		{
+			name: "unexpected argument",
+			file: baseYAML,
+			args: []string{"serve"},
+			want: []string{`unexpected argument "serve"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := append([]string{"-config", writeFile(t, "config.yaml", tt.file)}, tt.args...)
+			_, err := Load("test", args)
+			if err == nil {
+				t.Fatal("Load succeeded, want an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateJoinsErrors(t *testing.T) {
+	cfg := Default()
+	cfg.DB.Host, cfg.DB.User, cfg.DB.Name = "h", "u", "n"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults with a database: %v", err)
+	}
+
+	cfg.HTTP.WriteTimeout = 0
+	cfg.DB.Port = 0
+	cfg.Log.Format = "xml"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded, want errors")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 {
+		t.Errorf("got %d errors, want 3:\n%s", len(lines), err)
+	}
+}
@@ -0,0 +1,161 @@
+package cors
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Options struct {
+	// AllowedOrigins holds exact origins ("https://app.example.com"), wildcard
+	// subdomain patterns ("https://*.example.com") or "*" for any origin.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type CORS struct {
+	anyOrigin      bool
+	origins        []string
+	patterns       []pattern
+	methods        []string
+	headers        []string
+	anyHeader      bool
+	exposedHeaders string
+	credentials    bool
+	maxAge         string
+}
+
+type pattern struct {
+	prefix, suffix string
+}
+
+func New(opts Options) *CORS {
+	c := &CORS{
+		credentials:    opts.AllowCredentials,
+		exposedHeaders: strings.Join(opts.ExposedHeaders, ", "),
+	}
+	for _, o := range opts.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		switch {
+		case o == "*":
+			c.anyOrigin = true
+		case strings.Contains(o, "://*."):
+			prefix, suffix, _ := strings.Cut(o, "*")
+			c.patterns = append(c.patterns, pattern{prefix: prefix, suffix: suffix})
+		default:
+			c.origins = append(c.origins, o)
+		}
+	}
+	for _, m := range opts.AllowedMethods {
+		c.methods = append(c.methods, strings.ToUpper(m))
+	}
+	for _, h := range opts.AllowedHeaders {
+		if h == "*" {
+			c.anyHeader = true
+			continue
+		}
+		c.headers = append(c.headers, http.CanonicalHeaderKey(h))
+	}
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return c
+}
+
+// Handler answers preflight requests itself and decorates the other
+// cross-origin responses of next. Requests from origins that are not allowed
+// get no CORS headers, so the browser blocks them.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if origin != "" && c.originAllowed(origin) {
+				c.preflight(w, r, origin)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if origin != "" && c.originAllowed(origin) {
+			c.setOrigin(h, origin)
+			if c.exposedHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", c.exposedHeaders)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !slices.Contains(c.methods, method) {
+		return
+	}
+	var requested []string
+	for _, field := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			requested = append(requested, http.CanonicalHeaderKey(field))
+		}
+	}
+	if !c.anyHeader {
+		for _, header := range requested {
+			if !slices.Contains(c.headers, header) {
+				return
+			}
+		}
+	}
+
+	h := w.Header()
+	c.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+	if len(requested) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+}
+
+func (c *CORS) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin && !c.credentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		// Browsers reject "*" together with credentials, so the origin is echoed instead.
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *CORS) originAllowed(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(c.origins, origin) {
+		return true
+	}
+	for _, p := range c.patterns {
+		if len(origin) <= len(p.prefix)+len(p.suffix) ||
+			!strings.HasPrefix(origin, p.prefix) || !strings.HasSuffix(origin, p.suffix) {
+			continue
+		}
+		sub := origin[len(p.prefix) : len(origin)-len(p.suffix)]
+		if !strings.ContainsAny(sub, "/:@") {
+			return true
+		}
+	}
+	return false
+}
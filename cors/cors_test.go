@@ -0,0 +1,162 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	c := New(Options{AllowedOrigins: []string{"https://app.example.com/", "https://*.example.org"}})
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://other.example.com", false},
+		{"https://api.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://.example.org", false},
+		{"https://evil-example.org", false},
+		{"https://evilexample.org", false},
+		{"https://api.example.org.evil.com", false},
+		{"https://evil.com/.example.org", false},
+		{"https://user@evil.com:x.example.org", false},
+		{"http://api.example.org", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := c.originAllowed(tt.origin); got != tt.want {
+			t.Errorf("originAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	if !New(Options{AllowedOrigins: []string{"*"}}).originAllowed("https://anything.test") {
+		t.Error(`"*" does not allow every origin`)
+	}
+}
+
+func newTestHandler(opts Options) (http.Handler, *bool) {
+	called := new(bool)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*called = true
+		w.WriteHeader(http.StatusOK)
+	})
+	return New(opts).Handler(next), called
+}
+
+var testOptions = Options{
+	AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+	AllowedMethods: []string{"GET", "POST", "PUT"},
+	AllowedHeaders: []string{"Content-Type", "X-Request-ID"},
+	ExposedHeaders: []string{"X-Request-ID"},
+	MaxAge:         10 * time.Minute,
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name        string
+		origin      string
+		method      string
+		headers     string
+		wantAllowed bool
+	}{
+		{"exact origin", "https://app.example.com", "PUT", "content-type, x-request-id", true},
+		{"wildcard origin", "https://api.example.org", "POST", "", true},
+		{"suffix spoof", "https://evil-example.org", "POST", "", false},
+		{"disallowed origin", "https://evil.com", "GET", "", false},
+		{"disallowed method", "https://app.example.com", "DELETE", "", false},
+		{"disallowed header", "https://app.example.com", "GET", "Authorization", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, called := newTestHandler(testOptions)
+			r := httptest.NewRequest(http.MethodOptions, "/subscriptions", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+			}
+			if *called {
+				t.Error("preflight reached the wrapped handler")
+			}
+			vary := w.Header().Values("Vary")
+			for _, want := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+				if !slices.Contains(vary, want) {
+					t.Errorf("Vary = %q, missing %s", vary, want)
+				}
+			}
+
+			allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+			if !tt.wantAllowed {
+				if allowOrigin != "" || w.Header().Get("Access-Control-Allow-Methods") != "" {
+					t.Errorf("rejected preflight got CORS headers: %v", w.Header())
+				}
+				return
+			}
+			if allowOrigin != tt.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", allowOrigin, tt.origin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT" {
+				t.Errorf("Access-Control-Allow-Methods = %q", got)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+			}
+			if tt.headers != "" && w.Header().Get("Access-Control-Allow-Headers") != "Content-Type, X-Request-Id" {
+				t.Errorf("Access-Control-Allow-Headers = %q", w.Header().Get("Access-Control-Allow-Headers"))
+			}
+		})
+	}
+}
+
+func TestSimpleRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Options
+		origin     string
+		wantOrigin string
+	}{
+		{"allowed origin", testOptions, "https://api.example.org", "https://api.example.org"},
+		{"disallowed origin", testOptions, "https://evil-example.org", ""},
+		{"no origin", testOptions, "", ""},
+		{"any origin", Options{AllowedOrigins: []string{"*"}}, "https://evil.com", "*"},
+		{"any origin with credentials", Options{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "https://evil.com", "https://evil.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, called := newTestHandler(tt.opts)
+			r := httptest.NewRequest(http.MethodGet, "/subscriptions", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if !*called || w.Code != http.StatusOK {
+				t.Errorf("request did not reach the wrapped handler (status %d)", w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if !slices.Contains(w.Header().Values("Vary"), "Origin") {
+				t.Errorf("Vary = %q, missing Origin", w.Header().Values("Vary"))
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); (got == "true") != tt.opts.AllowCredentials || (tt.wantOrigin == "" && got != "") {
+				t.Errorf("Access-Control-Allow-Credentials = %q", got)
+			}
+		})
+	}
+}
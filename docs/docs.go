@@ -10,7 +10,7 @@ const docTemplate = `{
         "description": "{{escape .Description}}",
         "title": "{{.Title}}",
         "contact": {
-            "name": "API Support",
+            "name": "Oleg (API Author):",
             "url": "https://github.com/BrikozO",
             "email": "oleg.yakushev.work@gmail.com"
         },
@@ -19,9 +19,53 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/calculate": {
+        "/api/v1/budgets": {
+            "get": {
+                "description": "List the monthly budgets of a user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "List budgets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.BudgetDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Calculate total sum for subscriptions in given period",
+                "description": "Add a monthly spending limit for a user, on all subscriptions or only on one category or service. Alerts are raised when the month's cost reaches 80% and 100% of the limit.",
                 "consumes": [
                     "application/json"
                 ],
@@ -29,27 +73,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "budgets"
                 ],
-                "summary": "Calculate subscription sum",
+                "summary": "Create budget",
                 "parameters": [
                     {
-                        "description": "Calculation request",
-                        "name": "calculation",
+                        "description": "Budget data",
+                        "name": "budget",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.CalculationRequestDTO"
+                            "$ref": "#/definitions/models.Budget"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Returns total price",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "type": "object",
                             "properties": {
-                                "price": {
+                                "id": {
                                     "type": "integer"
                                 }
                             }
@@ -58,7 +102,7 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.problem"
                         }
                     },
                     "500": {
@@ -70,35 +114,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/subscriptions": {
-            "post": {
-                "description": "Create a new subscription. Dates should be in MM-YYYY format (e.g., \"01-2024\").",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/budgets/{budget_id}": {
+            "get": {
+                "description": "Get a budget by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "budgets"
                 ],
-                "summary": "Create subscription",
+                "summary": "Get budget",
                 "parameters": [
                     {
-                        "description": "Subscription data",
-                        "name": "subscription",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Subscription"
-                        }
+                        "type": "integer",
+                        "description": "Budget ID",
+                        "name": "budget_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/dto.BudgetDTO"
                         }
                     },
                     "400": {
@@ -107,6 +146,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -114,11 +159,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/subscriptions/{subscription_id}": {
+            },
             "put": {
-                "description": "Update an existing subscription by ID. Dates should be in MM-YYYY format.",
+                "description": "Replace a budget. Alerts already raised this month are not repeated.",
                 "consumes": [
                     "application/json"
                 ],
@@ -126,24 +169,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "budgets"
                 ],
-                "summary": "Update subscription",
+                "summary": "Update budget",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Subscription ID",
-                        "name": "subscription_id",
+                        "description": "Budget ID",
+                        "name": "budget_id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated subscription data",
-                        "name": "subscription",
+                        "description": "Budget data",
+                        "name": "budget",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Subscription"
+                            "$ref": "#/definitions/models.Budget"
                         }
                     }
                 ],
@@ -157,7 +200,7 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.problem"
                         }
                     },
                     "404": {
@@ -175,34 +218,25 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a subscription by ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Remove a budget together with its alert history",
                 "tags": [
-                    "subscriptions"
+                    "budgets"
                 ],
-                "summary": "Delete subscription",
+                "summary": "Delete budget",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Subscription ID",
-                        "name": "subscription_id",
+                        "description": "Budget ID",
+                        "name": "budget_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -226,9 +260,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/subscriptions/{user_id}": {
-            "get": {
-                "description": "Get all subscriptions for a specific user",
+        "/api/v1/calculate": {
+            "post": {
+                "description": "Calculate what subscriptions are charged over the given period: trial and promo months at their own prices, the regular price once per billing period at the price in effect after scheduled price changes. Optionally narrow it to a category and tags, and break it down with group_by.\nBehaviour change: the price of a subscription used to be counted once for any overlap with the period; it is now counted for every charge falling in the period, that is every month it is active in for monthly billing.",
                 "consumes": [
                     "application/json"
                 ],
@@ -238,14 +272,55 @@ const docTemplate = `{
                 "tags": [
                     "subscriptions"
                 ],
-                "summary": "Get user subscriptions",
+                "summary": "Calculate subscription sum",
+                "parameters": [
+                    {
+                        "description": "Calculation request",
+                        "name": "calculation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CalculationRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns total price",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CalculationResultDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/calendar/{token}": {
+            "get": {
+                "description": "RFC 5545 feed of the user's charges from the previous month to twelve months ahead, plus a reminder on the last day of each trial. Quarterly and yearly charges are one event in the month they are made. Event UIDs depend only on the subscription and the month, so calendar apps update events after a subscription changes instead of duplicating them.",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Calendar feed",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "uuid",
-                        "example": "550e8400-e29b-41d4-a716-446655440000",
-                        "description": "User ID (UUID)",
-                        "name": "user_id",
+                        "description": "Feed token, optionally followed by .ics",
+                        "name": "token",
                         "in": "path",
                         "required": true
                     }
@@ -254,14 +329,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Subscription"
-                            }
+                            "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "string"
                         }
@@ -275,9 +347,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/subscriptions/{user_id}/{subscription_id}": {
+        "/api/v1/services": {
             "get": {
-                "description": "Get a specific subscription by user ID and subscription ID",
+                "description": "List the service catalogue ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "services"
+                ],
+                "summary": "List services",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.ServiceDTO"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a catalogue entry. The name and every alias must not match another service, ignoring case and whitespace.",
                 "consumes": [
                     "application/json"
                 ],
@@ -285,23 +384,68 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "services"
                 ],
-                "summary": "Get subscription by ID",
+                "summary": "Create service",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "example": "550e8400-e29b-41d4-a716-446655440000",
-                        "description": "User ID (UUID)",
-                        "name": "user_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Service data",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Service"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "id": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
                     },
+                    "409": {
+                        "description": "Name or alias already used",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/services/{service_id}": {
+            "get": {
+                "description": "Get a catalogue entry by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "services"
+                ],
+                "summary": "Get service",
+                "parameters": [
                     {
                         "type": "integer",
-                        "description": "Subscription ID",
-                        "name": "subscription_id",
+                        "description": "Service ID",
+                        "name": "service_id",
                         "in": "path",
                         "required": true
                     }
@@ -310,7 +454,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Subscription"
+                            "$ref": "#/definitions/dto.ServiceDTO"
                         }
                     },
                     "400": {
@@ -332,20 +476,1573 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "dto.CalculationRequestDTO": {
-            "type": "object",
-            "properties": {
-                "end_date": {
-                    "type": "string",
-                    "format": "MM-YYYY",
-                    "example": "12-2024"
-                },
-                "service_name": {
-                    "type": "string",
+            },
+            "put": {
+                "description": "Replace a catalogue entry. Subscriptions linked to it are renamed to the new name; when that would give a user two overlapping subscriptions to the same service, nothing is changed and the answer is 409 with conflicting_subscription_id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "services"
+                ],
+                "summary": "Update service",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Service ID",
+                        "name": "service_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Service data",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Service"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Name or alias already used, or a renamed subscription would overlap another",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a catalogue entry. Its subscriptions keep their service_name and lose the service_id link.",
+                "tags": [
+                    "services"
+                ],
+                "summary": "Delete service",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Service ID",
+                        "name": "service_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions": {
+            "post": {
+                "description": "Create a new subscription. Dates should be in MM-YYYY format (e.g., \"01-2024\").",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Create subscription",
+                "parameters": [
+                    {
+                        "description": "Subscription data",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Overlaps a subscription to the same service",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unknown service_id",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{subscription_id}": {
+            "put": {
+                "description": "Update an existing subscription by ID. Dates should be in MM-YYYY format.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Update subscription",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "subscription_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated subscription data",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Overlaps a subscription to the same service",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unknown service_id",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a subscription by ID. The row is soft-deleted and hidden from every read until purged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Delete subscription",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "subscription_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{user_id}": {
+            "get": {
+                "description": "Get all subscriptions for a specific user. Rows are streamed from the database; send ` + "`" + `Accept: application/x-ndjson` + "`" + ` to get one JSON object per line instead of an array.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get user subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Effective category, case-insensitive",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "family,work",
+                        "description": "Comma-separated tags",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "any",
+                            "all"
+                        ],
+                        "type": "string",
+                        "default": "any",
+                        "description": "Match any or all of the tags",
+                        "name": "tag_mode",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.SubscriptionDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "The user has no subscriptions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{user_id}/calendar": {
+            "post": {
+                "description": "Create the private iCalendar feed URL of a user. Calling it again issues a new token and the previous URL stops working.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Issue calendar feed token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "token": {
+                                    "type": "string"
+                                },
+                                "url": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Disable the iCalendar feed URL of a user",
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Revoke calendar feed token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{user_id}/events": {
+            "get": {
+                "description": "Server-sent events of the user's subscription changes: subscription.created, subscription.updated, subscription.ended and subscription.deleted, with the subscription as data and the event id as id. A reconnecting client sends the last id it received in Last-Event-ID and first gets the events it missed. When they are no longer remembered the stream starts with a reset event, after which the client should reload the subscriptions. Idle streams get a comment line as heartbeat.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Stream subscription changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Same as Last-Event-ID, for clients that cannot set headers",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{user_id}/notifications": {
+            "get": {
+                "description": "Get the email and preferences of a user's reminders of upcoming charges and trial ends",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Get reminder settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.NotificationSettingsDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create or replace a user's reminder settings. Reminders are emailed days_before days ahead of every paid charge (charges fall on the first day of the month) and of the last day of every trial. Each reminder is sent once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Set reminder settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reminder settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationSettings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a user's reminder settings, which stops all of their reminders",
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Stop reminders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{user_id}/schedule": {
+            "get": {
+                "description": "Expand the user's subscriptions into dated charges over a period. Trial and promo months are charged monthly at their own prices; the regular price is charged once per billing period (monthly, quarterly or yearly) at the price in effect after the scheduled price changes, and paid_through tells which months a charge covers. Every month of the period is listed with its total, months without charges included; the totals match /calculate for the same user and period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get charge schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "01-2025",
+                        "description": "First month, MM-YYYY; defaults to the current month",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "12-2025",
+                        "description": "Last month, MM-YYYY; defaults to 11 months after from",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ScheduleDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{user_id}/{subscription_id}": {
+            "get": {
+                "description": "Get a specific subscription by user ID and subscription ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User ID (UUID)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "subscription_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/trials": {
+            "get": {
+                "description": "List subscriptions whose trial ends in the given month, so that they can be cancelled before the paid months start. Subscriptions already ending that month are left out.",
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "List ending trials",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "03-2025",
+                        "description": "Last trial month, MM-YYYY; defaults to the current month",
+                        "name": "month",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only this user's subscriptions",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.SubscriptionDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks": {
+            "get": {
+                "description": "List the registered webhooks; secrets are not returned",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.WebhookDTO"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Register a URL for subscription events: subscription.created, subscription.updated, subscription.ended (an update set the end date of an open-ended subscription) and subscription.deleted. Every delivery is a POST signed in X-Webhook-Signature as \"sha256=\" plus the hex HMAC-SHA256 of \"\u003cX-Webhook-Timestamp\u003e.\u003cbody\u003e\" with the secret. The secret is generated when omitted and is only shown in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook data",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "id": {
+                                    "type": "integer"
+                                },
+                                "secret": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/deliveries": {
+            "get": {
+                "description": "List deliveries, newest first. status=failed is the dead-letter queue: deliveries that used up their attempts.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Only deliveries of this webhook",
+                        "name": "webhook_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Delivery status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of deliveries, 1..500",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.DeliveryDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/deliveries/{delivery_id}/redeliver": {
+            "post": {
+                "description": "Queue a delivery again with a fresh set of attempts, typically one from the dead-letter queue. The body and X-Webhook-Id stay the same, so receivers can drop duplicates.",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{webhook_id}": {
+            "get": {
+                "description": "Get a webhook by ID; the secret is not returned",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebhookDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace a webhook. An omitted secret keeps the current one. Deliveries already queued are sent to the new URL.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Update webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook data",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a webhook together with its queued and past deliveries",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Reports that the process is alive. Does not check dependencies.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Runs every registered dependency check and reports each result with its latency",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.BudgetDTO": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Category and ServiceName narrow the budget; at most one may be set.",
+                    "type": "string",
+                    "example": "video"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "monthly_limit": {
+                    "type": "integer",
+                    "example": 2000
+                },
+                "service_name": {
+                    "type": "string",
+                    "example": "Netflix"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "dto.CalculationGroup": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "example": "video"
+                },
+                "price": {
+                    "type": "string",
+                    "example": "1998"
+                }
+            }
+        },
+        "dto.CalculationRequestDTO": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Category matches the effective category, ignoring case.",
+                    "type": "string",
+                    "example": "video"
+                },
+                "end_date": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "12-2024"
+                },
+                "group_by": {
+                    "description": "GroupBy adds a per-category or per-tag breakdown to the total.",
+                    "type": "string",
+                    "enum": [
+                        "category",
+                        "tag"
+                    ],
+                    "example": "category"
+                },
+                "service_name": {
+                    "type": "string",
+                    "example": "Netflix"
+                },
+                "start_date": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "01-2024"
+                },
+                "tag_mode": {
+                    "description": "TagMode is \"any\" (the default) or \"all\".",
+                    "type": "string",
+                    "enum": [
+                        "any",
+                        "all"
+                    ],
+                    "example": "any"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "family"
+                    ]
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "dto.CalculationResultDTO": {
+            "type": "object",
+            "properties": {
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.CalculationGroup"
+                    }
+                },
+                "price": {
+                    "type": "string",
+                    "example": "2997"
+                }
+            }
+        },
+        "dto.ChargeDTO": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "Date is the day of the charge, the first day of its month.",
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-02-01"
+                },
+                "paid_through": {
+                    "description": "PaidThrough is the last month the charge pays for; later than the\ncharge month for quarterly and yearly billing.",
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "02-2025"
+                },
+                "phase": {
+                    "description": "Phase is trial, promo or regular.",
+                    "type": "string",
+                    "example": "regular"
+                },
+                "price": {
+                    "type": "integer",
+                    "example": 999
+                },
+                "service_name": {
+                    "type": "string",
+                    "example": "Netflix"
+                },
+                "subscription_id": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "dto.DeliveryDTO": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "subscription.created"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status": {
+                    "type": "integer",
+                    "example": 502
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "failed"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.NotificationSettingsDTO": {
+            "type": "object",
+            "properties": {
+                "charge_reminders": {
+                    "description": "ChargeReminders and TrialReminders are true when omitted.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "days_before": {
+                    "description": "DaysBefore is how many days ahead reminders are sent, 3 when omitted.",
+                    "type": "integer",
+                    "maximum": 30,
+                    "minimum": 1,
+                    "example": 3
+                },
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "locale": {
+                    "description": "Locale is ru when omitted.",
+                    "type": "string",
+                    "enum": [
+                        "ru",
+                        "en"
+                    ],
+                    "example": "ru"
+                },
+                "trial_reminders": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "dto.ScheduleDTO": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "01-2025"
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ScheduleMonth"
+                    }
+                },
+                "to": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "03-2025"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 2997
+                }
+            }
+        },
+        "dto.ScheduleMonth": {
+            "type": "object",
+            "properties": {
+                "charges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ChargeDTO"
+                    }
+                },
+                "month": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "02-2025"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 999
+                }
+            }
+        },
+        "dto.ServiceDTO": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "description": "Aliases are other spellings resolved to this service, compared case-\nand whitespace-insensitively.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "NFLX",
+                        "Нетфликс"
+                    ]
+                },
+                "category": {
+                    "type": "string",
+                    "example": "video"
+                },
+                "default_price": {
+                    "type": "integer",
+                    "example": 999
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Netflix"
+                },
+                "website": {
+                    "type": "string",
+                    "example": "https://www.netflix.com"
+                }
+            }
+        },
+        "dto.SubscriptionDTO": {
+            "type": "object",
+            "properties": {
+                "billing_period": {
+                    "description": "BillingPeriod is how often Price is charged: monthly (the default),\nquarterly or yearly. Trial and promo months are charged monthly.",
+                    "type": "string",
+                    "enum": [
+                        "monthly",
+                        "quarterly",
+                        "yearly"
+                    ],
+                    "example": "monthly"
+                },
+                "category": {
+                    "description": "Category overrides the category of the catalogue entry.",
+                    "type": "string",
+                    "example": "entertainment"
+                },
+                "effective_category": {
+                    "description": "EffectiveCategory is the override when set, else the catalogue category.",
+                    "type": "string",
+                    "example": "video"
+                },
+                "end_date": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "12-2024"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "integer",
+                    "example": 999
+                },
+                "price_changes": {
+                    "description": "PriceChanges replace Price from their month on. On update, omitting\nthem keeps the current changes and an empty list removes them.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PriceChange"
+                    }
+                },
+                "promo_months": {
+                    "description": "PromoMonths follow the trial at PromoPrice; Price applies afterwards.",
+                    "type": "integer",
+                    "example": 3
+                },
+                "promo_price": {
+                    "type": "integer",
+                    "example": 499
+                },
+                "service_id": {
+                    "description": "ServiceID links the subscription to the service catalogue. When it is\nomitted, service_name is matched against catalogue names and aliases.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "service_name": {
+                    "type": "string",
                     "example": "Netflix"
                 },
                 "start_date": {
@@ -353,15 +2050,240 @@ const docTemplate = `{
                     "format": "MM-YYYY",
                     "example": "01-2024"
                 },
+                "tags": {
+                    "description": "Tags are free-form labels, stored lower-cased. On update, omitting them\nkeeps the current tags and an empty list removes them.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "family",
+                        "work"
+                    ]
+                },
+                "trial_months": {
+                    "description": "TrialMonths are the first months, charged at TrialPrice (usually 0).",
+                    "type": "integer",
+                    "example": 1
+                },
+                "trial_price": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "dto.WebhookDTO": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Active is true when omitted; inactive webhooks keep their pending\ndeliveries until they are activated again.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "subscription.created",
+                        "subscription.deleted"
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Secret signs the deliveries. It is generated when left empty and is\nonly returned when the webhook is created.",
+                    "type": "string",
+                    "example": "s3cr3t-at-least-16-bytes"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://crm.example.com/hooks/subscriptions"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.Result"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Result": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.problem": {
+            "type": "object",
+            "properties": {
+                "conflicting_service_id": {
+                    "description": "ConflictingServiceID names the catalogue entry behind a 409.",
+                    "type": "integer"
+                },
+                "conflicting_subscription_id": {
+                    "description": "ConflictingSubscriptionID names the existing subscription behind a 409,\nnil when it is not known.",
+                    "type": "integer"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Budget": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Category and ServiceName narrow the budget; at most one may be set.",
+                    "type": "string",
+                    "example": "video"
+                },
+                "monthly_limit": {
+                    "type": "integer",
+                    "example": 2000
+                },
+                "service_name": {
+                    "type": "string",
+                    "example": "Netflix"
+                },
                 "user_id": {
                     "type": "string",
                     "example": "550e8400-e29b-41d4-a716-446655440000"
                 }
             }
         },
+        "models.NotificationSettings": {
+            "type": "object",
+            "properties": {
+                "charge_reminders": {
+                    "description": "ChargeReminders and TrialReminders are true when omitted.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "days_before": {
+                    "description": "DaysBefore is how many days ahead reminders are sent, 3 when omitted.",
+                    "type": "integer",
+                    "maximum": 30,
+                    "minimum": 1,
+                    "example": 3
+                },
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "locale": {
+                    "description": "Locale is ru when omitted.",
+                    "type": "string",
+                    "enum": [
+                        "ru",
+                        "en"
+                    ],
+                    "example": "ru"
+                },
+                "trial_reminders": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "models.PriceChange": {
+            "type": "object",
+            "properties": {
+                "month": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "01-2026"
+                },
+                "price": {
+                    "type": "integer",
+                    "example": 1199
+                }
+            }
+        },
+        "models.Service": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "description": "Aliases are other spellings resolved to this service, compared case-\nand whitespace-insensitively.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "NFLX",
+                        "Нетфликс"
+                    ]
+                },
+                "category": {
+                    "type": "string",
+                    "example": "video"
+                },
+                "default_price": {
+                    "type": "integer",
+                    "example": 999
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Netflix"
+                },
+                "website": {
+                    "type": "string",
+                    "example": "https://www.netflix.com"
+                }
+            }
+        },
         "models.Subscription": {
             "type": "object",
             "properties": {
+                "billing_period": {
+                    "description": "BillingPeriod is how often Price is charged: monthly (the default),\nquarterly or yearly. Trial and promo months are charged monthly.",
+                    "type": "string",
+                    "enum": [
+                        "monthly",
+                        "quarterly",
+                        "yearly"
+                    ],
+                    "example": "monthly"
+                },
+                "category": {
+                    "description": "Category overrides the category of the catalogue entry.",
+                    "type": "string",
+                    "example": "entertainment"
+                },
                 "end_date": {
                     "type": "string",
                     "format": "MM-YYYY",
@@ -371,6 +2293,27 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 999
                 },
+                "price_changes": {
+                    "description": "PriceChanges replace Price from their month on. On update, omitting\nthem keeps the current changes and an empty list removes them.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PriceChange"
+                    }
+                },
+                "promo_months": {
+                    "description": "PromoMonths follow the trial at PromoPrice; Price applies afterwards.",
+                    "type": "integer",
+                    "example": 3
+                },
+                "promo_price": {
+                    "type": "integer",
+                    "example": 499
+                },
+                "service_id": {
+                    "description": "ServiceID links the subscription to the service catalogue. When it is\nomitted, service_name is matched against catalogue names and aliases.",
+                    "type": "integer",
+                    "example": 1
+                },
                 "service_name": {
                     "type": "string",
                     "example": "Netflix"
@@ -380,11 +2323,60 @@ const docTemplate = `{
                     "format": "MM-YYYY",
                     "example": "01-2024"
                 },
+                "tags": {
+                    "description": "Tags are free-form labels, stored lower-cased. On update, omitting them\nkeeps the current tags and an empty list removes them.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "family",
+                        "work"
+                    ]
+                },
+                "trial_months": {
+                    "description": "TrialMonths are the first months, charged at TrialPrice (usually 0).",
+                    "type": "integer",
+                    "example": 1
+                },
+                "trial_price": {
+                    "type": "integer",
+                    "example": 0
+                },
                 "user_id": {
                     "type": "string",
                     "example": "550e8400-e29b-41d4-a716-446655440000"
                 }
             }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Active is true when omitted; inactive webhooks keep their pending\ndeliveries until they are activated again.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "subscription.created",
+                        "subscription.deleted"
+                    ]
+                },
+                "secret": {
+                    "description": "Secret signs the deliveries. It is generated when left empty and is\nonly returned when the webhook is created.",
+                    "type": "string",
+                    "example": "s3cr3t-at-least-16-bytes"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://crm.example.com/hooks/subscriptions"
+                }
+            }
         }
     }
 }`
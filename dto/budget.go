@@ -0,0 +1,8 @@
+package dto
+
+import "testTaskEffectiveMobile/models"
+
+type BudgetDTO struct {
+	Id int `json:"id"`
+	models.Budget
+}
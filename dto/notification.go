@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"testTaskEffectiveMobile/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationSettingsDTO struct {
+	UserId uuid.UUID `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	models.NotificationSettings
+}
+
+// Reminder is one upcoming charge or trial end of a subscription.
+type Reminder struct {
+	// Kind is models.ReminderCharge or models.ReminderTrialEnd.
+	Kind           string
+	UserID         uuid.UUID
+	Email          string
+	Locale         string
+	SubscriptionID int
+	ServiceName    string
+	// Date is the day of the charge, or the last day of the trial.
+	Date time.Time
+	// Price and Phase are those of the charge; for a trial end, of the
+	// first paid month.
+	Price int
+	Phase string
+}
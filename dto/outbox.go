@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event recorded in the transaction of the change
+// it describes. Id grows with every event, so consumers can drop events
+// they have seen, and Data is the aggregate as it was after the change.
+type OutboxEvent struct {
+	Id            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   int64           `json:"aggregate_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Data          json.RawMessage `json:"data"`
+	// Attempts is how many times publishing the event failed so far.
+	Attempts int `json:"-"`
+	// PublishedTo names the publishers that already accepted the event.
+	PublishedTo []string `json:"-"`
+}
@@ -11,4 +11,41 @@ type CalculationRequestDTO struct {
 	UserID      *uuid.UUID           `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
 	StartDate   models.MonthYearDate `json:"start_date" example:"01-2024" swaggertype:"string" format:"MM-YYYY"`
 	EndDate     models.MonthYearDate `json:"end_date" example:"12-2024" swaggertype:"string" format:"MM-YYYY"`
+	// Category matches the effective category, ignoring case.
+	Category *string  `json:"category" example:"video"`
+	Tags     []string `json:"tags" example:"family"`
+	// TagMode is "any" (the default) or "all".
+	TagMode string `json:"tag_mode" enums:"any,all" example:"any"`
+	// GroupBy adds a per-category or per-tag breakdown to the total.
+	GroupBy string `json:"group_by" enums:"category,tag" example:"category"`
+}
+
+// CalculationResultDTO is the total cost, with the breakdown when grouping was requested.
+type CalculationResultDTO struct {
+	Price  int64              `json:"price,string" example:"2997"`
+	Groups []CalculationGroup `json:"groups,omitempty"`
+}
+
+// CalculationGroup is the cost of one category or tag; Key is null for
+// subscriptions without one. A subscription with several tags counts towards
+// each of them, so tag groups can add up to more than the total.
+type CalculationGroup struct {
+	Key   *string `json:"key" example:"video"`
+	Price int64   `json:"price,string" example:"1998"`
+}
+
+// SubscriptionFilter narrows a subscription search; zero-valued fields match everything.
+type SubscriptionFilter struct {
+	UserID *uuid.UUID
+	// ServiceName matches as a case-insensitive substring.
+	ServiceName string
+	// ActiveIn keeps subscriptions active during that month.
+	ActiveIn *models.MonthYearDate
+	// Category matches the effective category, ignoring case.
+	Category string
+	// Tags keeps subscriptions carrying any of the tags, or all of them with MatchAllTags.
+	Tags         []string
+	MatchAllTags bool
+	Limit        int
+	Offset       int
 }
@@ -0,0 +1,31 @@
+package dto
+
+import "testTaskEffectiveMobile/models"
+
+// ScheduleDTO is what a user is charged month by month over a period.
+type ScheduleDTO struct {
+	From   models.MonthYearDate `json:"from" swaggertype:"string" format:"MM-YYYY" example:"01-2025"`
+	To     models.MonthYearDate `json:"to" swaggertype:"string" format:"MM-YYYY" example:"03-2025"`
+	Months []ScheduleMonth      `json:"months"`
+	Total  int64                `json:"total" example:"2997"`
+}
+
+// ScheduleMonth holds every month of the period, months without charges included.
+type ScheduleMonth struct {
+	Month   models.MonthYearDate `json:"month" swaggertype:"string" format:"MM-YYYY" example:"02-2025"`
+	Total   int64                `json:"total" example:"999"`
+	Charges []ChargeDTO          `json:"charges"`
+}
+
+type ChargeDTO struct {
+	SubscriptionID int    `json:"subscription_id" example:"42"`
+	ServiceName    string `json:"service_name" example:"Netflix"`
+	// Date is the day of the charge, the first day of its month.
+	Date  string `json:"date" format:"date" example:"2025-02-01"`
+	Price int    `json:"price" example:"999"`
+	// Phase is trial, promo or regular.
+	Phase string `json:"phase" example:"regular"`
+	// PaidThrough is the last month the charge pays for; later than the
+	// charge month for quarterly and yearly billing.
+	PaidThrough models.MonthYearDate `json:"paid_through" swaggertype:"string" format:"MM-YYYY" example:"02-2025"`
+}
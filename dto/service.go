@@ -0,0 +1,26 @@
+package dto
+
+import "testTaskEffectiveMobile/models"
+
+type ServiceDTO struct {
+	Id int `json:"id"`
+	models.Service
+}
+
+// NormalizeReport summarises a run of the service normalisation job.
+type NormalizeReport struct {
+	// Created counts catalogue entries added for unknown names.
+	Created int64 `json:"created"`
+	// Linked counts subscriptions mapped to a catalogue entry.
+	Linked int `json:"linked"`
+	// Conflicts are subscriptions left unlinked because the canonical name
+	// would make them overlap another subscription of the same user.
+	Conflicts []int `json:"conflicts"`
+	// Unmatched are the most common names still missing from the catalogue.
+	Unmatched []NameCount `json:"unmatched"`
+}
+
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+// SubscriptionStats is a snapshot of subscriptions active in a given month.
+type SubscriptionStats struct {
+	Active                  int
+	ByService               map[string]int
+	MonthlyRecurringRevenue int64
+}
@@ -5,4 +5,6 @@ import "testTaskEffectiveMobile/models"
 type SubscriptionDTO struct {
 	Id int `json:"id"`
 	models.Subscription
+	// EffectiveCategory is the override when set, else the catalogue category.
+	EffectiveCategory *string `json:"effective_category,omitempty" example:"video"`
 }
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"encoding/json"
+	"testTaskEffectiveMobile/models"
+	"time"
+)
+
+type WebhookDTO struct {
+	Id int `json:"id"`
+	models.Webhook
+}
+
+// DeliveryDTO is one event queued for one webhook.
+type DeliveryDTO struct {
+	Id            int64           `json:"id"`
+	WebhookID     int             `json:"webhook_id"`
+	Event         string          `json:"event" example:"subscription.created"`
+	Status        string          `json:"status" example:"failed"`
+	Attempts      int             `json:"attempts"`
+	LastStatus    *int            `json:"last_status,omitempty" example:"502"`
+	LastError     *string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+// DueDelivery is a delivery claimed for sending, with its webhook's URL and secret.
+type DueDelivery struct {
+	Id        int64
+	URL       string
+	Secret    string
+	Event     string
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
+}
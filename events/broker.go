@@ -0,0 +1,156 @@
+// Package events fans subscription changes out to the open event streams of
+// their users. A Broker keeps a bounded history so that a reconnecting client
+// can resume from the last event it saw.
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"testTaskEffectiveMobile/dto"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many events a stream may fall behind before it is
+// dropped; the client then reconnects and resumes from the history.
+const subscriberBuffer = 64
+
+// Event is a subscription change of one user. ID is the outbox event id,
+// unique and shared by every replica.
+type Event struct {
+	ID     int64
+	UserID uuid.UUID
+	Type   string
+	Data   json.RawMessage
+}
+
+// FromOutbox turns an outbox event into an Event; ok is false for events that
+// do not belong to a user.
+func FromOutbox(e dto.OutboxEvent) (ev Event, ok bool) {
+	var owner struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := json.Unmarshal(e.Data, &owner); err != nil || owner.UserID == uuid.Nil {
+		return Event{}, false
+	}
+	return Event{ID: e.Id, UserID: owner.UserID, Type: e.Type, Data: e.Data}, true
+}
+
+type Broker struct {
+	mu sync.Mutex
+	// history holds the latest events in arrival order, at most size of them.
+	history []Event
+	size    int
+	// forgotten is the highest id that may be missing from history.
+	forgotten int64
+	subs      map[*Subscription]struct{}
+	closed    bool
+}
+
+// NewBroker returns a broker remembering the last size events. Events up to
+// forgotten happened before it started and cannot be replayed.
+func NewBroker(size int, forgotten int64) *Broker {
+	return &Broker{size: size, forgotten: forgotten, subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events of one user until it is closed, by the
+// client, by the broker shutting down or for falling behind.
+type Subscription struct {
+	userID uuid.UUID
+	ch     chan Event
+	broker *Broker
+}
+
+// Events is closed when the subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s)
+}
+
+// Publish records the event and hands it to the subscriptions of its user.
+// An event still in the history is not published twice.
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	for _, seen := range b.history {
+		if seen.ID == e.ID {
+			return
+		}
+	}
+	if len(b.history) == b.size {
+		b.forgotten = max(b.forgotten, b.history[0].ID)
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, e)
+	for s := range b.subs {
+		if s.userID != e.UserID {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			b.drop(s)
+		}
+	}
+}
+
+// Subscribe starts a subscription for the user and returns the remembered
+// events after lastID to send first. Those are the events that arrived after
+// it, which include any with a lower id committed later; when lastID is no
+// longer remembered, those with a higher id. When some events after lastID
+// are no longer remembered, lost is the newest of them and the client has to
+// reload; otherwise it is 0.
+func (b *Broker) Subscribe(userID uuid.UUID, lastID int64) (s *Subscription, replay []Event, lost int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s = &Subscription{userID: userID, ch: make(chan Event, subscriberBuffer), broker: b}
+	if b.closed {
+		close(s.ch)
+		return s, nil, 0
+	}
+	b.subs[s] = struct{}{}
+	for i, e := range b.history {
+		if e.ID == lastID {
+			for _, e := range b.history[i+1:] {
+				if e.UserID == userID {
+					replay = append(replay, e)
+				}
+			}
+			return s, replay, 0
+		}
+	}
+	for _, e := range b.history {
+		if e.UserID == userID && e.ID > lastID {
+			replay = append(replay, e)
+		}
+	}
+	if lastID < b.forgotten {
+		lost = b.forgotten
+	}
+	return s, replay, lost
+}
+
+// Close ends every subscription; later ones end right away.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		b.drop(s)
+	}
+}
+
+func (b *Broker) drop(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
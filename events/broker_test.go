@@ -0,0 +1,174 @@
+package events
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	alice = uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	bob   = uuid.MustParse("7b2c9b1e-3c1a-4f0e-9d7a-2f5c8e6b4a10")
+)
+
+func event(id int64, user uuid.UUID) Event {
+	return Event{ID: id, UserID: user, Type: "subscription.updated", Data: []byte(`{}`)}
+}
+
+func ids(events []Event) []int64 {
+	var out []int64
+	for _, e := range events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+// received drains what the subscription has buffered and reports whether it
+// is still open.
+func received(s *Subscription) (events []Event, open bool) {
+	for {
+		select {
+		case e, ok := <-s.Events():
+			if !ok {
+				return events, false
+			}
+			events = append(events, e)
+		default:
+			return events, true
+		}
+	}
+}
+
+func TestSubscribeReplaysAfterLastEventID(t *testing.T) {
+	b := NewBroker(10, 0)
+	// Event 2 commits after event 3 and arrives later.
+	for _, e := range []Event{event(1, alice), event(3, alice), event(4, bob), event(2, alice), event(5, alice)} {
+		b.Publish(e)
+	}
+
+	tests := []struct {
+		name   string
+		user   uuid.UUID
+		lastID int64
+		replay []int64
+	}{
+		{"first connection", alice, 0, []int64{1, 3, 2, 5}},
+		{"remembered id", alice, 3, []int64{2, 5}},
+		{"latest id", alice, 5, nil},
+		{"other user", bob, 0, []int64{4}},
+		// Ids are shared by all users, so a stream may resume from any of them.
+		{"id of another user", alice, 4, []int64{2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, replay, lost := b.Subscribe(tt.user, tt.lastID)
+			defer s.Close()
+			if !slices.Equal(ids(replay), tt.replay) || lost != 0 {
+				t.Errorf("Subscribe(%d) = %v, lost %d; want %v, lost 0", tt.lastID, ids(replay), lost, tt.replay)
+			}
+		})
+	}
+}
+
+func TestHistoryIsBounded(t *testing.T) {
+	b := NewBroker(3, 0)
+	for id := range int64(5) {
+		b.Publish(event(id+1, alice))
+	}
+	// A repeated event is neither remembered nor delivered twice.
+	b.Publish(event(5, alice))
+
+	tests := []struct {
+		name   string
+		lastID int64
+		replay []int64
+		lost   int64
+	}{
+		{"remembered id", 3, []int64{4, 5}, 0},
+		{"forgotten id", 1, []int64{3, 4, 5}, 2},
+		{"first connection", 0, []int64{3, 4, 5}, 2},
+		{"newest forgotten id", 2, []int64{3, 4, 5}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, replay, lost := b.Subscribe(alice, tt.lastID)
+			defer s.Close()
+			if !slices.Equal(ids(replay), tt.replay) || lost != tt.lost {
+				t.Errorf("Subscribe(%d) = %v, lost %d; want %v, lost %d", tt.lastID, ids(replay), lost, tt.replay, tt.lost)
+			}
+		})
+	}
+}
+
+func TestEventsBeforeStartAreLost(t *testing.T) {
+	b := NewBroker(10, 7)
+	b.Publish(event(8, alice))
+
+	s, replay, lost := b.Subscribe(alice, 5)
+	defer s.Close()
+	if !slices.Equal(ids(replay), []int64{8}) || lost != 7 {
+		t.Errorf("Subscribe(5) = %v, lost %d; want [8], lost 7", ids(replay), lost)
+	}
+}
+
+func TestPublishDeliversToTheUser(t *testing.T) {
+	b := NewBroker(10, 0)
+	a, _, _ := b.Subscribe(alice, 0)
+	bb, _, _ := b.Subscribe(bob, 0)
+	b.Publish(event(1, alice))
+	b.Publish(event(2, bob))
+	b.Publish(event(3, alice))
+
+	if got, open := received(a); !slices.Equal(ids(got), []int64{1, 3}) || !open {
+		t.Errorf("alice received %v, open %v; want [1 3], open", ids(got), open)
+	}
+	if got, open := received(bb); !slices.Equal(ids(got), []int64{2}) || !open {
+		t.Errorf("bob received %v, open %v; want [2], open", ids(got), open)
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker(2*subscriberBuffer, 0)
+	slow, _, _ := b.Subscribe(alice, 0)
+	fast, _, _ := b.Subscribe(alice, 0)
+
+	var fastGot []Event
+	for id := range int64(subscriberBuffer + 1) {
+		b.Publish(event(id+1, alice))
+		got, _ := received(fast)
+		fastGot = append(fastGot, got...)
+	}
+
+	got, open := received(slow)
+	if len(got) != subscriberBuffer || open {
+		t.Errorf("slow subscriber received %d events, open %v; want %d, closed", len(got), open, subscriberBuffer)
+	}
+	if len(fastGot) != subscriberBuffer+1 {
+		t.Errorf("fast subscriber received %d events, want %d", len(fastGot), subscriberBuffer+1)
+	}
+	// The dropped client resumes from the history.
+	s, replay, lost := b.Subscribe(alice, got[len(got)-1].ID)
+	defer s.Close()
+	if !slices.Equal(ids(replay), []int64{subscriberBuffer + 1}) || lost != 0 {
+		t.Errorf("resume = %v, lost %d; want [%d], lost 0", ids(replay), lost, subscriberBuffer+1)
+	}
+	// Closing a dropped subscription is harmless.
+	slow.Close()
+}
+
+func TestClose(t *testing.T) {
+	b := NewBroker(10, 0)
+	s, _, _ := b.Subscribe(alice, 0)
+	b.Close()
+	b.Publish(event(1, alice))
+
+	if got, open := received(s); len(got) != 0 || open {
+		t.Errorf("after Close received %v, open %v; want nothing, closed", ids(got), open)
+	}
+	late, replay, _ := b.Subscribe(alice, 0)
+	if got, open := received(late); len(replay) != 0 || len(got) != 0 || open {
+		t.Errorf("subscription after Close is open %v with %v; want closed and empty", open, ids(append(replay, got...)))
+	}
+	s.Close()
+}
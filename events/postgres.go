@@ -0,0 +1,159 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"testTaskEffectiveMobile/dto"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// notifyBatch is how many notifications are looked up together.
+	notifyBatch = 100
+	// catchUpBatch is how many missed events are read per query after a reconnect.
+	catchUpBatch = 1000
+	// catchUpWindow is how long a transaction may take to commit after
+	// recording an event. Event ids are taken before commit, so an event may
+	// commit after events with higher ids; catch-up therefore reads again
+	// everything recorded this long before the newest event seen.
+	catchUpWindow = time.Minute
+	// listenerPing detects a connection that died without an error.
+	listenerPing = time.Minute
+)
+
+// Source reads outbox events; repositories.OutboxRepository implements it.
+type Source interface {
+	ByIDs(ctx context.Context, ids []int64) ([]dto.OutboxEvent, error)
+	Since(ctx context.Context, since time.Time, afterID int64, limit int) ([]dto.OutboxEvent, error)
+}
+
+// Listener feeds a broker from PostgreSQL notifications carrying the ids of
+// new outbox events. Every replica listens, so every replica's streams see
+// every change. Notifications are only sent on commit, in commit order.
+type Listener struct {
+	// DSN opens the dedicated listening connection.
+	DSN     string
+	Channel string
+	Source  Source
+	Broker  *Broker
+	Logger  *slog.Logger
+
+	// newest is when the newest event seen was recorded.
+	newest time.Time
+	// seen holds the ids of the events published that catch-up may read
+	// again, with the time they were recorded.
+	seen map[int64]time.Time
+}
+
+// Run listens until ctx is done.
+func (l *Listener) Run(ctx context.Context) {
+	listener := pq.NewListener(l.DSN, time.Second, 30*time.Second, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			l.Logger.Warn("event listener disconnected", "error", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			l.Logger.Warn("event listener could not connect", "error", err)
+		case pq.ListenerEventReconnected:
+			l.Logger.Info("event listener reconnected")
+		}
+	})
+	defer listener.Close()
+	l.newest, l.seen = time.Now(), make(map[int64]time.Time)
+	if err := listener.Listen(l.Channel); err != nil {
+		// The channel stays registered and is listened to on reconnect.
+		l.Logger.Warn("event listener could not listen", "channel", l.Channel, "error", err)
+	}
+	// Events recorded before listening started.
+	l.catchUp(ctx)
+
+	ping := time.NewTicker(listenerPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			go listener.Ping()
+		case n := <-listener.Notify:
+			if n == nil {
+				// Reconnected: notifications sent meanwhile are lost.
+				l.catchUp(ctx)
+				continue
+			}
+			ids, reconnected := l.collect(n, listener.Notify)
+			events, err := l.Source.ByIDs(ctx, ids)
+			if err != nil {
+				l.Logger.Error("reading notified events failed", "error", err)
+			} else {
+				l.publish(events)
+			}
+			if reconnected {
+				l.catchUp(ctx)
+			}
+		}
+	}
+}
+
+// collect returns the event id of n and of the notifications already queued
+// behind it, and whether a reconnect was signalled among them.
+func (l *Listener) collect(n *pq.Notification, queue <-chan *pq.Notification) (ids []int64, reconnected bool) {
+	for n != nil {
+		if id, err := strconv.ParseInt(n.Extra, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+		if len(ids) == notifyBatch {
+			break
+		}
+		select {
+		case n = <-queue:
+			reconnected = reconnected || n == nil
+		default:
+			n = nil
+		}
+	}
+	return ids, reconnected
+}
+
+// catchUp publishes the events recorded within catchUpWindow before the
+// newest event seen, or since, that have not been published yet.
+func (l *Listener) catchUp(ctx context.Context) {
+	since := l.newest.Add(-catchUpWindow)
+	var afterID int64
+	for {
+		events, err := l.Source.Since(ctx, since, afterID, catchUpBatch)
+		if err != nil {
+			l.Logger.Error("reading missed events failed", "error", err)
+			return
+		}
+		l.publish(events)
+		if len(events) < catchUpBatch {
+			return
+		}
+		afterID = events[len(events)-1].Id
+	}
+}
+
+func (l *Listener) publish(events []dto.OutboxEvent) {
+	for _, e := range events {
+		if _, ok := l.seen[e.Id]; ok {
+			continue
+		}
+		l.seen[e.Id] = e.CreatedAt
+		if e.CreatedAt.After(l.newest) {
+			l.newest = e.CreatedAt
+		}
+		if ev, ok := FromOutbox(e); ok {
+			l.Broker.Publish(ev)
+		}
+	}
+	// Catch-up does not read these again.
+	cutoff := l.newest.Add(-catchUpWindow)
+	for id, at := range l.seen {
+		if at.Before(cutoff) {
+			delete(l.seen, id)
+		}
+	}
+}
@@ -6,13 +6,28 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"testTaskEffectiveMobile/billing"
 	"testTaskEffectiveMobile/dto"
 	"testTaskEffectiveMobile/models"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	// maxTags caps the tags of one subscription.
+	maxTags = 20
+	// maxPhaseMonths caps the length of a trial or promo phase.
+	maxPhaseMonths = 120
+	// maxScheduleMonths caps the period of a charge schedule.
+	maxScheduleMonths = 120
+	// maxPriceChanges caps the price changes scheduled for one subscription.
+	maxPriceChanges = 24
+)
+
 func parseUserUuidFromRequest(r *http.Request) (uuid.UUID, error) {
 	userId := r.PathValue("user_id")
 	uid, err := uuid.Parse(userId)
@@ -25,12 +40,13 @@ func parseUserUuidFromRequest(r *http.Request) (uuid.UUID, error) {
 // CalculateSum godoc
 //
 //	@Summary		Calculate subscription sum
-//	@Description	Calculate total sum for subscriptions in given period
+//	@Description	Calculate what subscriptions are charged over the given period: trial and promo months at their own prices, the regular price once per billing period at the price in effect after scheduled price changes. Optionally narrow it to a category and tags, and break it down with group_by.
+//	@Description	Behaviour change: the price of a subscription used to be counted once for any overlap with the period; it is now counted for every charge falling in the period, that is every month it is active in for monthly billing.
 //	@Tags			subscriptions
 //	@Accept			json
 //	@Produce		json
 //	@Param			calculation	body		dto.CalculationRequestDTO	true	"Calculation request"
-//	@Success		200			{object}	object{price=integer}		"Returns total price"
+//	@Success		200			{object}	dto.CalculationResultDTO	"Returns total price"
 //	@Failure		400				{string}	string
 //	@Failure		500				{string}	string
 //	@Router			/api/v1/calculate [post]
@@ -46,26 +62,47 @@ func (app *application) calculateSum(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Period start and end required", http.StatusBadRequest)
 		return
 	}
-	result, err := app.subscriptions.CalculateSum(calcDto)
+	if calcDto.TagMode != "" && calcDto.TagMode != "any" && calcDto.TagMode != "all" {
+		http.Error(w, "tag_mode must be any or all", http.StatusBadRequest)
+		return
+	}
+	if calcDto.GroupBy != "" && calcDto.GroupBy != "category" && calcDto.GroupBy != "tag" {
+		http.Error(w, "group_by must be category or tag", http.StatusBadRequest)
+		return
+	}
+	var result dto.CalculationResultDTO
+	result.Price, err = app.subscriptions.CalculateSum(r.Context(), calcDto)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
+	if calcDto.GroupBy != "" {
+		result.Groups, err = app.subscriptions.CalculateGroups(r.Context(), calcDto)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(fmt.Sprintf(`{"price": "%d"}`, result)))
+	json.NewEncoder(w).Encode(result)
 }
 
 // GetSubscriptions godoc
 //
 //	@Summary		Get user subscriptions
-//	@Description	Get all subscriptions for a specific user
+//	@Description	Get all subscriptions for a specific user. Rows are streamed from the database; send `Accept: application/x-ndjson` to get one JSON object per line instead of an array.
 //	@Tags			subscriptions
 //	@Accept			json
 //	@Produce		json
-//	@Param			user_id	path		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
-//	@Success		200		{array}		models.Subscription
-//	@Failure		400				{string}	string
-//	@Failure		500				{string}	string
+//	@Produce		application/x-ndjson
+//	@Param			user_id		path		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Param			category	query		string	false	"Effective category, case-insensitive"
+//	@Param			tags		query		string	false	"Comma-separated tags"	example(family,work)
+//	@Param			tag_mode	query		string	false	"Match any or all of the tags"	Enums(any, all)	default(any)
+//	@Success		200			{array}		dto.SubscriptionDTO
+//	@Failure		400			{string}	string
+//	@Failure		404			{string}	string	"The user has no subscriptions"
+//	@Failure		500			{string}	string
 //	@Router			/api/v1/subscriptions/{user_id} [get]
 func (app *application) getSubscriptions(w http.ResponseWriter, r *http.Request) {
 	userId, err := parseUserUuidFromRequest(r)
@@ -73,17 +110,215 @@ func (app *application) getSubscriptions(w http.ResponseWriter, r *http.Request)
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	subscriptions, err := app.subscriptions.GetByUserID(userId)
+	filter := dto.SubscriptionFilter{UserID: &userId}
+	if detail := parseTaxonomyQuery(r.URL.Query(), &filter); detail != "" {
+		http.Error(w, detail, http.StatusBadRequest)
+		return
+	}
+	filtered := filter.Category != "" || len(filter.Tags) > 0
+
+	list := newListWriter(w, r)
+	write := func(s dto.SubscriptionDTO) error {
+		return list.Write(&s)
+	}
+	if filtered {
+		err = app.subscriptions.Search(r.Context(), filter, write)
+	} else {
+		err = app.subscriptions.StreamByUserID(r.Context(), userId, write)
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		app.streamError(w, r, list, err)
+		return
+	}
+	if list.Count() == 0 {
+		// Without filters an empty list means an unknown user.
+		if !filtered {
 			app.clientError(w, http.StatusNotFound)
-		} else {
-			app.serverError(w, r, err)
+			return
+		}
+		list.WriteEmpty()
+		return
+	}
+	list.Close()
+}
+
+// parseTaxonomyQuery reads the category, tags and tag_mode parameters into
+// filter and returns a message for invalid ones.
+func parseTaxonomyQuery(q url.Values, filter *dto.SubscriptionFilter) string {
+	filter.Category = strings.TrimSpace(q.Get("category"))
+	for _, v := range q["tags"] {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	switch q.Get("tag_mode") {
+	case "", "any":
+	case "all":
+		filter.MatchAllTags = true
+	default:
+		return "tag_mode must be any or all"
+	}
+	return ""
+}
+
+// validateSubscription returns a problem detail for an oversized category or
+// tag list, for inconsistent trial and promo phases or for an unknown billing
+// period or misplaced price changes, or "" when the body is fine.
+func validateSubscription(s models.Subscription) string {
+	if s.Category != nil && len(strings.TrimSpace(*s.Category)) > 64 {
+		return "category must not exceed 64 bytes"
+	}
+	if len(s.Tags) > maxTags {
+		return fmt.Sprintf("at most %d tags are allowed", maxTags)
+	}
+	for _, tag := range s.Tags {
+		if len(strings.TrimSpace(tag)) > 64 {
+			return "tags must not exceed 64 bytes each"
+		}
+	}
+	switch {
+	case s.TrialMonths < 0 || s.TrialMonths > maxPhaseMonths:
+		return fmt.Sprintf("trial_months must be between 0 and %d", maxPhaseMonths)
+	case s.PromoMonths < 0 || s.PromoMonths > maxPhaseMonths:
+		return fmt.Sprintf("promo_months must be between 0 and %d", maxPhaseMonths)
+	case s.TrialPrice < 0 || s.PromoPrice < 0:
+		return "trial_price and promo_price must not be negative"
+	case s.TrialMonths == 0 && s.TrialPrice != 0:
+		return "trial_price requires trial_months"
+	case s.PromoMonths == 0 && s.PromoPrice != 0:
+		return "promo_price requires promo_months"
+	case models.BillingPeriodMonths(s.BillingPeriod) == 0:
+		return "billing_period must be monthly, quarterly or yearly"
+	case len(s.PriceChanges) > maxPriceChanges:
+		return fmt.Sprintf("at most %d price changes are allowed", maxPriceChanges)
+	}
+	months := make(map[int]bool, len(s.PriceChanges))
+	for _, c := range s.PriceChanges {
+		month := billing.Month(c.Month.Time)
+		switch {
+		case c.Price < 0:
+			return "price_changes must not have negative prices"
+		case c.Month.Before(s.StartDate.Time) || (s.EndDate != nil && c.Month.After(s.EndDate.Time)):
+			return fmt.Sprintf("price change in %s is outside the subscription", c.Month.MonthYear())
+		case months[month]:
+			return fmt.Sprintf("more than one price change in %s", c.Month.MonthYear())
+		}
+		months[month] = true
+	}
+	return ""
+}
+
+// GetEndingTrials godoc
+//
+//	@Summary		List ending trials
+//	@Description	List subscriptions whose trial ends in the given month, so that they can be cancelled before the paid months start. Subscriptions already ending that month are left out.
+//	@Tags			subscriptions
+//	@Produce		json
+//	@Produce		application/x-ndjson
+//	@Param			month	query		string	false	"Last trial month, MM-YYYY; defaults to the current month"	example(03-2025)
+//	@Param			user_id	query		string	false	"Only this user's subscriptions"	format(uuid)
+//	@Success		200		{array}		dto.SubscriptionDTO
+//	@Failure		400		{string}	string
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/trials [get]
+func (app *application) getEndingTrials(w http.ResponseWriter, r *http.Request) {
+	month, err := monthQuery(r, "month", currentMonth())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var userID *uuid.UUID
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
 		}
+		userID = &id
+	}
+
+	list := newListWriter(w, r)
+	err = app.subscriptions.TrialsEnding(r.Context(), month, userID, func(s dto.SubscriptionDTO) error {
+		return list.Write(&s)
+	})
+	if err != nil {
+		app.streamError(w, r, list, err)
+		return
+	}
+	if list.Count() == 0 {
+		list.WriteEmpty()
+		return
+	}
+	list.Close()
+}
+
+// GetSchedule godoc
+//
+//	@Summary		Get charge schedule
+//	@Description	Expand the user's subscriptions into dated charges over a period. Trial and promo months are charged monthly at their own prices; the regular price is charged once per billing period (monthly, quarterly or yearly) at the price in effect after the scheduled price changes, and paid_through tells which months a charge covers. Every month of the period is listed with its total, months without charges included; the totals match /calculate for the same user and period.
+//	@Tags			subscriptions
+//	@Produce		json
+//	@Param			user_id	path		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Param			from	query		string	false	"First month, MM-YYYY; defaults to the current month"	example(01-2025)
+//	@Param			to		query		string	false	"Last month, MM-YYYY; defaults to 11 months after from"	example(12-2025)
+//	@Success		200		{object}	dto.ScheduleDTO
+//	@Failure		400		{string}	string
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/subscriptions/{user_id}/schedule [get]
+func (app *application) getSchedule(w http.ResponseWriter, r *http.Request) {
+	userId, err := parseUserUuidFromRequest(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	from, err := monthQuery(r, "from", currentMonth())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := monthQuery(r, "to", models.MonthYearDate{Time: from.AddDate(0, 11, 0)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	switch months := billing.Month(to.Time) - billing.Month(from.Time) + 1; {
+	case months < 1:
+		http.Error(w, "to is before from", http.StatusBadRequest)
+		return
+	case months > maxScheduleMonths:
+		http.Error(w, fmt.Sprintf("the period must not exceed %d months", maxScheduleMonths), http.StatusBadRequest)
+		return
+	}
+
+	subs, err := app.subscriptions.ActiveBetween(r.Context(), userId, from, to)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	schedule := billing.Schedule(subs, from, to)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(subscriptions)
+	json.NewEncoder(w).Encode(&schedule)
+}
+
+// currentMonth is the first day of the current month in UTC.
+func currentMonth() models.MonthYearDate {
+	now := time.Now().UTC()
+	return models.MonthYearDate{Time: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// monthQuery reads an MM-YYYY query parameter, returning def when it is absent.
+func monthQuery(r *http.Request, name string, def models.MonthYearDate) (models.MonthYearDate, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	month, err := models.ParseMonthYearDate(v)
+	if err != nil {
+		return models.MonthYearDate{}, fmt.Errorf("%s must be MM-YYYY", name)
+	}
+	return month, nil
 }
 
 // GetSubscriptionByID godoc
@@ -112,7 +347,7 @@ func (app *application) getSubscriptionByID(w http.ResponseWriter, r *http.Reque
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	s, err := app.subscriptions.GetByUserIDAndID(userId, intSubscrId)
+	s, err := app.subscriptions.GetByUserIDAndID(r.Context(), userId, intSubscrId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			app.clientError(w, http.StatusNotFound)
@@ -135,6 +370,8 @@ func (app *application) getSubscriptionByID(w http.ResponseWriter, r *http.Reque
 //	@Param			subscription	body		models.Subscription				true	"Subscription data"
 //	@Success		201				{string}	string							"Created"
 //	@Failure		400				{string}	string
+//	@Failure		409				{object}	problem							"Overlaps a subscription to the same service"
+//	@Failure		422				{object}	problem							"Unknown service_id"
 //	@Failure		500				{string}	string
 //	@Router			/api/v1/subscriptions [post]
 func (app *application) postSubscription(w http.ResponseWriter, r *http.Request) {
@@ -145,9 +382,13 @@ func (app *application) postSubscription(w http.ResponseWriter, r *http.Request)
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	err = app.subscriptions.Insert(sub)
+	if detail := validateSubscription(sub); detail != "" {
+		app.writeProblem(w, http.StatusBadRequest, detail)
+		return
+	}
+	_, err = app.subscriptions.Insert(r.Context(), sub)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -165,6 +406,8 @@ func (app *application) postSubscription(w http.ResponseWriter, r *http.Request)
 //	@Success		202				{string}	string							"Accepted"
 //	@Failure		400				{string}	string
 //	@Failure		404				{string}	string
+//	@Failure		409				{object}	problem							"Overlaps a subscription to the same service"
+//	@Failure		422				{object}	problem							"Unknown service_id"
 //	@Failure		500				{string}	string
 //	@Router			/api/v1/subscriptions/{subscription_id} [put]
 func (app *application) updateSubscription(w http.ResponseWriter, r *http.Request) {
@@ -181,13 +424,13 @@ func (app *application) updateSubscription(w http.ResponseWriter, r *http.Reques
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	err = app.subscriptions.Update(intSubscrId, sub)
+	if detail := validateSubscription(sub); detail != "" {
+		app.writeProblem(w, http.StatusBadRequest, detail)
+		return
+	}
+	err = app.subscriptions.Update(r.Context(), intSubscrId, sub)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			app.clientError(w, http.StatusNotFound)
-		} else {
-			app.serverError(w, r, err)
-		}
+		app.writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusAccepted)
@@ -196,7 +439,7 @@ func (app *application) updateSubscription(w http.ResponseWriter, r *http.Reques
 // DeleteSubscription godoc
 //
 //	@Summary		Delete subscription
-//	@Description	Delete a subscription by ID
+//	@Description	Delete a subscription by ID. The row is soft-deleted and hidden from every read until purged.
 //	@Tags			subscriptions
 //	@Accept			json
 //	@Produce		json
@@ -213,7 +456,7 @@ func (app *application) deleteSubscription(w http.ResponseWriter, r *http.Reques
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	err = app.subscriptions.Delete(intSubscrId)
+	err = app.subscriptions.Delete(r.Context(), intSubscrId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			app.clientError(w, http.StatusNotFound)
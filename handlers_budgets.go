@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testTaskEffectiveMobile/models"
+
+	"github.com/google/uuid"
+)
+
+// ListBudgets godoc
+//
+//	@Summary		List budgets
+//	@Description	List the monthly budgets of a user
+//	@Tags			budgets
+//	@Produce		json
+//	@Param			user_id	query		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Success		200		{array}		dto.BudgetDTO
+//	@Failure		400		{string}	string
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/budgets [get]
+func (app *application) listBudgets(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.Parse(r.URL.Query().Get("user_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	budgets, err := app.budgets.List(r.Context(), &userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budgets)
+}
+
+// GetBudget godoc
+//
+//	@Summary		Get budget
+//	@Description	Get a budget by ID
+//	@Tags			budgets
+//	@Produce		json
+//	@Param			budget_id	path		int	true	"Budget ID"
+//	@Success		200			{object}	dto.BudgetDTO
+//	@Failure		400			{string}	string
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/budgets/{budget_id} [get]
+func (app *application) getBudget(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("budget_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	b, err := app.budgets.GetByID(r.Context(), id)
+	if err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+// PostBudget godoc
+//
+//	@Summary		Create budget
+//	@Description	Add a monthly spending limit for a user, on all subscriptions or only on one category or service. Alerts are raised when the month's cost reaches 80% and 100% of the limit.
+//	@Tags			budgets
+//	@Accept			json
+//	@Produce		json
+//	@Param			budget	body		models.Budget	true	"Budget data"
+//	@Success		201		{object}	object{id=integer}
+//	@Failure		400		{object}	problem
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/budgets [post]
+func (app *application) postBudget(w http.ResponseWriter, r *http.Request) {
+	b, ok := app.decodeBudget(w, r)
+	if !ok {
+		return
+	}
+	id, err := app.budgets.Insert(r.Context(), b)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int{"id": id})
+}
+
+// UpdateBudget godoc
+//
+//	@Summary		Update budget
+//	@Description	Replace a budget. Alerts already raised this month are not repeated.
+//	@Tags			budgets
+//	@Accept			json
+//	@Produce		json
+//	@Param			budget_id	path		int				true	"Budget ID"
+//	@Param			budget		body		models.Budget	true	"Budget data"
+//	@Success		202			{string}	string			"Accepted"
+//	@Failure		400			{object}	problem
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/budgets/{budget_id} [put]
+func (app *application) updateBudget(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("budget_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	b, ok := app.decodeBudget(w, r)
+	if !ok {
+		return
+	}
+	if err = app.budgets.Update(r.Context(), id, b); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// DeleteBudget godoc
+//
+//	@Summary		Delete budget
+//	@Description	Remove a budget together with its alert history
+//	@Tags			budgets
+//	@Param			budget_id	path		int		true	"Budget ID"
+//	@Success		204			{string}	string	"No Content"
+//	@Failure		400			{string}	string
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/budgets/{budget_id} [delete]
+func (app *application) deleteBudget(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("budget_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err = app.budgets.Delete(r.Context(), id); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeBudget reads and validates a budget body, answering 400 itself.
+func (app *application) decodeBudget(w http.ResponseWriter, r *http.Request) (models.Budget, bool) {
+	var b models.Budget
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		app.writeProblem(w, http.StatusBadRequest, "invalid JSON body")
+		return b, false
+	}
+	blank := func(s *string) bool { return s == nil || strings.TrimSpace(*s) == "" }
+	var detail string
+	switch {
+	case b.UserId == uuid.Nil:
+		detail = "user_id is required"
+	case b.MonthlyLimit <= 0:
+		detail = "monthly_limit must be positive"
+	case !blank(b.Category) && !blank(b.ServiceName):
+		detail = "set either category or service_name, not both"
+	case b.Category != nil && len(*b.Category) > 64:
+		detail = "category must not exceed 64 bytes"
+	case b.ServiceName != nil && len(*b.ServiceName) > 256:
+		detail = "service_name must not exceed 256 bytes"
+	}
+	if detail != "" {
+		app.writeProblem(w, http.StatusBadRequest, detail)
+		return b, false
+	}
+	return b, true
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testTaskEffectiveMobile/billing"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/ical"
+	"testTaskEffectiveMobile/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// calendarFeedRoute holds the token in its path, which is masked
+	// wherever the request is logged or traced, see safeURI.
+	calendarFeedRoute  = calendarFeedPrefix + "{token}"
+	calendarFeedPrefix = "/calendar/"
+	// calendarUIDDomain ends every event UID; it must never change, or
+	// calendar apps would see all events as new.
+	calendarUIDDomain = "subscriptions.testtask"
+	// The feed covers the previous month and the next twelve.
+	calendarMonthsBack  = 1
+	calendarMonthsAhead = 12
+	trialReminder       = 3 * 24 * time.Hour
+)
+
+// calendarTokens keeps the feed tokens; *repositories.CalendarRepository
+// outside tests. Unknown and revoked tokens are sql.ErrNoRows.
+type calendarTokens interface {
+	IssueToken(ctx context.Context, userID uuid.UUID) (string, error)
+	RevokeToken(ctx context.Context, userID uuid.UUID) error
+	UserByToken(ctx context.Context, token string) (uuid.UUID, error)
+}
+
+// CreateCalendarToken godoc
+//
+//	@Summary		Issue calendar feed token
+//	@Description	Create the private iCalendar feed URL of a user. Calling it again issues a new token and the previous URL stops working.
+//	@Tags			calendar
+//	@Produce		json
+//	@Param			user_id	path		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Success		201		{object}	object{token=string,url=string}
+//	@Failure		400		{string}	string
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/subscriptions/{user_id}/calendar [post]
+func (app *application) createCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userId, err := parseUserUuidFromRequest(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	token, err := app.calendar.IssueToken(r.Context(), userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": token,
+		"url":   strings.TrimSuffix(app.config.HTTP.PublicURL, "/") + "/api/v1" + calendarFeedPrefix + token + ".ics",
+	})
+}
+
+// RevokeCalendarToken godoc
+//
+//	@Summary		Revoke calendar feed token
+//	@Description	Disable the iCalendar feed URL of a user
+//	@Tags			calendar
+//	@Param			user_id	path		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Success		204		{string}	string	"No Content"
+//	@Failure		400		{string}	string
+//	@Failure		404		{string}	string
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/subscriptions/{user_id}/calendar [delete]
+func (app *application) revokeCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userId, err := parseUserUuidFromRequest(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err = app.calendar.RevokeToken(r.Context(), userId); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCalendarFeed godoc
+//
+//	@Summary		Calendar feed
+//	@Description	RFC 5545 feed of the user's charges from the previous month to twelve months ahead, plus a reminder on the last day of each trial. Quarterly and yearly charges are one event in the month they are made. Event UIDs depend only on the subscription and the month, so calendar apps update events after a subscription changes instead of duplicating them.
+//	@Tags			calendar
+//	@Produce		text/calendar
+//	@Param			token	path		string	true	"Feed token, optionally followed by .ics"
+//	@Success		200		{string}	string
+//	@Failure		404		{string}	string
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/calendar/{token} [get]
+func (app *application) getCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+	userId, err := app.calendar.UserByToken(r.Context(), token)
+	if err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	now := currentMonth()
+	from := models.MonthYearDate{Time: now.AddDate(0, -calendarMonthsBack, 0)}
+	to := models.MonthYearDate{Time: now.AddDate(0, calendarMonthsAhead, 0)}
+	subs, err := app.subscriptions.ActiveBetween(r.Context(), userId, from, to)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	calendarFeed(subs, from, to).Encode(w, time.Now())
+}
+
+// calendarFeed turns the charges of the months from..to into events. Free
+// trial months get no charge event; the trial end reminder covers them. A
+// quarterly or yearly charge is one event in the month it is made.
+func calendarFeed(subs []dto.SubscriptionDTO, from, to models.MonthYearDate) ical.Calendar {
+	cal := ical.Calendar{ProdID: "-//testTaskEffectiveMobile//Subscriptions//EN", Name: "Subscriptions"}
+	for _, m := range billing.Schedule(subs, from, to).Months {
+		for _, c := range m.Charges {
+			if c.Price == 0 {
+				continue
+			}
+			summary := fmt.Sprintf("%s — %d", c.ServiceName, c.Price)
+			if c.Phase != string(billing.PhaseRegular) {
+				summary += " (" + c.Phase + ")"
+			}
+			description := fmt.Sprintf("Monthly charge for subscription #%d", c.SubscriptionID)
+			if c.PaidThrough.After(m.Month.Time) {
+				description = fmt.Sprintf("Charge for subscription #%d, paid through %s", c.SubscriptionID, c.PaidThrough.MonthYear())
+			}
+			cal.Events = append(cal.Events, ical.Event{
+				UID:         fmt.Sprintf("charge-%d-%s@%s", c.SubscriptionID, m.Month.Format("200601"), calendarUIDDomain),
+				Date:        m.Month.Time,
+				Summary:     summary,
+				Description: description,
+			})
+		}
+	}
+	for _, s := range subs {
+		end, ok := billing.PlanOf(s.Subscription).TrialEnd()
+		if !ok || end.Before(from.Time) || end.After(to.Time) {
+			continue
+		}
+		if s.EndDate != nil && !s.EndDate.After(end.Time) {
+			// Ends with the trial, nothing to cancel.
+			continue
+		}
+		firstPaid := end.AddDate(0, 1, 0)
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         fmt.Sprintf("trial-%d@%s", s.Id, calendarUIDDomain),
+			Date:        firstPaid.AddDate(0, 0, -1),
+			Summary:     s.ServiceName + " — trial ends",
+			Description: fmt.Sprintf("Paid months of subscription #%d start on %s. Cancel before then to avoid the charge.", s.Id, firstPaid.Format("02.01.2006")),
+			Reminder:    trialReminder,
+		})
+	}
+	return cal
+}
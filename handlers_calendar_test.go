@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// memTokens keeps one feed token per user in memory.
+type memTokens struct {
+	byUser map[uuid.UUID]string
+	issued int
+}
+
+func (m *memTokens) IssueToken(_ context.Context, userID uuid.UUID) (string, error) {
+	m.issued++
+	token := fmt.Sprintf("token%d", m.issued)
+	m.byUser[userID] = token
+	return token, nil
+}
+
+func (m *memTokens) RevokeToken(_ context.Context, userID uuid.UUID) error {
+	if _, ok := m.byUser[userID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.byUser, userID)
+	return nil
+}
+
+func (m *memTokens) UserByToken(_ context.Context, token string) (uuid.UUID, error) {
+	for user, t := range m.byUser {
+		if t == token {
+			return user, nil
+		}
+	}
+	return uuid.Nil, sql.ErrNoRows
+}
+
+func TestCalendarFeedRejectsUnknownTokens(t *testing.T) {
+	app := testApp()
+	app.calendar = &memTokens{byUser: map[uuid.UUID]string{}}
+	userID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	call := func(method, path string, handler http.HandlerFunc, values map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		for k, v := range values {
+			r.SetPathValue(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+	issue := func() string {
+		w := call(http.MethodPost, "/api/v1/subscriptions/"+userID.String()+"/calendar", app.createCalendarToken,
+			map[string]string{"user_id": userID.String()})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("issuing a token: status %d", w.Code)
+		}
+		var body struct{ Token string }
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Token == "" {
+			t.Fatalf("issuing a token: body %q, %v", w.Body, err)
+		}
+		return body.Token
+	}
+	feed := func(token string) int {
+		return call(http.MethodGet, "/api/v1/calendar/"+token+".ics", app.getCalendarFeed,
+			map[string]string{"token": token + ".ics"}).Code
+	}
+
+	first := issue()
+	second := issue()
+	if code := feed(first); code != http.StatusNotFound {
+		t.Errorf("feed with a replaced token: status %d, want 404", code)
+	}
+	if code := feed("wrong"); code != http.StatusNotFound {
+		t.Errorf("feed with a wrong token: status %d, want 404", code)
+	}
+	w := call(http.MethodDelete, "/api/v1/subscriptions/"+userID.String()+"/calendar", app.revokeCalendarToken,
+		map[string]string{"user_id": userID.String()})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("revoking: status %d", w.Code)
+	}
+	if code := feed(second); code != http.StatusNotFound {
+		t.Errorf("feed with a revoked token: status %d, want 404", code)
+	}
+}
+
+func TestCalendarFeedGolden(t *testing.T) {
+	month := func(s string) models.MonthYearDate {
+		m, err := models.ParseMonthYearDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	end, trialOnly := month("06-2025"), month("04-2025")
+	subs := []dto.SubscriptionDTO{
+		{Id: 7, Subscription: models.Subscription{ServiceName: "Кинопоиск", Price: 399, StartDate: month("03-2025"),
+			TrialMonths: 1, PromoMonths: 1, PromoPrice: 199, PriceChanges: []models.PriceChange{{Month: month("06-2025"), Price: 449}}}},
+		{Id: 42, Subscription: models.Subscription{ServiceName: "Netflix", Price: 9990, StartDate: month("04-2025"),
+			BillingPeriod: models.BillingYearly}},
+		// Ends with its trial: no reminder and no charges.
+		{Id: 43, Subscription: models.Subscription{ServiceName: "Okko", Price: 299, StartDate: month("04-2025"), EndDate: &trialOnly,
+			TrialMonths: 1}},
+		{Id: 44, Subscription: models.Subscription{ServiceName: "Spotify", Price: 199, StartDate: month("01-2025"), EndDate: &end}},
+	}
+	var buf bytes.Buffer
+	cal := calendarFeed(subs, month("03-2025"), month("06-2025"))
+	if err := cal.Encode(&buf, time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "calendar_feed.ics")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("calendar feed differs from %s (run go test -update to accept):\n%s", golden, buf.Bytes())
+	}
+
+	// UIDs depend on the subscription and the month only, so that calendar
+	// apps update events instead of duplicating them.
+	uid := regexp.MustCompile(`^(charge-\d+-\d{6}|trial-\d+)@subscriptions\.testtask$`)
+	seen := map[string]bool{}
+	for _, e := range cal.Events {
+		if !uid.MatchString(e.UID) || seen[e.UID] {
+			t.Errorf("UID %q is malformed or repeated", e.UID)
+		}
+		seen[e.UID] = true
+	}
+	for _, want := range []string{"charge-7-202505@subscriptions.testtask", "trial-7@subscriptions.testtask", "charge-42-202504@subscriptions.testtask"} {
+		if !seen[want] {
+			t.Errorf("no event %s", want)
+		}
+	}
+}
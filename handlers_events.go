@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"testTaskEffectiveMobile/events"
+	"time"
+)
+
+// eventsRetry is the reconnect delay suggested to event stream clients.
+const eventsRetry = 3 * time.Second
+
+// GetSubscriptionEvents godoc
+//
+//	@Summary		Stream subscription changes
+//	@Description	Server-sent events of the user's subscription changes: subscription.created, subscription.updated, subscription.ended and subscription.deleted, with the subscription as data and the event id as id. A reconnecting client sends the last id it received in Last-Event-ID and first gets the events it missed. When they are no longer remembered the stream starts with a reset event, after which the client should reload the subscriptions. Idle streams get a comment line as heartbeat.
+//	@Tags			subscriptions
+//	@Produce		text/event-stream
+//	@Param			user_id			path		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Param			Last-Event-ID	header		integer	false	"Id of the last event received"
+//	@Param			last_event_id	query		integer	false	"Same as Last-Event-ID, for clients that cannot set headers"
+//	@Success		200				{string}	string	"event stream"
+//	@Failure		400				{string}	string
+//	@Failure		500				{string}	string
+//	@Router			/api/v1/subscriptions/{user_id}/events [get]
+func (app *application) getSubscriptionEvents(w http.ResponseWriter, r *http.Request) {
+	userId, err := parseUserUuidFromRequest(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	// Without a last id the client only wants what happens from now on.
+	lastID := int64(math.MaxInt64)
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v != "" {
+		if lastID, err = strconv.ParseInt(v, 10, 64); err != nil || lastID < 0 {
+			app.writeProblem(w, http.StatusBadRequest, "Last-Event-ID: must be an event id")
+			return
+		}
+	}
+
+	// The stream outlives the server's read and write timeouts, so the
+	// write deadline is pushed out before every write instead.
+	conn := connController(r, w)
+	extend := func() error {
+		var deadline time.Time
+		if app.config.HTTP.WriteTimeout > 0 {
+			deadline = time.Now().Add(app.config.HTTP.WriteTimeout)
+		}
+		return conn.SetWriteDeadline(deadline)
+	}
+	if err = extend(); err != nil {
+		app.serverError(w, r, fmt.Errorf("event stream: %w", err))
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	flusher := http.NewResponseController(w)
+
+	sub, replay, lost := app.events.Subscribe(userId, lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds())
+	if lost > 0 {
+		writeEvent(w, lost, "reset", []byte("{}"))
+	}
+	for _, e := range replay {
+		writeEvent(w, e.ID, e.Type, e.Data)
+	}
+	if err = flusher.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(app.config.Events.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var e events.Event
+		var ok bool
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok = <-sub.Events():
+			if !ok {
+				// Shutting down, or the client fell behind and resumes after reconnecting.
+				return
+			}
+		case <-heartbeat.C:
+		}
+		if err = extend(); err != nil {
+			return
+		}
+		if ok {
+			writeEvent(w, e.ID, e.Type, e.Data)
+		} else {
+			io.WriteString(w, ": ping\n\n")
+		}
+		if err = flusher.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes one server-sent event; every line of data gets its own field.
+func writeEvent(w io.Writer, id int64, event string, data []byte) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\n", id, event)
+	for line := range bytes.Lines(data) {
+		fmt.Fprintf(w, "data: %s\n", bytes.TrimRight(line, "\r\n"))
+	}
+	io.WriteString(w, "\n")
+}
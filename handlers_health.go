@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Healthz godoc
+//
+//	@Summary		Liveness probe
+//	@Description	Reports that the process is alive. Does not check dependencies.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	map[string]string
+//	@Router			/healthz [get]
+func (app *application) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status": "up"}`))
+}
+
+// Readyz godoc
+//
+//	@Summary		Readiness probe
+//	@Description	Runs every registered dependency check and reports each result with its latency
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	health.Report
+//	@Failure		503	{object}	health.Report
+//	@Router			/readyz [get]
+func (app *application) readyz(w http.ResponseWriter, r *http.Request) {
+	report := app.health.Run(r.Context())
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"slices"
+	"testTaskEffectiveMobile/models"
+)
+
+// GetNotificationSettings godoc
+//
+//	@Summary		Get reminder settings
+//	@Description	Get the email and preferences of a user's reminders of upcoming charges and trial ends
+//	@Tags			notifications
+//	@Produce		json
+//	@Param			user_id	path		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Success		200		{object}	dto.NotificationSettingsDTO
+//	@Failure		400		{string}	string
+//	@Failure		404		{string}	string
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/subscriptions/{user_id}/notifications [get]
+func (app *application) getNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userId, err := parseUserUuidFromRequest(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	settings, err := app.notifications.Get(r.Context(), userId)
+	if err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// PutNotificationSettings godoc
+//
+//	@Summary		Set reminder settings
+//	@Description	Create or replace a user's reminder settings. Reminders are emailed days_before days ahead of every paid charge (charges fall on the first day of the month) and of the last day of every trial. Each reminder is sent once.
+//	@Tags			notifications
+//	@Accept			json
+//	@Param			user_id		path		string						true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Param			settings	body		models.NotificationSettings	true	"Reminder settings"
+//	@Success		202			{string}	string						"Accepted"
+//	@Failure		400			{object}	problem
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/subscriptions/{user_id}/notifications [put]
+func (app *application) putNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userId, err := parseUserUuidFromRequest(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	settings, ok := app.decodeNotificationSettings(w, r)
+	if !ok {
+		return
+	}
+	if err = app.notifications.Put(r.Context(), userId, settings); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// DeleteNotificationSettings godoc
+//
+//	@Summary		Stop reminders
+//	@Description	Remove a user's reminder settings, which stops all of their reminders
+//	@Tags			notifications
+//	@Param			user_id	path		string	true	"User ID (UUID)"	format(uuid)	example(550e8400-e29b-41d4-a716-446655440000)
+//	@Success		204		{string}	string	"No Content"
+//	@Failure		400		{string}	string
+//	@Failure		404		{string}	string
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/subscriptions/{user_id}/notifications [delete]
+func (app *application) deleteNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userId, err := parseUserUuidFromRequest(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err = app.notifications.Delete(r.Context(), userId); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeNotificationSettings reads and validates a settings body, filling in
+// the defaults and answering 400 itself.
+func (app *application) decodeNotificationSettings(w http.ResponseWriter, r *http.Request) (models.NotificationSettings, bool) {
+	var s models.NotificationSettings
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		app.writeProblem(w, http.StatusBadRequest, "invalid JSON body")
+		return s, false
+	}
+	if s.Locale == "" {
+		s.Locale = models.Locales[0]
+	}
+	if s.DaysBefore == 0 {
+		s.DaysBefore = 3
+	}
+	var detail string
+	switch addr, err := mail.ParseAddress(s.Email); {
+	case s.Email == "":
+		detail = "email is required"
+	case err != nil || addr.Address != s.Email || len(s.Email) > 254:
+		detail = "email must be a plain email address"
+	case !slices.Contains(models.Locales, s.Locale):
+		detail = "locale must be ru or en"
+	case s.DaysBefore < 1 || s.DaysBefore > 30:
+		detail = "days_before must be between 1 and 30"
+	}
+	if detail != "" {
+		app.writeProblem(w, http.StatusBadRequest, detail)
+		return s, false
+	}
+	return s, true
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testTaskEffectiveMobile/models"
+)
+
+// ListServices godoc
+//
+//	@Summary		List services
+//	@Description	List the service catalogue ordered by name
+//	@Tags			services
+//	@Produce		json
+//	@Success		200	{array}		dto.ServiceDTO
+//	@Failure		500	{string}	string
+//	@Router			/api/v1/services [get]
+func (app *application) listServices(w http.ResponseWriter, r *http.Request) {
+	services, err := app.services.List(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
+
+// GetService godoc
+//
+//	@Summary		Get service
+//	@Description	Get a catalogue entry by ID
+//	@Tags			services
+//	@Produce		json
+//	@Param			service_id	path		int	true	"Service ID"
+//	@Success		200			{object}	dto.ServiceDTO
+//	@Failure		400			{string}	string
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/services/{service_id} [get]
+func (app *application) getService(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("service_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	s, err := app.services.GetByID(r.Context(), id)
+	if err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// PostService godoc
+//
+//	@Summary		Create service
+//	@Description	Add a catalogue entry. The name and every alias must not match another service, ignoring case and whitespace.
+//	@Tags			services
+//	@Accept			json
+//	@Produce		json
+//	@Param			service	body		models.Service	true	"Service data"
+//	@Success		201		{object}	object{id=integer}
+//	@Failure		400		{object}	problem
+//	@Failure		409		{object}	problem	"Name or alias already used"
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/services [post]
+func (app *application) postService(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.decodeService(w, r)
+	if !ok {
+		return
+	}
+	id, err := app.services.Insert(r.Context(), s)
+	if err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int{"id": id})
+}
+
+// UpdateService godoc
+//
+//	@Summary		Update service
+//	@Description	Replace a catalogue entry. Subscriptions linked to it are renamed to the new name; when that would give a user two overlapping subscriptions to the same service, nothing is changed and the answer is 409 with conflicting_subscription_id.
+//	@Tags			services
+//	@Accept			json
+//	@Produce		json
+//	@Param			service_id	path		int				true	"Service ID"
+//	@Param			service		body		models.Service	true	"Service data"
+//	@Success		202			{string}	string			"Accepted"
+//	@Failure		400			{object}	problem
+//	@Failure		404			{string}	string
+//	@Failure		409			{object}	problem	"Name or alias already used, or a renamed subscription would overlap another"
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/services/{service_id} [put]
+func (app *application) updateService(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("service_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	s, ok := app.decodeService(w, r)
+	if !ok {
+		return
+	}
+	if err = app.services.Update(r.Context(), id, s); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// DeleteService godoc
+//
+//	@Summary		Delete service
+//	@Description	Remove a catalogue entry. Its subscriptions keep their service_name and lose the service_id link.
+//	@Tags			services
+//	@Param			service_id	path		int		true	"Service ID"
+//	@Success		204			{string}	string	"No Content"
+//	@Failure		400			{string}	string
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/services/{service_id} [delete]
+func (app *application) deleteService(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("service_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err = app.services.Delete(r.Context(), id); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeService reads and validates a service body, answering 400 itself.
+func (app *application) decodeService(w http.ResponseWriter, r *http.Request) (models.Service, bool) {
+	var s models.Service
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		app.writeProblem(w, http.StatusBadRequest, "invalid JSON body")
+		return s, false
+	}
+	var detail string
+	switch {
+	case strings.TrimSpace(s.Name) == "":
+		detail = "name is required"
+	case len(s.Name) > 256:
+		detail = "name must not exceed 256 bytes"
+	case s.Category != nil && len(*s.Category) > 64:
+		detail = "category must not exceed 64 bytes"
+	case s.DefaultPrice != nil && *s.DefaultPrice < 0:
+		detail = "default_price must not be negative"
+	case s.Website != nil && !validWebsite(*s.Website):
+		detail = "website must be an http(s) URL"
+	}
+	if detail != "" {
+		app.writeProblem(w, http.StatusBadRequest, detail)
+		return s, false
+	}
+	return s, true
+}
+
+func validWebsite(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && len(s) <= 512
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testTaskEffectiveMobile/models"
+	"testing"
+)
+
+func TestValidateSubscription(t *testing.T) {
+	month := func(s string) models.MonthYearDate {
+		m, err := models.ParseMonthYearDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	end := month("12-2025")
+	base := models.Subscription{ServiceName: "Netflix", Price: 999, StartDate: month("01-2025"), EndDate: &end}
+	change := func(m string, price int) models.PriceChange {
+		return models.PriceChange{Month: month(m), Price: price}
+	}
+	tests := []struct {
+		name    string
+		edit    func(*models.Subscription)
+		problem string
+	}{
+		{"plain", func(s *models.Subscription) {}, ""},
+		{"yearly with a change", func(s *models.Subscription) {
+			s.BillingPeriod = models.BillingYearly
+			s.PriceChanges = []models.PriceChange{change("06-2025", 1099), change("12-2025", 1199)}
+		}, ""},
+		{"unknown period", func(s *models.Subscription) { s.BillingPeriod = "weekly" }, "billing_period"},
+		{"change before start", func(s *models.Subscription) {
+			s.PriceChanges = []models.PriceChange{change("12-2024", 1099)}
+		}, "outside the subscription"},
+		{"change after end", func(s *models.Subscription) {
+			s.PriceChanges = []models.PriceChange{change("01-2026", 1099)}
+		}, "outside the subscription"},
+		{"two changes in a month", func(s *models.Subscription) {
+			s.PriceChanges = []models.PriceChange{change("03-2025", 1099), change("03-2025", 1199)}
+		}, "more than one"},
+		{"negative change", func(s *models.Subscription) {
+			s.PriceChanges = []models.PriceChange{change("03-2025", -1)}
+		}, "negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := base
+			tt.edit(&s)
+			got := validateSubscription(s)
+			if (got == "") != (tt.problem == "") || !strings.Contains(got, tt.problem) {
+				t.Errorf("validateSubscription = %q, want a problem mentioning %q", got, tt.problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testTaskEffectiveMobile/models"
+	"testTaskEffectiveMobile/webhooks"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+	minWebhookSecret       = 16
+)
+
+// ListWebhooks godoc
+//
+//	@Summary		List webhooks
+//	@Description	List the registered webhooks; secrets are not returned
+//	@Tags			webhooks
+//	@Produce		json
+//	@Success		200	{array}		dto.WebhookDTO
+//	@Failure		500	{string}	string
+//	@Router			/api/v1/webhooks [get]
+func (app *application) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := app.webhooks.List(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// GetWebhook godoc
+//
+//	@Summary		Get webhook
+//	@Description	Get a webhook by ID; the secret is not returned
+//	@Tags			webhooks
+//	@Produce		json
+//	@Param			webhook_id	path		int	true	"Webhook ID"
+//	@Success		200			{object}	dto.WebhookDTO
+//	@Failure		400			{string}	string
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/webhooks/{webhook_id} [get]
+func (app *application) getWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("webhook_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	webhook, err := app.webhooks.GetByID(r.Context(), id)
+	if err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// PostWebhook godoc
+//
+//	@Summary		Register webhook
+//	@Description	Register a URL for subscription events: subscription.created, subscription.updated, subscription.ended (an update set the end date of an open-ended subscription) and subscription.deleted. Every delivery is a POST signed in X-Webhook-Signature as "sha256=" plus the hex HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>" with the secret. The secret is generated when omitted and is only shown in this response.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhook	body		models.Webhook	true	"Webhook data"
+//	@Success		201		{object}	object{id=integer,secret=string}
+//	@Failure		400		{object}	problem
+//	@Failure		500		{string}	string
+//	@Router			/api/v1/webhooks [post]
+func (app *application) postWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := app.decodeWebhook(w, r)
+	if !ok {
+		return
+	}
+	id, secret, err := app.webhooks.Insert(r.Context(), webhook)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"id": id, "secret": secret})
+}
+
+// UpdateWebhook godoc
+//
+//	@Summary		Update webhook
+//	@Description	Replace a webhook. An omitted secret keeps the current one. Deliveries already queued are sent to the new URL.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Param			webhook_id	path		int				true	"Webhook ID"
+//	@Param			webhook		body		models.Webhook	true	"Webhook data"
+//	@Success		202			{string}	string			"Accepted"
+//	@Failure		400			{object}	problem
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/webhooks/{webhook_id} [put]
+func (app *application) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("webhook_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	webhook, ok := app.decodeWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err = app.webhooks.Update(r.Context(), id, webhook); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary		Delete webhook
+//	@Description	Remove a webhook together with its queued and past deliveries
+//	@Tags			webhooks
+//	@Param			webhook_id	path		int		true	"Webhook ID"
+//	@Success		204			{string}	string	"No Content"
+//	@Failure		400			{string}	string
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/webhooks/{webhook_id} [delete]
+func (app *application) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("webhook_id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err = app.webhooks.Delete(r.Context(), id); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+//
+//	@Summary		List webhook deliveries
+//	@Description	List deliveries, newest first. status=failed is the dead-letter queue: deliveries that used up their attempts.
+//	@Tags			webhooks
+//	@Produce		json
+//	@Param			webhook_id	query		int		false	"Only deliveries of this webhook"
+//	@Param			status		query		string	false	"Delivery status"	Enums(pending, delivered, failed)
+//	@Param			limit		query		int		false	"Maximum number of deliveries, 1..500"	default(50)
+//	@Success		200			{array}		dto.DeliveryDTO
+//	@Failure		400			{object}	problem
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/webhooks/deliveries [get]
+func (app *application) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var webhookID *int
+	if v := query.Get("webhook_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			app.writeProblem(w, http.StatusBadRequest, "webhook_id must be an integer")
+			return
+		}
+		webhookID = &id
+	}
+	status := query.Get("status")
+	if status != "" && status != models.DeliveryPending && status != models.DeliveryDelivered && status != models.DeliveryFailed {
+		app.writeProblem(w, http.StatusBadRequest, "status must be pending, delivered or failed")
+		return
+	}
+	limit := defaultDeliveriesLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveriesLimit {
+			app.writeProblem(w, http.StatusBadRequest, fmt.Sprintf("limit must be in 1..%d", maxDeliveriesLimit))
+			return
+		}
+		limit = n
+	}
+	deliveries, err := app.webhooks.Deliveries(r.Context(), webhookID, status, limit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// RedeliverDelivery godoc
+//
+//	@Summary		Redeliver
+//	@Description	Queue a delivery again with a fresh set of attempts, typically one from the dead-letter queue. The body and X-Webhook-Id stay the same, so receivers can drop duplicates.
+//	@Tags			webhooks
+//	@Param			delivery_id	path		int		true	"Delivery ID"
+//	@Success		202			{string}	string	"Accepted"
+//	@Failure		400			{string}	string
+//	@Failure		404			{string}	string
+//	@Failure		500			{string}	string
+//	@Router			/api/v1/webhooks/deliveries/{delivery_id}/redeliver [post]
+func (app *application) redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("delivery_id"), 10, 64)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err = app.webhooks.Redeliver(r.Context(), id); err != nil {
+		app.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// decodeWebhook reads and validates a webhook body, answering 400 itself.
+func (app *application) decodeWebhook(w http.ResponseWriter, r *http.Request) (models.Webhook, bool) {
+	var webhook models.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		app.writeProblem(w, http.StatusBadRequest, "invalid JSON body")
+		return webhook, false
+	}
+	u, err := url.Parse(webhook.URL)
+	var detail string
+	switch {
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		detail = "url must be an absolute http or https URL"
+	case !app.config.Webhooks.AllowPrivate && !webhooks.PublicHost(u.Hostname()):
+		detail = "url must not point to localhost or a private network"
+	case len(webhook.Events) == 0:
+		detail = "events must not be empty"
+	case webhook.Secret != "" && len(webhook.Secret) < minWebhookSecret:
+		detail = fmt.Sprintf("secret must be at least %d bytes", minWebhookSecret)
+	case len(webhook.Secret) > 256:
+		detail = "secret must not exceed 256 bytes"
+	}
+	for _, event := range webhook.Events {
+		if detail == "" && !slices.Contains(models.SubscriptionEvents, event) {
+			detail = fmt.Sprintf("unknown event %q", event)
+		}
+	}
+	if detail != "" {
+		app.writeProblem(w, http.StatusBadRequest, detail)
+		return webhook, false
+	}
+	slices.Sort(webhook.Events)
+	webhook.Events = slices.Compact(webhook.Events)
+	return webhook, true
+}
@@ -1,18 +1,166 @@
 package main
 
 import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
+	"strings"
+	"testTaskEffectiveMobile/postgres_db/repositories"
 )
 
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
 	var (
 		method = r.Method
-		uri    = r.URL.RequestURI()
+		uri    = safeURI(r)
 	)
-	app.logger.Error(err.Error(), "method", method, "uri", uri)
+	app.logger.ErrorContext(r.Context(), err.Error(), "method", method, "uri", uri)
 	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
 
 func (app *application) clientError(w http.ResponseWriter, status int) {
 	http.Error(w, http.StatusText(status), status)
 }
+
+// problem is an RFC 9457 problem details body.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// ConflictingSubscriptionID names the existing subscription behind a 409,
+	// nil when it is not known.
+	ConflictingSubscriptionID *int `json:"conflicting_subscription_id,omitempty"`
+	// ConflictingServiceID names the catalogue entry behind a 409.
+	ConflictingServiceID int `json:"conflicting_service_id,omitempty"`
+}
+
+func (app *application) writeProblem(w http.ResponseWriter, status int, detail string) {
+	app.writeProblemBody(w, problem{Status: status, Detail: detail})
+}
+
+func (app *application) writeProblemBody(w http.ResponseWriter, p problem) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// writeError answers the repository errors that are the client's fault with
+// a problem response and everything else with a 500.
+func (app *application) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		overlap   *repositories.OverlapError
+		duplicate *repositories.DuplicateServiceError
+	)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.clientError(w, http.StatusNotFound)
+	case errors.As(err, &overlap):
+		p := problem{Status: http.StatusConflict, Detail: overlap.Error()}
+		if overlap.ConflictingID != 0 {
+			p.ConflictingSubscriptionID = &overlap.ConflictingID
+		}
+		app.writeProblemBody(w, p)
+	case errors.As(err, &duplicate):
+		app.writeProblemBody(w, problem{Status: http.StatusConflict, Detail: duplicate.Error(), ConflictingServiceID: duplicate.ExistingID})
+	case errors.Is(err, repositories.ErrUnknownService):
+		app.writeProblem(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.serverError(w, r, err)
+	}
+}
+
+// streamError reports a failure of a streamed list. Before the first item it
+// is a regular 500; afterwards the status is gone, so the connection is
+// aborted to keep the client from taking a truncated body for a complete one.
+func (app *application) streamError(w http.ResponseWriter, r *http.Request, list *listWriter, err error) {
+	if list.Count() == 0 {
+		app.serverError(w, r, err)
+		return
+	}
+	app.logger.ErrorContext(r.Context(), "stream aborted: "+err.Error(), "method", r.Method, "uri", safeURI(r))
+	panic(http.ErrAbortHandler)
+}
+
+const ndjsonContentType = "application/x-ndjson"
+
+// listWriter encodes items one by one as a JSON array or, when the client
+// accepts application/x-ndjson, as newline-delimited JSON. Headers are only
+// sent with the first item, so an empty list can still get an error status.
+type listWriter struct {
+	w      http.ResponseWriter
+	buf    *bufio.Writer
+	ndjson bool
+	count  int
+}
+
+func newListWriter(w http.ResponseWriter, r *http.Request) *listWriter {
+	return &listWriter{w: w, ndjson: acceptsNDJSON(r)}
+}
+
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && mediaType == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
+
+func (lw *listWriter) Write(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if lw.count == 0 {
+		if lw.ndjson {
+			lw.w.Header().Set("Content-Type", ndjsonContentType)
+		} else {
+			lw.w.Header().Set("Content-Type", "application/json")
+		}
+		lw.buf = bufio.NewWriterSize(lw.w, 32<<10)
+		if !lw.ndjson {
+			lw.buf.WriteByte('[')
+		}
+	} else if !lw.ndjson {
+		lw.buf.WriteByte(',')
+	}
+	lw.count++
+	lw.buf.Write(b)
+	if lw.ndjson {
+		lw.buf.WriteByte('\n')
+	}
+	return nil
+}
+
+func (lw *listWriter) Count() int {
+	return lw.count
+}
+
+// WriteEmpty sends an empty list in the negotiated format, in place of Close
+// when nothing was written.
+func (lw *listWriter) WriteEmpty() {
+	if lw.ndjson {
+		lw.w.Header().Set("Content-Type", ndjsonContentType)
+		lw.w.WriteHeader(http.StatusOK)
+		return
+	}
+	lw.w.Header().Set("Content-Type", "application/json")
+	lw.w.Write([]byte("[]\n"))
+}
+
+// Close terminates the list. An empty list writes nothing.
+func (lw *listWriter) Close() error {
+	if lw.count == 0 {
+		return nil
+	}
+	if !lw.ndjson {
+		lw.buf.WriteString("]\n")
+	}
+	return lw.buf.Flush()
+}
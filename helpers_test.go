@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testTaskEffectiveMobile/postgres_db/repositories"
+	"testing"
+)
+
+func testApp() *application {
+	return &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantFields map[string]any
+		absent     []string
+	}{
+		{
+			name:       "overlap with a known subscription",
+			err:        &repositories.OverlapError{ConflictingID: 42},
+			wantStatus: http.StatusConflict,
+			wantFields: map[string]any{"status": 409.0, "conflicting_subscription_id": 42.0},
+		},
+		{
+			name:       "overlap of a renamed service",
+			err:        fmt.Errorf("update service: %w", &repositories.OverlapError{ConflictingID: 7}),
+			wantStatus: http.StatusConflict,
+			wantFields: map[string]any{"conflicting_subscription_id": 7.0},
+		},
+		{
+			name:       "overlap with an unknown subscription",
+			err:        &repositories.OverlapError{},
+			wantStatus: http.StatusConflict,
+			wantFields: map[string]any{"detail": "subscription overlaps another subscription to the same service"},
+			absent:     []string{"conflicting_subscription_id"},
+		},
+		{
+			name:       "duplicate service",
+			err:        &repositories.DuplicateServiceError{ExistingID: 3},
+			wantStatus: http.StatusConflict,
+			wantFields: map[string]any{"conflicting_service_id": 3.0},
+			absent:     []string{"conflicting_subscription_id"},
+		},
+		{
+			name:       "unknown service",
+			err:        repositories.ErrUnknownService,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "not found",
+			err:        sql.ErrNoRows,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "anything else",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			testApp().writeError(w, httptest.NewRequest(http.MethodPut, "/api/v1/services/1", nil), tt.err)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantFields == nil && tt.absent == nil {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q: %v", w.Body, err)
+			}
+			for k, want := range tt.wantFields {
+				if body[k] != want {
+					t.Errorf("%s = %v, want %v", k, body[k], want)
+				}
+			}
+			for _, k := range tt.absent {
+				if v, ok := body[k]; ok {
+					t.Errorf("%s = %v, want it left out", k, v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Package ical writes RFC 5545 calendars made of all-day events.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Calendar struct {
+	ProdID string
+	// Name is shown by calendar apps that support X-WR-CALNAME.
+	Name   string
+	Events []Event
+}
+
+// Event is an all-day event. Apps match events by UID, so an event keeps its
+// UID when its details change.
+type Event struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	// Reminder, when positive, adds a display alarm that long before the day starts.
+	Reminder time.Duration
+}
+
+// Encode writes the calendar with CRLF line endings and lines folded at 75
+// octets. stamp is the DTSTAMP of every event.
+func (c Calendar) Encode(w io.Writer, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", c.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	dtstamp := stamp.UTC().Format("20060102T150405Z")
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", dtstamp)
+		line("DTSTART;VALUE=DATE", e.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE", e.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		line("TRANSP", "TRANSPARENT")
+		if e.Reminder > 0 {
+			line("BEGIN", "VALARM")
+			line("ACTION", "DISPLAY")
+			line("DESCRIPTION", escape(e.Summary))
+			line("TRIGGER", "-"+duration(e.Reminder))
+			line("END", "VALARM")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// escape escapes a TEXT value (RFC 5545, section 3.3.11).
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeFolded writes a content line, folding it into lines of at most 75
+// octets without splitting a UTF-8 sequence.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space that counts towards the limit.
+		limit = 74
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// duration formats d as a dur-value with day, hour and minute precision.
+func duration(d time.Duration) string {
+	var b strings.Builder
+	b.WriteString("P")
+	if days := d / (24 * time.Hour); days > 0 {
+		b.WriteString(strconv.Itoa(int(days)) + "D")
+		d -= days * 24 * time.Hour
+	}
+	if d >= time.Minute {
+		b.WriteString("T")
+		if h := d / time.Hour; h > 0 {
+			b.WriteString(strconv.Itoa(int(h)) + "H")
+			d -= h * time.Hour
+		}
+		if m := d / time.Minute; m > 0 {
+			b.WriteString(strconv.Itoa(int(m)) + "M")
+		}
+	}
+	if b.Len() == 1 {
+		return "PT0M"
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package ical
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var stamp = time.Date(2025, 3, 14, 9, 26, 53, 0, time.FixedZone("MSK", 3*60*60))
+
+var testCalendar = Calendar{
+	ProdID: "-//testTaskEffectiveMobile//Subscriptions//EN",
+	Name:   "Подписки, семья; работа",
+	Events: []Event{
+		{
+			UID:         "charge-42-202504@subscriptions.testtask",
+			Date:        time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+			Summary:     "Netflix — 999",
+			Description: "Monthly charge for subscription #42",
+		},
+		{
+			UID:  "charge-7-202505@subscriptions.testtask",
+			Date: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+			// Cyrillic takes two octets a letter, so folding must not split one.
+			Summary:     "Кинопоиск: онлайн-кинотеатр, подписка «Плюс Мульти» с Амедиатекой — 1 299",
+			Description: "Строка 1\nC:\\path; a, b\r\nстрока 3",
+		},
+		{
+			UID:         "trial-7@subscriptions.testtask",
+			Date:        time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC),
+			Summary:     "Кинопоиск — trial ends",
+			Description: "Paid months of subscription #7 start on 01.05.2025. Cancel before then to avoid the charge.",
+			Reminder:    3*24*time.Hour + 90*time.Minute,
+		},
+	},
+}
+
+func TestEncodeGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testCalendar.Encode(&buf, stamp); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "calendar.ics")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Encode differs from %s (run go test -update to accept):\n%s", golden, buf.Bytes())
+	}
+}
+
+func TestEncodeLines(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testCalendar.Encode(&buf, stamp); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Errorf("calendar does not end with a CRLF-terminated END:VCALENDAR")
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+	folded := 0
+	for i, line := range lines {
+		if strings.ContainsAny(line, "\r\n") {
+			t.Errorf("line %d %q has a bare CR or LF", i+1, line)
+		}
+		if len(line) > 75 {
+			t.Errorf("line %d is %d octets long, want at most 75", i+1, len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line %d %q splits a UTF-8 sequence", i+1, line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded++
+		}
+	}
+	if folded == 0 {
+		t.Error("no line was folded")
+	}
+
+	// Unfolding restores the escaped values.
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	for _, want := range []string{
+		`X-WR-CALNAME:Подписки\, семья\; работа` + "\r\n",
+		`SUMMARY:Кинопоиск: онлайн-кинотеатр\, подписка «Плюс Мульти» с Амедиатекой — 1 299` + "\r\n",
+		`DESCRIPTION:Строка 1\nC:\\path\; a\, b\nстрока 3` + "\r\n",
+		"UID:charge-42-202504@subscriptions.testtask\r\n",
+		"DTSTAMP:20250314T062653Z\r\n",
+		"DTSTART;VALUE=DATE:20250430\r\nDTEND;VALUE=DATE:20250501\r\n",
+		"TRIGGER:-P3DT1H30M\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("unfolded calendar has no %q", want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "PT0M"},
+		{30 * time.Second, "PT0M"},
+		{15 * time.Minute, "PT15M"},
+		{2 * time.Hour, "PT2H"},
+		{3 * 24 * time.Hour, "P3D"},
+		{24*time.Hour + 5*time.Minute, "P1DT5M"},
+	}
+	for _, tt := range tests {
+		if got := duration(tt.d); got != tt.want {
+			t.Errorf("duration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
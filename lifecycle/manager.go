@@ -0,0 +1,176 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Manager owns the process lifetime: it runs the HTTP servers and background
+// workers, waits for SIGINT/SIGTERM and tears everything down in order —
+// servers are drained first, then workers are stopped, then resources are closed.
+type Manager struct {
+	logger          *slog.Logger
+	shutdownTimeout time.Duration
+
+	ready  atomic.Bool
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	servers []server
+	closers []closer
+}
+
+type server struct {
+	srv   *http.Server
+	serve func(net.Listener) error
+}
+
+type closer struct {
+	name string
+	fn   func() error
+}
+
+func New(logger *slog.Logger, shutdownTimeout time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		logger:          logger,
+		shutdownTimeout: shutdownTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// Ready reports whether the service accepts traffic. It turns false as soon as shutdown begins.
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// Serve registers a server to be started by Run on a listener bound to
+// srv.Addr. serve is the blocking serve call (e.g. srv.ServeTLS); nil means
+// srv.Serve.
+func (m *Manager) Serve(srv *http.Server, serve func(net.Listener) error) {
+	if serve == nil {
+		serve = srv.Serve
+	}
+	m.servers = append(m.servers, server{srv: srv, serve: serve})
+}
+
+// Go starts a background worker. The context passed to fn is cancelled once
+// the servers have been drained, and Run waits for fn to return.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			if rec := recover(); rec != nil {
+				m.logger.Error("background worker panicked", "worker", name, "panic", fmt.Sprint(rec))
+			}
+		}()
+		fn(m.ctx)
+	}()
+}
+
+// OnClose registers a resource to be released at the very end of shutdown.
+// Closers run in reverse registration order, like deferred calls.
+func (m *Manager) OnClose(name string, fn func() error) {
+	m.closers = append(m.closers, closer{name: name, fn: fn})
+}
+
+// Run binds the listeners of the registered servers, starts serving and
+// blocks until a termination signal arrives or a server fails, then performs
+// the ordered shutdown. The service is ready only once every listener is
+// bound; when one cannot be bound, Run shuts down and returns the error.
+func (m *Manager) Run() error {
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listeners := make([]net.Listener, 0, len(m.servers))
+	for _, s := range m.servers {
+		addr := s.srv.Addr
+		if addr == "" {
+			addr = ":http"
+		}
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return m.Abort(fmt.Errorf("server %s: %w", s.srv.Addr, err))
+		}
+		listeners = append(listeners, ln)
+	}
+
+	serveErr := make(chan error, len(m.servers))
+	for i, s := range m.servers {
+		go func() {
+			m.logger.Info("starting server", "addr", listeners[i].Addr().String())
+			err := s.serve(listeners[i])
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("server %s: %w", s.srv.Addr, err)
+			}
+		}()
+	}
+	m.ready.Store(true)
+
+	var runErr error
+	select {
+	case <-sigCtx.Done():
+		m.logger.Info("shutdown signal received")
+	case runErr = <-serveErr:
+		m.logger.Error("server failed", "error", runErr)
+	}
+	return errors.Join(runErr, m.shutdown())
+}
+
+// Abort is the ordered shutdown of a start-up that failed before Run: it
+// stops the workers and closes the resources registered so far, and returns
+// err together with any error of the shutdown.
+func (m *Manager) Abort(err error) error {
+	return errors.Join(err, m.shutdown())
+}
+
+func (m *Manager) shutdown() error {
+	m.ready.Store(false)
+	m.logger.Info("shutting down", "timeout", m.shutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, s := range m.servers {
+		if err := s.srv.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown server %s: %w", s.srv.Addr, err))
+		}
+	}
+
+	m.cancel()
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, errors.New("background workers did not stop before shutdown deadline"))
+	}
+
+	for i := len(m.closers) - 1; i >= 0; i-- {
+		c := m.closers[i]
+		if err := c.fn(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", c.name, err))
+		}
+	}
+	m.logger.Info("shutdown complete")
+	return errors.Join(errs...)
+}
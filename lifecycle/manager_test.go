@@ -0,0 +1,97 @@
+package lifecycle
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newManager() *Manager {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), time.Second)
+}
+
+func TestRunFailsWhenAListenerCannotBeBound(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	m := newManager()
+	var order []string
+	m.OnClose("db", func() error { order = append(order, "db"); return nil })
+	workerStopped := make(chan struct{})
+	m.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		close(workerStopped)
+	})
+	free := &http.Server{Addr: "127.0.0.1:0"}
+	m.Serve(free, func(ln net.Listener) error {
+		t.Error("a server was started although another listener could not be bound")
+		return free.Serve(ln)
+	})
+	m.Serve(&http.Server{Addr: taken.Addr().String()}, nil)
+
+	err = m.Run()
+	if err == nil || !strings.Contains(err.Error(), taken.Addr().String()) {
+		t.Fatalf("Run = %v, want the bind error", err)
+	}
+	if m.Ready() {
+		t.Error("ready after a failed start")
+	}
+	select {
+	case <-workerStopped:
+	default:
+		t.Error("worker was not stopped")
+	}
+	if len(order) != 1 {
+		t.Errorf("closers run: %v, want db", order)
+	}
+}
+
+func TestRunIsReadyOnceListeningAndDrainsOnSignal(t *testing.T) {
+	m := newManager()
+	srv := &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}
+	addr := make(chan string, 1)
+	m.Serve(srv, func(ln net.Listener) error {
+		addr <- ln.Addr().String()
+		return srv.Serve(ln)
+	})
+	var closed bool
+	m.OnClose("db", func() error { closed = true; return nil })
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run() }()
+
+	a := <-addr
+	conn, err := net.Dial("tcp", a)
+	if err != nil {
+		t.Fatalf("listener is not accepting: %v", err)
+	}
+	conn.Close()
+	for deadline := time.Now().Add(time.Second); !m.Ready(); {
+		if time.Now().After(deadline) {
+			t.Fatal("never became ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after SIGTERM")
+	}
+	if err != nil {
+		t.Errorf("Run = %v", err)
+	}
+	if m.Ready() || !closed {
+		t.Errorf("after shutdown: ready %v, closed %v", m.Ready(), closed)
+	}
+}
@@ -1,15 +1,29 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"log/slog"
-	"net/http"
 	"os"
+	"testTaskEffectiveMobile/budgets"
+	"testTaskEffectiveMobile/config"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/events"
+	"testTaskEffectiveMobile/health"
+	"testTaskEffectiveMobile/lifecycle"
+	"testTaskEffectiveMobile/logging"
+	"testTaskEffectiveMobile/metrics"
+	"testTaskEffectiveMobile/models"
+	"testTaskEffectiveMobile/notifications"
+	"testTaskEffectiveMobile/outbox"
 	"testTaskEffectiveMobile/postgres_db"
-	v1 "testTaskEffectiveMobile/postgres_db/migrations/v1"
+	"testTaskEffectiveMobile/postgres_db/migrations"
 	"testTaskEffectiveMobile/postgres_db/repositories"
+	"testTaskEffectiveMobile/seed"
+	"testTaskEffectiveMobile/tracing"
+	"testTaskEffectiveMobile/webhooks"
 	"time"
 
 	_ "testTaskEffectiveMobile/docs"
@@ -20,7 +34,17 @@ import (
 // TODO: разобраться, зачем здесь указатели
 type application struct {
 	subscriptions *repositories.SubscriptionsRepository
+	services      *repositories.ServicesRepository
+	calendar      calendarTokens
+	budgets       *repositories.BudgetsRepository
+	webhooks      *repositories.WebhooksRepository
+	notifications *repositories.NotificationsRepository
+	events        *events.Broker
 	logger        *slog.Logger
+	lifecycle     *lifecycle.Manager
+	health        *health.Registry
+	metrics       *metrics.Metrics
+	config        config.Config
 }
 
 //	@title			Swagger API Documentation
@@ -34,39 +58,263 @@ type application struct {
 // @host		localhost:8080
 // @BasePath	/api/v1
 func main() {
-	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("POSTGRES_HOST"),
-		os.Getenv("POSTGRES_PORT"),
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB"))
-	db, closer, err := postgres_db.ConnectPostgres(psqlInfo)
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
 	if err != nil {
-		log.Fatal(err)
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	logger := newLogger(cfg.Log)
+	logger.Info("effective configuration", "config", cfg)
+
+	db, closer, err := postgres_db.ConnectPostgres(context.Background(), cfg.DB, logger)
+	if err != nil {
+		fail(logger, err)
+	}
+	lc := lifecycle.New(logger, cfg.HTTP.ShutdownTimeout)
+	lc.OnClose("postgres", func() error {
+		closer()
+		return nil
+	})
+	if cfg.DB.StatsInterval > 0 {
+		lc.Go("db-stats", func(ctx context.Context) {
+			postgres_db.LogStats(ctx, db, cfg.DB.StatsInterval, logger)
+		})
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fail(logger, lc.Abort(err))
 	}
-	defer closer()
+	lc.OnClose("tracing", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
 
-	// Simple migrations
-	subscriptionMigration := v1.SubscriptionMigration{Db: db}
-	err = subscriptionMigration.Init()
+	err = migrations.Apply(context.Background(), db)
 	if err != nil {
-		log.Fatal(err)
+		fail(logger, lc.Abort(err))
+	}
+
+	checks := health.NewRegistry(cfg.Health.CheckTimeout)
+	checks.Register("postgres", db.PingContext)
+	checks.Register("migrations", func(ctx context.Context) error {
+		current, err := migrations.Current(ctx, db)
+		if err != nil {
+			return err
+		}
+		if current != migrations.Latest() {
+			return fmt.Errorf("schema version %d, expected %d", current, migrations.Latest())
+		}
+		return nil
+	})
+	checks.Register("shutdown", func(ctx context.Context) error {
+		if !lc.Ready() {
+			return errors.New("shutting down")
+		}
+		return nil
+	})
+
+	var replicas *postgres_db.ReplicaSet
+	if len(cfg.DB.ReplicaDSNs) > 0 {
+		replicas, err = postgres_db.OpenReplicas(db, cfg.DB, logger)
+		if err != nil {
+			fail(logger, lc.Abort(err))
+		}
+		lc.OnClose("replicas", replicas.Close)
+		lc.Go("replica-health", func(ctx context.Context) {
+			replicas.Run(ctx, cfg.DB.ReplicaCheckInterval)
+		})
+	}
+
+	app := &application{subscriptions: &repositories.SubscriptionsRepository{Db: db, Replicas: replicas},
+		services:      &repositories.ServicesRepository{Db: db},
+		calendar:      &repositories.CalendarRepository{Db: db},
+		budgets:       &repositories.BudgetsRepository{Db: db},
+		webhooks:      &repositories.WebhooksRepository{Db: db},
+		notifications: &repositories.NotificationsRepository{Db: db},
+		logger:        logger,
+		lifecycle:     lc,
+		health:        checks,
+		config:        cfg}
+
+	if cfg.Seed.Users > 0 {
+		if err = app.seedIfEmpty(context.Background()); err != nil {
+			fail(logger, lc.Abort(err))
+		}
+	}
+
+	if cfg.Features.Metrics {
+		app.metrics = metrics.New(db)
+		lc.Go("business-metrics", func(ctx context.Context) {
+			app.metrics.CollectBusiness(ctx, cfg.Metrics.BusinessInterval, logger,
+				func(ctx context.Context) (dto.SubscriptionStats, error) {
+					return app.subscriptions.Stats(ctx, time.Now())
+				})
+		})
 	}
 
-	app := &application{subscriptions: &repositories.SubscriptionsRepository{Db: db},
-		logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	if cfg.Budgets.CheckInterval > 0 {
+		var notifier budgets.Notifier = budgets.LogNotifier{Logger: logger}
+		if cfg.Notifications.SMTP.Addr != "" {
+			notifier = &notifications.BudgetNotifier{
+				Settings: app.notifications.Get,
+				Sender:   newMailSender(cfg.Notifications.SMTP, logger),
+				Logger:   logger,
+			}
+		}
+		evaluator := &budgets.Evaluator{
+			Store:     app.budgets,
+			Calculate: app.subscriptions.CalculateSum,
+			Notifier:  notifier,
+			Logger:    logger,
+		}
+		lc.Go("budget-alerts", func(ctx context.Context) {
+			evaluator.Run(ctx, cfg.Budgets.CheckInterval)
+		})
+	}
 
-	s := http.Server{
-		Addr:         ":8080",
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
-		IdleTimeout:  120 * time.Second,
-		Handler:      app.routes(),
+	if cfg.Notifications.CheckInterval > 0 {
+		scheduler := &notifications.Scheduler{
+			Store:         app.notifications,
+			Subscriptions: app.subscriptions.ActiveBetween,
+			Sender:        newMailSender(cfg.Notifications.SMTP, logger),
+			Logger:        logger,
+		}
+		lc.Go("reminders", func(ctx context.Context) {
+			scheduler.Run(ctx, cfg.Notifications.CheckInterval)
+		})
+	}
+
+	if cfg.Webhooks.PollInterval > 0 {
+		dispatcher := &webhooks.Dispatcher{
+			Store:       app.webhooks,
+			Client:      webhooks.NewClient(cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivate),
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			Backoff:     cfg.Webhooks.Backoff,
+			Logger:      logger,
+		}
+		lc.Go("webhooks", func(ctx context.Context) {
+			dispatcher.Run(ctx, cfg.Webhooks.PollInterval)
+		})
 	}
-	err = s.ListenAndServe()
+
+	outboxRepo := &repositories.OutboxRepository{Db: db}
+	lastEventID, err := outboxRepo.LastID(context.Background())
 	if err != nil {
-		if !errors.Is(err, http.ErrServerClosed) {
-			panic(err)
+		fail(logger, lc.Abort(err))
+	}
+	app.events = events.NewBroker(cfg.Events.History, lastEventID)
+	if cfg.Events.Source == "postgres" {
+		listener := &events.Listener{
+			DSN:     cfg.DB.ConnectionString(),
+			Channel: repositories.EventsChannel,
+			Source:  outboxRepo,
+			Broker:  app.events,
+			Logger:  logger,
+		}
+		lc.Go("event-listener", listener.Run)
+	}
+
+	if cfg.Outbox.PollInterval > 0 {
+		relay := &outbox.Relay{
+			Store:      outboxRepo,
+			Publishers: make(map[string]outbox.Publisher),
+			Retention:  cfg.Outbox.Retention,
+			Logger:     logger,
+		}
+		for _, name := range cfg.Outbox.Publishers {
+			switch name {
+			case "webhook":
+				relay.Publishers[name] = outbox.PublisherFunc(app.webhooks.Enqueue)
+			case "log":
+				relay.Publishers[name] = outbox.LogPublisher{Logger: logger}
+			case "nats":
+				nats := &outbox.NATSPublisher{
+					URL:           cfg.Outbox.NATS.URL,
+					SubjectPrefix: cfg.Outbox.NATS.SubjectPrefix,
+					JetStream:     cfg.Outbox.NATS.JetStream,
+					Timeout:       cfg.Outbox.NATS.Timeout,
+				}
+				relay.Publishers[name] = nats
+				lc.OnClose("nats", nats.Close)
+			}
+		}
+		if cfg.Events.Source == "local" {
+			relay.Publishers["events"] = outbox.PublisherFunc(func(_ context.Context, e dto.OutboxEvent) error {
+				if ev, ok := events.FromOutbox(e); ok {
+					app.events.Publish(ev)
+				}
+				return nil
+			})
 		}
+		lc.Go("outbox-relay", func(ctx context.Context) {
+			relay.Run(ctx, cfg.Outbox.PollInterval)
+		})
+	}
+
+	if err = app.serve(); err != nil {
+		fail(logger, err)
+	}
+}
+
+// fail logs err and exits. Once the lifecycle manager exists, err comes from
+// its Abort or Run, so that workers are stopped and resources closed first.
+func fail(logger *slog.Logger, err error) {
+	logger.Error(err.Error())
+	os.Exit(1)
+}
+
+// seedIfEmpty fills a fresh database with demo data, so that restarts with
+// seeding still enabled do not duplicate it.
+func (app *application) seedIfEmpty(ctx context.Context) error {
+	empty, err := app.subscriptions.Empty(ctx)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		app.logger.Info("database is not empty, seeding skipped")
+		return nil
+	}
+	opts, err := seed.FromConfig(app.config.Seed, time.Now())
+	if err != nil {
+		return err
+	}
+	n, err := app.subscriptions.CopyFrom(ctx, func(add func(models.Subscription) error) error {
+		return seed.Generate(opts, add)
+	})
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	app.logger.Info("database seeded", "subscriptions", n, "seed", app.config.Seed.Seed)
+	return nil
+}
+
+// newMailSender sends through the configured SMTP server, or only logs the
+// messages without one.
+func newMailSender(cfg config.SMTP, logger *slog.Logger) notifications.Sender {
+	if cfg.Addr == "" {
+		return notifications.LogSender{Logger: logger}
+	}
+	return &notifications.SMTPSender{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		From:     cfg.From,
+		Timeout:  cfg.Timeout,
+	}
+}
+
+func newLogger(cfg config.Log) *slog.Logger {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.Level))
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(os.Stdout, opts)
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
 	}
+	return slog.New(logging.NewContextHandler(handler))
 }
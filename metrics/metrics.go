@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"testTaskEffectiveMobile/dto"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "subscriptions"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpPanics   *prometheus.CounterVec
+
+	activeSubscriptions     prometheus.Gauge
+	subscriptionsByService  *prometheus.GaugeVec
+	monthlyRecurringRevenue prometheus.Gauge
+}
+
+// New creates the metric families and registers them, together with the Go
+// runtime, process and sql.DB pool collectors, on a dedicated registry.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests processed, by route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by route pattern, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		httpPanics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_panics_total",
+			Help:      "Handler panics recovered, by route pattern.",
+		}, []string{"route"}),
+		activeSubscriptions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active",
+			Help:      "Subscriptions active in the current month.",
+		}),
+		subscriptionsByService: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_by_service",
+			Help:      "Subscriptions active in the current month, by service name.",
+		}, []string{"service"}),
+		monthlyRecurringRevenue: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "monthly_recurring_revenue",
+			Help:      "What subscriptions active in the current month are charged for it, trial and promo prices included.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "postgres"),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpPanics,
+		m.activeSubscriptions,
+		m.subscriptionsByService,
+		m.monthlyRecurringRevenue,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) ObserveRequest(route, method string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, code).Inc()
+	m.httpDuration.WithLabelValues(route, method, code).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) ObservePanic(route string) {
+	m.httpPanics.WithLabelValues(route).Inc()
+}
+
+func (m *Metrics) SetBusiness(stats dto.SubscriptionStats) {
+	m.activeSubscriptions.Set(float64(stats.Active))
+	m.monthlyRecurringRevenue.Set(float64(stats.MonthlyRecurringRevenue))
+	m.subscriptionsByService.Reset()
+	for service, count := range stats.ByService {
+		m.subscriptionsByService.WithLabelValues(service).Set(float64(count))
+	}
+}
+
+// CollectBusiness refreshes the business gauges from source every interval until ctx is cancelled.
+func (m *Metrics) CollectBusiness(ctx context.Context, interval time.Duration, logger *slog.Logger,
+	source func(ctx context.Context) (dto.SubscriptionStats, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats, err := source(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Error("collect business metrics: " + err.Error())
+		} else {
+			m.SetBusiness(stats)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
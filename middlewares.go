@@ -1,16 +1,313 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testTaskEffectiveMobile/logging"
+	"testTaskEffectiveMobile/postgres_db"
+	"time"
 
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// LogMiddleware assigns the request id (taken from a well-formed client
+// X-Request-ID or generated), echoes it back, attaches it to the request
+// context for every log call made while serving, and writes one access log
+// record when the request completes.
 func (app *application) LogMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var (
-			ip     = r.RemoteAddr
-			proto  = r.Proto
-			method = r.Method
-			uri    = r.RequestURI
-		)
-		app.logger.Info("received request", "ip", ip, "proto", proto, "method", method, "uri", uri)
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		r = r.WithContext(logging.WithRequestID(r.Context(), id))
+
+		start := time.Now()
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		attrs := []any{
+			"method", r.Method,
+			"route", routePattern(r),
+			"uri", safeURI(r),
+			"proto", r.Proto,
+			"status", rec.status,
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", rec.bytes,
+			"ip", ip,
+			"user_agent", r.UserAgent(),
+		}
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			attrs = append(attrs, "forwarded_for", fwd)
+		}
+		app.logger.InfoContext(r.Context(), "request completed", attrs...)
+	})
+}
+
+// validRequestID accepts short ids made of characters that are safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (app *application) MetricsMiddleware(next http.Handler) http.Handler {
+	if app.metrics == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r)
+		app.metrics.ObserveRequest(routePattern(r), r.Method, rec.status, time.Since(start))
+	})
+}
+
+// TracingMiddleware starts a server span per request, continuing the trace
+// from an incoming W3C traceparent header. Once the request has been routed
+// the span is named after the route, e.g. "GET /subscriptions/{user_id}".
+//
+// otelhttp records the request path as a span attribute, so it is handed a
+// copy of the request with the calendar feed token masked; the handlers
+// behind it get the real URL back.
+func (app *application) TracingMiddleware(next http.Handler) http.Handler {
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if orig, ok := r.Context().Value(unmaskedKey{}).(*http.Request); ok {
+			r = r.WithContext(r.Context())
+			r.URL, r.RequestURI = orig.URL, orig.RequestURI
+		}
+		next.ServeHTTP(w, r)
+		route := routePattern(r)
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(attribute.String("http.route", route))
+	})
+	traced := otelhttp.NewHandler(routed, "api", otelhttp.WithSpanNameFormatter(
+		func(_ string, r *http.Request) string {
+			return r.Method + " " + routePattern(r)
+		}))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		masked := r.WithContext(context.WithValue(r.Context(), unmaskedKey{}, r))
+		u := *r.URL
+		u.Path, u.RawPath = maskPath(u.Path), ""
+		masked.URL = &u
+		masked.RequestURI = safeURI(r)
+		traced.ServeHTTP(w, masked)
+	})
+}
+
+type unmaskedKey struct{}
+
+// RecoverMiddleware turns a handler panic into a 500 problem response, logs
+// it with its stack trace, counts it and optionally writes a crash report.
+func (app *application) RecoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := newResponseRecorder(w)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			stack := debug.Stack()
+			route := routePattern(r)
+			app.logger.ErrorContext(r.Context(), "panic recovered",
+				"panic", fmt.Sprint(v), "method", r.Method, "route", route, "uri", safeURI(r),
+				"stack", string(stack))
+			if app.metrics != nil {
+				app.metrics.ObservePanic(route)
+			}
+			if dir := app.config.HTTP.CrashReportDir; dir != "" {
+				path, err := writeCrashReport(dir, r, route, v, stack)
+				if err != nil {
+					app.logger.ErrorContext(r.Context(), "write crash report: "+err.Error())
+				} else {
+					app.logger.InfoContext(r.Context(), "crash report written", "path", path)
+				}
+			}
+			if rec.wroteHeader {
+				// The response is already on its way; the client will see a truncated body.
+				return
+			}
+			w.Header().Set("Connection", "close")
+			app.writeProblem(w, http.StatusInternalServerError, "internal error, request id "+logging.RequestID(r.Context()))
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+func writeCrashReport(dir string, r *http.Request, route string, v any, stack []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	requestID := logging.RequestID(r.Context())
+	name := fmt.Sprintf("crash-%s-%s.txt", now.Format("20060102T150405.000000000Z"), requestID)
+	path := filepath.Join(dir, name)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "time: %s\n", now.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "request_id: %s\n", requestID)
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		fmt.Fprintf(&b, "trace_id: %s\n", sc.TraceID())
+	}
+	fmt.Fprintf(&b, "method: %s\nroute: %s\nuri: %s\n", r.Method, route, safeURI(r))
+	fmt.Fprintf(&b, "remote_addr: %s\nuser_agent: %s\n", r.RemoteAddr, r.UserAgent())
+	fmt.Fprintf(&b, "panic: %v\n\n%s", v, stack)
+
+	return path, os.WriteFile(path, []byte(b.String()), 0o640)
+}
+
+type routeKey struct{}
+
+// readYourWritesHeader lets a client that has just changed data ask for its
+// reads to be served by the primary instead of a possibly lagging replica.
+const readYourWritesHeader = "X-Read-Your-Writes"
+
+func readYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if primary, _ := strconv.ParseBool(r.Header.Get(readYourWritesHeader)); primary {
+			r = r.WithContext(postgres_db.WithPrimary(r.Context()))
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// trackRoute must be the outermost API middleware: it gives the request a
+// slot that recordRoute fills with the matched mux pattern, so that every
+// middleware in between can label by route no matter how it copies the request.
+// It also keeps a controller of the server's own response writer, see connController.
+func trackRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), routeKey{}, new(string))
+		ctx = context.WithValue(ctx, connKey{}, http.NewResponseController(w))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type connKey struct{}
+
+// connController returns a controller for the deadlines of the request's
+// connection. The tracing middleware's writer hides the server's one from
+// http.ResponseController, so it is taken from before that middleware.
+func connController(r *http.Request, w http.ResponseWriter) *http.ResponseController {
+	if rc, ok := r.Context().Value(connKey{}).(*http.ResponseController); ok {
+		return rc
+	}
+	return http.NewResponseController(w)
+}
+
+// recordRoute must wrap the router itself. The route is recorded even when a handler panics.
+func recordRoute(router http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if slot, ok := r.Context().Value(routeKey{}).(*string); ok {
+				*slot = r.Pattern
+			}
+		}()
+		router.ServeHTTP(w, r)
+	})
+}
+
+// safeURI is the request URI to put in logs, crash reports and spans: the
+// token in the path of a calendar feed is masked, as it alone grants access
+// to the feed. It is matched by path rather than by route, which is unknown
+// until the request has been routed.
+func safeURI(r *http.Request) string {
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	if u, err := url.ParseRequestURI(uri); err == nil {
+		if masked := maskPath(u.Path); masked != u.Path {
+			return masked
+		}
+	}
+	return uri
+}
+
+// maskPath masks the token of a calendar feed path, with or without the API prefix.
+func maskPath(p string) string {
+	clean := path.Clean(p)
+	for _, prefix := range []string{"/api/v1" + calendarFeedPrefix, calendarFeedPrefix} {
+		if strings.HasPrefix(clean, prefix) {
+			return prefix + "***"
+		}
+	}
+	return p
+}
+
+// routePattern returns the matched route without its method, e.g. "/subscriptions/{user_id}".
+// It is only known once the router has served the request.
+func routePattern(r *http.Request) string {
+	slot, ok := r.Context().Value(routeKey{}).(*string)
+	if !ok || *slot == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(*slot, " "); ok {
+		return path
+	}
+	return *slot
+}
+
+// responseRecorder remembers the status code and body size written through it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
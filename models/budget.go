@@ -0,0 +1,13 @@
+package models
+
+import "github.com/google/uuid"
+
+// Budget caps what a user spends on subscriptions per month, either in total
+// or on one category or one service.
+type Budget struct {
+	UserId       uuid.UUID `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	MonthlyLimit int       `json:"monthly_limit" example:"2000"`
+	// Category and ServiceName narrow the budget; at most one may be set.
+	Category    *string `json:"category,omitempty" example:"video"`
+	ServiceName *string `json:"service_name,omitempty" example:"Netflix"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Reminder kinds, also the names of their message templates.
+const (
+	ReminderCharge   = "charge"
+	ReminderTrialEnd = "trial_end"
+)
+
+// AlertBudget names the message template of budget alerts.
+const AlertBudget = "budget"
+
+// Locales are the languages reminders are written in; the first is the default.
+var Locales = []string{"ru", "en"}
+
+// NotificationSettings are where and when a user is reminded of upcoming
+// charges and trial ends.
+type NotificationSettings struct {
+	Email string `json:"email" example:"user@example.com"`
+	// Locale is ru when omitted.
+	Locale string `json:"locale,omitempty" example:"ru" enums:"ru,en"`
+	// DaysBefore is how many days ahead reminders are sent, 3 when omitted.
+	DaysBefore int `json:"days_before,omitempty" example:"3" minimum:"1" maximum:"30"`
+	// ChargeReminders and TrialReminders are true when omitted.
+	ChargeReminders *bool `json:"charge_reminders,omitempty" example:"true"`
+	TrialReminders  *bool `json:"trial_reminders,omitempty" example:"true"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Service is a catalogue entry that subscriptions can reference.
+type Service struct {
+	Name string `json:"name" example:"Netflix"`
+	// Aliases are other spellings resolved to this service, compared case-
+	// and whitespace-insensitively.
+	Aliases      []string `json:"aliases" example:"NFLX,Нетфликс"`
+	Category     *string  `json:"category" example:"video"`
+	DefaultPrice *int     `json:"default_price" example:"999"`
+	Website      *string  `json:"website" example:"https://www.netflix.com"`
+}
@@ -30,14 +30,28 @@ func (m *MonthYearDate) UnmarshalJSON(b []byte) error {
 	if s == "null" {
 		return nil
 	}
-	t, err := time.Parse(monthYearDateFormat, s)
+	t, err := ParseMonthYearDate(s)
 	if err != nil {
 		return err
 	}
-	*m = MonthYearDate{t}
+	*m = t
 	return nil
 }
 
+// ParseMonthYearDate parses an MM-YYYY string.
+func ParseMonthYearDate(s string) (MonthYearDate, error) {
+	t, err := time.Parse(monthYearDateFormat, s)
+	if err != nil {
+		return MonthYearDate{}, err
+	}
+	return MonthYearDate{t}, nil
+}
+
+// MonthYear formats the date as MM-YYYY.
+func (m MonthYearDate) MonthYear() string {
+	return m.Format(monthYearDateFormat)
+}
+
 // TODO: разобраться подробнее с работой этих ресиверов
 func (m MonthYearDate) Value() (driver.Value, error) {
 	return m.Time, nil
@@ -69,4 +83,50 @@ type Subscription struct {
 	UserId      uuid.UUID      `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
 	StartDate   MonthYearDate  `json:"start_date" example:"01-2024" swaggertype:"string" format:"MM-YYYY"`
 	EndDate     *MonthYearDate `json:"end_date" example:"12-2024" swaggertype:"string" format:"MM-YYYY"`
+	// TrialMonths are the first months, charged at TrialPrice (usually 0).
+	TrialMonths int `json:"trial_months,omitempty" example:"1"`
+	TrialPrice  int `json:"trial_price,omitempty" example:"0"`
+	// PromoMonths follow the trial at PromoPrice; Price applies afterwards.
+	PromoMonths int `json:"promo_months,omitempty" example:"3"`
+	PromoPrice  int `json:"promo_price,omitempty" example:"499"`
+	// BillingPeriod is how often Price is charged: monthly (the default),
+	// quarterly or yearly. Trial and promo months are charged monthly.
+	BillingPeriod string `json:"billing_period,omitempty" enums:"monthly,quarterly,yearly" example:"monthly"`
+	// PriceChanges replace Price from their month on. On update, omitting
+	// them keeps the current changes and an empty list removes them.
+	PriceChanges []PriceChange `json:"price_changes,omitempty"`
+	// ServiceID links the subscription to the service catalogue. When it is
+	// omitted, service_name is matched against catalogue names and aliases.
+	ServiceID *int `json:"service_id,omitempty" example:"1"`
+	// Category overrides the category of the catalogue entry.
+	Category *string `json:"category,omitempty" example:"entertainment"`
+	// Tags are free-form labels, stored lower-cased. On update, omitting them
+	// keeps the current tags and an empty list removes them.
+	Tags []string `json:"tags,omitempty" example:"family,work"`
+}
+
+const (
+	BillingMonthly   = "monthly"
+	BillingQuarterly = "quarterly"
+	BillingYearly    = "yearly"
+)
+
+// BillingPeriodMonths returns how many months one charge of the billing
+// period covers, 0 for an unknown period. An empty period is monthly.
+func BillingPeriodMonths(period string) int {
+	switch period {
+	case "", BillingMonthly:
+		return 1
+	case BillingQuarterly:
+		return 3
+	case BillingYearly:
+		return 12
+	}
+	return 0
+}
+
+// PriceChange is a regular price taking effect from Month on.
+type PriceChange struct {
+	Month MonthYearDate `json:"month" example:"01-2026" swaggertype:"string" format:"MM-YYYY"`
+	Price int           `json:"price" example:"1199"`
 }
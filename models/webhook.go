@@ -0,0 +1,37 @@
+package models
+
+// Subscription lifecycle events sent to webhooks.
+const (
+	EventSubscriptionCreated = "subscription.created"
+	EventSubscriptionUpdated = "subscription.updated"
+	// EventSubscriptionEnded follows EventSubscriptionUpdated when an update
+	// gives an open-ended subscription an end date.
+	EventSubscriptionEnded   = "subscription.ended"
+	EventSubscriptionDeleted = "subscription.deleted"
+)
+
+// Delivery statuses; failed deliveries form the dead-letter queue.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+var SubscriptionEvents = []string{
+	EventSubscriptionCreated,
+	EventSubscriptionUpdated,
+	EventSubscriptionEnded,
+	EventSubscriptionDeleted,
+}
+
+// Webhook registers a URL to receive signed subscription events.
+type Webhook struct {
+	URL string `json:"url" example:"https://crm.example.com/hooks/subscriptions"`
+	// Secret signs the deliveries. It is generated when left empty and is
+	// only returned when the webhook is created.
+	Secret string   `json:"secret,omitempty" example:"s3cr3t-at-least-16-bytes"`
+	Events []string `json:"events" example:"subscription.created,subscription.deleted"`
+	// Active is true when omitted; inactive webhooks keep their pending
+	// deliveries until they are activated again.
+	Active *bool `json:"active,omitempty" example:"true"`
+}
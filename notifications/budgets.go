@@ -0,0 +1,40 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testTaskEffectiveMobile/budgets"
+	"testTaskEffectiveMobile/dto"
+
+	"github.com/google/uuid"
+)
+
+// Settings returns the notification settings of a user, or sql.ErrNoRows,
+// as NotificationsRepository.Get.
+type Settings func(ctx context.Context, userID uuid.UUID) (dto.NotificationSettingsDTO, error)
+
+// BudgetNotifier emails budget alerts to the address in the notification
+// settings of the user, in their locale. Alerts of users without settings
+// are only logged.
+type BudgetNotifier struct {
+	Settings Settings
+	Sender   Sender
+	Logger   *slog.Logger
+}
+
+func (n *BudgetNotifier) Notify(ctx context.Context, a budgets.Alert) error {
+	settings, err := n.Settings(ctx, a.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return budgets.LogNotifier{Logger: n.Logger}.Notify(ctx, a)
+	}
+	if err != nil {
+		return err
+	}
+	msg, err := RenderBudgetAlert(a, settings.Email, settings.Locale)
+	if err != nil {
+		return err
+	}
+	return n.Sender.Send(ctx, msg)
+}
@@ -0,0 +1,115 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testTaskEffectiveMobile/budgets"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func alert(category, service *string) budgets.Alert {
+	return budgets.Alert{
+		BudgetID:    4,
+		UserID:      uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+		Month:       month("03-2025"),
+		Threshold:   80,
+		Limit:       1000,
+		Spent:       850,
+		Category:    category,
+		ServiceName: service,
+	}
+}
+
+func TestRenderBudgetAlert(t *testing.T) {
+	video, netflix := "video <hd>", "Netflix"
+	tests := []struct {
+		name    string
+		alert   budgets.Alert
+		locale  string
+		subject string
+		text    string
+	}{
+		{"all subscriptions", alert(nil, nil), "ru", "Бюджет: потрачено 80% лимита за 03.2025",
+			"Расходы на подписки за 03.2025 достигли 80% месячного лимита: 850 ₽ из 1000 ₽."},
+		{"category", alert(&video, nil), "ru", "Бюджет «video <hd>»",
+			"на подписки в категории «video <hd>» за 03.2025"},
+		{"service", alert(nil, &netflix), "en", "Budget Netflix: 80% of the limit spent in March 2025",
+			"Your spending on the Netflix subscription in March 2025 has reached 80% of the monthly limit: 850 RUB of 1000 RUB."},
+		// Unknown locales fall back to Russian.
+		{"unknown locale", alert(nil, nil), "de", "за 03.2025", "Бюджет #4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := RenderBudgetAlert(tt.alert, "user@example.com", tt.locale)
+			if err != nil {
+				t.Fatalf("RenderBudgetAlert: %v", err)
+			}
+			if m.To != "user@example.com" || !strings.Contains(m.Subject, tt.subject) {
+				t.Errorf("to %q, subject %q; want user@example.com, %q", m.To, m.Subject, tt.subject)
+			}
+			if !strings.Contains(m.Text, tt.text) {
+				t.Errorf("text = %q, want it to contain %q", m.Text, tt.text)
+			}
+			if strings.Contains(m.HTML, "<hd>") {
+				t.Errorf("HTML does not escape the category: %q", m.HTML)
+			}
+		})
+	}
+}
+
+func TestBudgetNotifier(t *testing.T) {
+	unavailable := errors.New("database unavailable")
+	tests := []struct {
+		name     string
+		settings dto.NotificationSettingsDTO
+		err      error
+		sent     int
+		wantErr  error
+	}{
+		{"with settings", dto.NotificationSettingsDTO{NotificationSettings: models.NotificationSettings{Email: "user@example.com", Locale: "en"}}, nil, 1, nil},
+		{"without settings", dto.NotificationSettingsDTO{}, sql.ErrNoRows, 0, nil},
+		{"failing settings", dto.NotificationSettingsDTO{}, unavailable, 0, unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &flakySender{}
+			n := &BudgetNotifier{
+				Settings: func(context.Context, uuid.UUID) (dto.NotificationSettingsDTO, error) {
+					return tt.settings, tt.err
+				},
+				Sender: sender,
+				Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			if err := n.Notify(context.Background(), alert(nil, nil)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Notify = %v, want %v", err, tt.wantErr)
+			}
+			if len(sender.sent) != tt.sent {
+				t.Fatalf("sent %d messages, want %d", len(sender.sent), tt.sent)
+			}
+			if tt.sent > 0 && (sender.sent[0].To != "user@example.com" || !strings.HasPrefix(sender.sent[0].Subject, "Budget")) {
+				t.Errorf("sent to %q with subject %q", sender.sent[0].To, sender.sent[0].Subject)
+			}
+		})
+	}
+}
+
+func TestBudgetNotifierReportsSendFailures(t *testing.T) {
+	n := &BudgetNotifier{
+		Settings: func(context.Context, uuid.UUID) (dto.NotificationSettingsDTO, error) {
+			return dto.NotificationSettingsDTO{NotificationSettings: models.NotificationSettings{Email: "user@example.com"}}, nil
+		},
+		Sender: &flakySender{fail: 1},
+	}
+	// The evaluator raises the alert again when delivery fails.
+	if err := n.Notify(context.Background(), alert(nil, nil)); err == nil {
+		t.Error("Notify succeeded although the mail server is unavailable")
+	}
+}
@@ -0,0 +1,192 @@
+// Package notifications reminds users by email of their upcoming charges and
+// trial ends, as many days ahead as their settings ask for. A reminder is
+// recorded before it is sent, so every replica and every run sends it once.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testTaskEffectiveMobile/billing"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a rendered email.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers messages. An error makes the scheduler send the reminder
+// again on its next run.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// LogSender writes messages to the log instead of sending them.
+type LogSender struct {
+	Logger *slog.Logger
+}
+
+func (s LogSender) Send(ctx context.Context, m Message) error {
+	s.Logger.InfoContext(ctx, "reminder", "to", m.To, "subject", m.Subject)
+	return nil
+}
+
+// Store is the reminder persistence; repositories.NotificationsRepository implements it.
+type Store interface {
+	List(ctx context.Context) ([]dto.NotificationSettingsDTO, error)
+	RecordSent(ctx context.Context, r dto.Reminder) (bool, error)
+	ForgetSent(ctx context.Context, r dto.Reminder) error
+}
+
+// Subscriptions returns the subscriptions of a user active in any month of
+// from..to, as SubscriptionsRepository.ActiveBetween.
+type Subscriptions func(ctx context.Context, userID uuid.UUID, from, to models.MonthYearDate) ([]dto.SubscriptionDTO, error)
+
+type Scheduler struct {
+	Store         Store
+	Subscriptions Subscriptions
+	Sender        Sender
+	Logger        *slog.Logger
+}
+
+// Run sends the reminders due today right away and then every interval
+// until ctx is done.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.SendDue(ctx, time.Now()); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.Logger.Error("sending reminders failed", "error", err, "sent", n)
+		} else if n > 0 {
+			s.Logger.Info("reminders sent", "sent", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends every reminder due on the day of now and returns how many
+// were sent. A failing user does not stop the others; their errors are
+// joined.
+func (s *Scheduler) SendDue(ctx context.Context, now time.Time) (sent int, err error) {
+	all, err := s.Store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	for _, settings := range all {
+		n, err := s.sendDue(ctx, settings, now)
+		sent += n
+		if err != nil {
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (s *Scheduler) sendDue(ctx context.Context, settings dto.NotificationSettingsDTO, now time.Time) (sent int, err error) {
+	today := day(now)
+	last := today.AddDate(0, 0, settings.DaysBefore)
+	subs, err := s.Subscriptions(ctx, settings.UserId,
+		models.MonthYearDate{Time: billing.FirstDay(billing.Month(today))},
+		// A trial ending in the window can leave the first paid month after it.
+		models.MonthYearDate{Time: billing.FirstDay(billing.Month(last) + 1)})
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range Due(settings, subs, now) {
+		msg, err := Render(r, now)
+		if err != nil {
+			return sent, err
+		}
+		recorded, err := s.Store.RecordSent(ctx, r)
+		if err != nil {
+			return sent, err
+		}
+		if !recorded {
+			continue
+		}
+		if err = s.Sender.Send(ctx, msg); err != nil {
+			if forgetErr := s.Store.ForgetSent(context.WithoutCancel(ctx), r); forgetErr != nil {
+				err = errors.Join(err, forgetErr)
+			}
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Due lists the reminders the settings ask for on the day of now: the paid
+// charges and the trial ends of subs after that day and at most DaysBefore
+// days ahead. Charges are due on the first day of their month.
+func Due(settings dto.NotificationSettingsDTO, subs []dto.SubscriptionDTO, now time.Time) []dto.Reminder {
+	today := day(now)
+	last := today.AddDate(0, 0, settings.DaysBefore)
+	inWindow := func(t time.Time) bool { return t.After(today) && !t.After(last) }
+	remind := func(kind string, s dto.SubscriptionDTO, date time.Time, price int, phase string) dto.Reminder {
+		return dto.Reminder{
+			Kind:           kind,
+			UserID:         settings.UserId,
+			Email:          settings.Email,
+			Locale:         settings.Locale,
+			SubscriptionID: s.Id,
+			ServiceName:    s.ServiceName,
+			Date:           date,
+			Price:          price,
+			Phase:          phase,
+		}
+	}
+
+	var due []dto.Reminder
+	for _, s := range subs {
+		plan := billing.PlanOf(s.Subscription)
+		if enabled(settings.ChargeReminders) {
+			for _, c := range plan.Charges(today, last) {
+				if c.Price > 0 && inWindow(c.Month.Time) {
+					due = append(due, remind(models.ReminderCharge, s, c.Month.Time, c.Price, string(c.Phase)))
+				}
+			}
+		}
+		if enabled(settings.TrialReminders) {
+			end, ok := plan.TrialEnd()
+			if !ok {
+				continue
+			}
+			firstPaid := end.AddDate(0, 1, 0)
+			price, phase, ok := plan.PriceAt(firstPaid)
+			// Nothing to cancel when the subscription ends with the trial.
+			if ok && inWindow(firstPaid.AddDate(0, 0, -1)) {
+				due = append(due, remind(models.ReminderTrialEnd, s, firstPaid.AddDate(0, 0, -1), price, string(phase)))
+			}
+		}
+	}
+	return due
+}
+
+// day is the start of the day of t in UTC, the time zone of the schedule.
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func enabled(b *bool) bool {
+	return b == nil || *b
+}
@@ -0,0 +1,247 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func month(s string) models.MonthYearDate {
+	m, err := models.ParseMonthYearDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func monthPtr(s string) *models.MonthYearDate {
+	m := month(s)
+	return &m
+}
+
+func settings(daysBefore int) dto.NotificationSettingsDTO {
+	return dto.NotificationSettingsDTO{
+		UserId: uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"),
+		NotificationSettings: models.NotificationSettings{
+			Email:      "user@example.com",
+			Locale:     "ru",
+			DaysBefore: daysBefore,
+		},
+	}
+}
+
+func subscription(s models.Subscription) dto.SubscriptionDTO {
+	s.ServiceName = "Netflix"
+	return dto.SubscriptionDTO{Id: 7, Subscription: s}
+}
+
+// reminder is the part of a dto.Reminder the Due tests compare.
+type reminder struct {
+	kind  string
+	date  string
+	price int
+	phase string
+}
+
+func TestDue(t *testing.T) {
+	off := false
+	noCharges := settings(3)
+	noCharges.ChargeReminders = &off
+	noTrials := settings(3)
+	noTrials.TrialReminders = &off
+
+	june28 := time.Date(2025, 6, 28, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		settings dto.NotificationSettingsDTO
+		sub      models.Subscription
+		now      time.Time
+		want     []reminder
+	}{
+		{
+			name:     "charge on the first of next month",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("01-2025"), Price: 500},
+			now:      june28,
+			want:     []reminder{{models.ReminderCharge, "2025-07-01", 500, "regular"}},
+		},
+		{
+			name:     "charge beyond the window",
+			settings: settings(2),
+			sub:      models.Subscription{StartDate: month("01-2025"), Price: 500},
+			now:      june28,
+		},
+		{
+			name:     "charge today is not ahead",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("01-2025"), Price: 500},
+			now:      time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day is taken in UTC",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("01-2025"), Price: 500},
+			// 2025-06-27 22:00 UTC, so the window ends on June 30.
+			now: time.Date(2025, 6, 28, 1, 0, 0, 0, time.FixedZone("MSK", 3*60*60)),
+		},
+		{
+			name:     "charges of two months",
+			settings: settings(30),
+			sub:      models.Subscription{StartDate: month("01-2025"), Price: 500},
+			now:      time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+			want: []reminder{
+				{models.ReminderCharge, "2025-02-01", 500, "regular"},
+				{models.ReminderCharge, "2025-03-01", 500, "regular"},
+			},
+		},
+		{
+			name:     "no charge after the last month",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("01-2025"), EndDate: monthPtr("06-2025"), Price: 500},
+			now:      june28,
+		},
+		{
+			name:     "trial end and first paid charge",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("06-2025"), Price: 500, TrialMonths: 1},
+			now:      june28,
+			want: []reminder{
+				{models.ReminderCharge, "2025-07-01", 500, "regular"},
+				{models.ReminderTrialEnd, "2025-06-30", 500, "regular"},
+			},
+		},
+		{
+			name:     "trial followed by promo",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("05-2025"), Price: 500, TrialMonths: 2, PromoMonths: 3, PromoPrice: 299},
+			now:      june28,
+			want: []reminder{
+				{models.ReminderCharge, "2025-07-01", 299, "promo"},
+				{models.ReminderTrialEnd, "2025-06-30", 299, "promo"},
+			},
+		},
+		{
+			name:     "free trial month is not a charge",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("07-2025"), Price: 500, TrialMonths: 1},
+			now:      june28,
+		},
+		{
+			name:     "paid trial month is a charge",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("07-2025"), Price: 500, TrialMonths: 1, TrialPrice: 1},
+			now:      june28,
+			want:     []reminder{{models.ReminderCharge, "2025-07-01", 1, "trial"}},
+		},
+		{
+			name:     "subscription ending with its trial",
+			settings: settings(3),
+			sub:      models.Subscription{StartDate: month("06-2025"), EndDate: monthPtr("06-2025"), Price: 500, TrialMonths: 1},
+			now:      june28,
+		},
+		{
+			name:     "charge reminders off",
+			settings: noCharges,
+			sub:      models.Subscription{StartDate: month("06-2025"), Price: 500, TrialMonths: 1},
+			now:      june28,
+			want:     []reminder{{models.ReminderTrialEnd, "2025-06-30", 500, "regular"}},
+		},
+		{
+			name:     "trial reminders off",
+			settings: noTrials,
+			sub:      models.Subscription{StartDate: month("06-2025"), Price: 500, TrialMonths: 1},
+			now:      june28,
+			want:     []reminder{{models.ReminderCharge, "2025-07-01", 500, "regular"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []reminder
+			for _, r := range Due(tt.settings, []dto.SubscriptionDTO{subscription(tt.sub)}, tt.now) {
+				if r.SubscriptionID != 7 || r.Email != "user@example.com" || r.ServiceName != "Netflix" {
+					t.Errorf("reminder not filled in: %+v", r)
+				}
+				got = append(got, reminder{r.Kind, r.Date.Format(time.DateOnly), r.Price, r.Phase})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Due = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// memStore records sent reminders in memory like NotificationsRepository.
+type memStore struct {
+	settings []dto.NotificationSettingsDTO
+	sent     map[string]bool
+}
+
+func key(r dto.Reminder) string {
+	return r.Kind + "/" + r.Date.Format(time.DateOnly)
+}
+
+func (s *memStore) List(context.Context) ([]dto.NotificationSettingsDTO, error) {
+	return s.settings, nil
+}
+
+func (s *memStore) RecordSent(_ context.Context, r dto.Reminder) (bool, error) {
+	if s.sent[key(r)] {
+		return false, nil
+	}
+	s.sent[key(r)] = true
+	return true, nil
+}
+
+func (s *memStore) ForgetSent(_ context.Context, r dto.Reminder) error {
+	delete(s.sent, key(r))
+	return nil
+}
+
+type flakySender struct {
+	fail int
+	sent []Message
+}
+
+func (s *flakySender) Send(_ context.Context, m Message) error {
+	if s.fail > 0 {
+		s.fail--
+		return errors.New("mail server unavailable")
+	}
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func TestSendDueSendsOnceAndRetriesFailures(t *testing.T) {
+	store := &memStore{settings: []dto.NotificationSettingsDTO{settings(3)}, sent: make(map[string]bool)}
+	sender := &flakySender{fail: 1}
+	s := &Scheduler{
+		Store: store,
+		Subscriptions: func(context.Context, uuid.UUID, models.MonthYearDate, models.MonthYearDate) ([]dto.SubscriptionDTO, error) {
+			return []dto.SubscriptionDTO{subscription(models.Subscription{StartDate: month("01-2025"), Price: 500})}, nil
+		},
+		Sender: sender,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	now := time.Date(2025, 6, 28, 10, 0, 0, 0, time.UTC)
+
+	for i, want := range []int{0, 1, 0} {
+		n, err := s.SendDue(context.Background(), now)
+		if (err != nil) != (i == 0) {
+			t.Errorf("run %d: err = %v", i+1, err)
+		}
+		if n != want {
+			t.Errorf("run %d: sent %d, want %d", i+1, n, want)
+		}
+	}
+	if len(sender.sent) != 1 || sender.sent[0].To != "user@example.com" {
+		t.Errorf("sent %+v, want one reminder to user@example.com", sender.sent)
+	}
+}
@@ -0,0 +1,206 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession is what the stand-in server received.
+type smtpSession struct {
+	commands []string
+	data     string
+}
+
+// startSMTP runs a minimal SMTP server for one session on loopback. It does
+// not offer STARTTLS and answers RCPT with rcptReply.
+func startSMTP(t *testing.T, rcptReply string) (addr string, done <-chan smtpSession) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		var s smtpSession
+		defer func() { sessions <- s }()
+
+		r := bufio.NewReader(conn)
+		reply := func(lines ...string) { io.WriteString(conn, strings.Join(lines, "\r\n")+"\r\n") }
+		reply("220 localhost ESMTP stand-in")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			s.commands = append(s.commands, cmd)
+			switch verb, _, _ := strings.Cut(strings.ToUpper(cmd), " "); verb {
+			case "EHLO":
+				reply("250-localhost", "250-8BITMIME", "250 AUTH PLAIN")
+			case "AUTH":
+				reply("235 2.7.0 Authentication successful")
+			case "MAIL":
+				reply("250 2.1.0 OK")
+			case "RCPT":
+				reply(rcptReply)
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(line, "."))
+				}
+				s.data = data.String()
+				reply("250 2.0.0 queued")
+			case "QUIT":
+				reply("221 2.0.0 bye")
+				return
+			default:
+				reply("502 5.5.2 command not recognized")
+			}
+		}
+	}()
+	return ln.Addr().String(), sessions
+}
+
+func testMessage() Message {
+	return Message{
+		To:      "user@example.com",
+		Subject: "Netflix: списание 500 ₽ 01.07.2025",
+		Text:    "Через 3 дня, 01.07.2025, будет списано 500 ₽.\n",
+		HTML:    "<p>Через 3 дня будет списано <b>500 ₽</b>.</p>",
+	}
+}
+
+func TestSMTPSenderWithoutSTARTTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		wantAuth string
+	}{
+		{"authenticated", "mailer", "\x00mailer\x00secret"},
+		{"anonymous", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, done := startSMTP(t, "250 2.1.5 OK")
+			sender := &SMTPSender{
+				Addr:     addr,
+				Username: tt.username,
+				Password: "secret",
+				From:     "Subscriptions <noreply@localhost>",
+				Timeout:  5 * time.Second,
+			}
+			if err := sender.Send(context.Background(), testMessage()); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			s := <-done
+
+			var auth string
+			for _, cmd := range s.commands {
+				if strings.HasPrefix(strings.ToUpper(cmd), "STARTTLS") {
+					t.Errorf("STARTTLS sent although not offered")
+				}
+				if rest, ok := strings.CutPrefix(cmd, "AUTH PLAIN "); ok {
+					decoded, err := base64.StdEncoding.DecodeString(rest)
+					if err != nil {
+						t.Fatalf("AUTH PLAIN argument: %v", err)
+					}
+					auth = string(decoded)
+				}
+			}
+			if auth != tt.wantAuth {
+				t.Errorf("AUTH PLAIN = %q, want %q", auth, tt.wantAuth)
+			}
+			if !containsCommand(s.commands, "MAIL FROM:<noreply@localhost>") || !containsCommand(s.commands, "RCPT TO:<user@example.com>") {
+				t.Errorf("envelope commands = %q", s.commands)
+			}
+			checkMessage(t, s.data, testMessage())
+		})
+	}
+}
+
+func TestSMTPSenderRejectedRecipient(t *testing.T) {
+	addr, done := startSMTP(t, "550 5.1.1 mailbox unavailable")
+	sender := &SMTPSender{Addr: addr, From: "noreply@localhost", Timeout: 5 * time.Second}
+	err := sender.Send(context.Background(), testMessage())
+	if err == nil || !strings.Contains(err.Error(), "mailbox unavailable") {
+		t.Errorf("Send = %v, want the server's rejection", err)
+	}
+	<-done
+}
+
+func containsCommand(commands []string, prefix string) bool {
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMessage parses the received message and compares it with m.
+func checkMessage(t *testing.T, data string, m Message) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != m.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, m.Subject)
+	}
+	if to, err := mail.ParseAddress(msg.Header.Get("To")); err != nil || to.Address != m.To {
+		t.Errorf("To = %q", msg.Header.Get("To"))
+	}
+	if msg.Header.Get("Message-Id") == "" || msg.Header.Get("Date") == "" {
+		t.Error("Message-ID or Date missing")
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q", msg.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, want.contentType)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("decode part: %v", err)
+		}
+		// SMTP carries CRLF line endings.
+		if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != want.body {
+			t.Errorf("part body = %q, want %q", got, want.body)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package notifications
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"strings"
+	"testTaskEffectiveMobile/budgets"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	texttemplate "text/template"
+	"time"
+)
+
+// templateFS holds templates/<locale>/<kind>.txt, which defines the subject
+// in a "subject" block and is the plain text body, and <kind>.html, the HTML
+// body. Both get a messageData, or a budgetData for budget alerts.
+//
+//go:embed templates
+var templateFS embed.FS
+
+type messageTemplates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates are keyed by "<locale>/<kind>".
+var templates = loadTemplates()
+
+func loadTemplates() map[string]messageTemplates {
+	loaded := make(map[string]messageTemplates)
+	for _, locale := range models.Locales {
+		funcs := map[string]any{"plural": pluralFunc(locale)}
+		for _, kind := range []string{models.ReminderCharge, models.ReminderTrialEnd, models.AlertBudget} {
+			name := path.Join("templates", locale, kind)
+			loaded[locale+"/"+kind] = messageTemplates{
+				text: texttemplate.Must(texttemplate.New(kind+".txt").Funcs(funcs).ParseFS(templateFS, name+".txt")),
+				html: htmltemplate.Must(htmltemplate.New(kind+".html").Funcs(funcs).ParseFS(templateFS, name+".html")),
+			}
+		}
+	}
+	return loaded
+}
+
+// messageData is what the templates see.
+type messageData struct {
+	Service        string
+	SubscriptionID int
+	// Date is formatted for the locale.
+	Date string
+	// Days is how many days are left until Date.
+	Days  int
+	Price int
+	// Phase is trial, promo or regular.
+	Phase string
+}
+
+// budgetData is what the budget alert templates see.
+type budgetData struct {
+	BudgetID int
+	// Month is formatted for the locale.
+	Month     string
+	Threshold int
+	Limit     int
+	Spent     int64
+	// Category and Service are empty for a budget on all subscriptions.
+	Category string
+	Service  string
+}
+
+// Render writes the reminder as of now in its locale, falling back to the
+// default one.
+func Render(r dto.Reminder, now time.Time) (Message, error) {
+	t, locale, err := lookup(r.Locale, r.Kind)
+	if err != nil {
+		return Message{}, err
+	}
+	return execute(t, r.Email, messageData{
+		Service:        r.ServiceName,
+		SubscriptionID: r.SubscriptionID,
+		Date:           formatDate(locale, r.Date),
+		Days:           int(r.Date.Sub(day(now)).Hours() / 24),
+		Price:          r.Price,
+		Phase:          r.Phase,
+	})
+}
+
+// RenderBudgetAlert writes the alert to the address in the locale, falling
+// back to the default one.
+func RenderBudgetAlert(a budgets.Alert, email, locale string) (Message, error) {
+	t, locale, err := lookup(locale, models.AlertBudget)
+	if err != nil {
+		return Message{}, err
+	}
+	data := budgetData{
+		BudgetID:  a.BudgetID,
+		Month:     formatMonth(locale, a.Month.Time),
+		Threshold: a.Threshold,
+		Limit:     a.Limit,
+		Spent:     a.Spent,
+	}
+	if a.Category != nil {
+		data.Category = *a.Category
+	}
+	if a.ServiceName != nil {
+		data.Service = *a.ServiceName
+	}
+	return execute(t, email, data)
+}
+
+// lookup returns the templates of the kind in the locale, or in the default
+// one, and the locale they are written in.
+func lookup(locale, kind string) (messageTemplates, string, error) {
+	if t, ok := templates[locale+"/"+kind]; ok {
+		return t, locale, nil
+	}
+	locale = models.Locales[0]
+	if t, ok := templates[locale+"/"+kind]; ok {
+		return t, locale, nil
+	}
+	return messageTemplates{}, "", fmt.Errorf("no template for %s messages", kind)
+}
+
+func execute(t messageTemplates, to string, data any) (Message, error) {
+	var subject, text, html strings.Builder
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+func formatDate(locale string, t time.Time) string {
+	if locale == "en" {
+		return t.Format("January 2, 2006")
+	}
+	return t.Format("02.01.2006")
+}
+
+func formatMonth(locale string, t time.Time) string {
+	if locale == "en" {
+		return t.Format("January 2006")
+	}
+	return t.Format("01.2006")
+}
+
+// pluralFunc picks the word form for a count: {{plural .Days "день" "дня" "дней"}}
+// in Russian, {{plural .Days "day" "days"}} in English.
+func pluralFunc(locale string) func(n int, forms ...string) string {
+	return func(n int, forms ...string) string {
+		if len(forms) == 0 {
+			return ""
+		}
+		form := len(forms) - 1
+		switch {
+		case locale == "ru" && len(forms) == 3:
+			switch n10, n100 := n%10, n%100; {
+			case n10 == 1 && n100 != 11:
+				form = 0
+			case n10 >= 2 && n10 <= 4 && (n100 < 12 || n100 > 14):
+				form = 1
+			}
+		case n == 1:
+			form = 0
+		}
+		return forms[form]
+	}
+}
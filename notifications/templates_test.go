@@ -0,0 +1,92 @@
+package notifications
+
+import (
+	"strings"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"testing"
+	"time"
+)
+
+func TestRenderPlurals(t *testing.T) {
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		locale string
+		days   int
+		want   string
+	}{
+		{"ru", 1, "Через 1 день,"},
+		{"ru", 2, "Через 2 дня,"},
+		{"ru", 4, "Через 4 дня,"},
+		{"ru", 5, "Через 5 дней,"},
+		{"ru", 11, "Через 11 дней,"},
+		{"ru", 12, "Через 12 дней,"},
+		{"ru", 14, "Через 14 дней,"},
+		{"ru", 21, "Через 21 день,"},
+		{"ru", 22, "Через 22 дня,"},
+		{"ru", 25, "Через 25 дней,"},
+		{"ru", 111, "Через 111 дней,"},
+		{"ru", 101, "Через 101 день,"},
+		{"en", 1, "In 1 day,"},
+		{"en", 2, "In 2 days,"},
+		{"en", 11, "In 11 days,"},
+		{"en", 21, "In 21 days,"},
+	}
+	for _, tt := range tests {
+		r := dto.Reminder{
+			Kind:        models.ReminderCharge,
+			Email:       "user@example.com",
+			Locale:      tt.locale,
+			ServiceName: "Netflix",
+			Date:        now.Truncate(24*time.Hour).AddDate(0, 0, tt.days),
+			Price:       500,
+			Phase:       "regular",
+		}
+		m, err := Render(r, now)
+		if err != nil {
+			t.Fatalf("Render(%s, %d days): %v", tt.locale, tt.days, err)
+		}
+		if !strings.Contains(m.Text, tt.want) {
+			t.Errorf("Render(%s, %d days) text = %q, want it to contain %q", tt.locale, tt.days, m.Text, tt.want)
+		}
+	}
+}
+
+func TestRenderLocales(t *testing.T) {
+	now := time.Date(2025, 6, 28, 9, 0, 0, 0, time.UTC)
+	r := dto.Reminder{
+		Kind:           models.ReminderTrialEnd,
+		Email:          "user@example.com",
+		ServiceName:    "Netflix <Premium>",
+		SubscriptionID: 7,
+		Date:           time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC),
+		Price:          299,
+		Phase:          "promo",
+	}
+	tests := []struct {
+		locale  string
+		subject string
+		text    string
+	}{
+		{"ru", "30.06.2025", "Через 2 дня"},
+		{"en", "June 30, 2025", "In 2 days"},
+		// Unknown locales fall back to Russian.
+		{"de", "30.06.2025", "Через 2 дня"},
+	}
+	for _, tt := range tests {
+		r.Locale = tt.locale
+		m, err := Render(r, now)
+		if err != nil {
+			t.Fatalf("Render(%s): %v", tt.locale, err)
+		}
+		if m.To != r.Email || !strings.Contains(m.Subject, "Netflix <Premium>") || !strings.Contains(m.Subject, tt.subject) {
+			t.Errorf("Render(%s) = to %q, subject %q", tt.locale, m.To, m.Subject)
+		}
+		if !strings.Contains(m.Text, tt.text) || !strings.Contains(m.Text, "#7") {
+			t.Errorf("Render(%s) text = %q", tt.locale, m.Text)
+		}
+		if !strings.Contains(m.HTML, "Netflix &lt;Premium&gt;") {
+			t.Errorf("Render(%s) does not escape the service name in HTML: %q", tt.locale, m.HTML)
+		}
+	}
+}
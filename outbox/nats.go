@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"testTaskEffectiveMobile/dto"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSPublisher publishes events to NATS on the subject
+// "<SubjectPrefix>.<event type>". The event id is sent in the Nats-Msg-Id
+// header, which JetStream uses to drop the duplicates of at-least-once
+// delivery.
+//
+// Without JetStream an event is accepted once the server has received it;
+// with JetStream once the stream acknowledged it.
+type NATSPublisher struct {
+	URL           string
+	SubjectPrefix string
+	JetStream     bool
+	// Timeout bounds connecting; publishing is bounded by the context.
+	Timeout time.Duration
+
+	mu sync.Mutex
+	nc *nats.Conn
+	js jetstream.JetStream
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, e dto.OutboxEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	nc, js, err := p.conn()
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	msg := nats.NewMsg(p.SubjectPrefix + "." + e.Type)
+	msg.Data = body
+	if p.JetStream {
+		_, err = js.PublishMsg(ctx, msg, jetstream.WithMsgID(strconv.FormatInt(e.Id, 10)))
+		return err
+	}
+	if nc.HeadersSupported() {
+		msg.Header.Set(nats.MsgIdHdr, strconv.FormatInt(e.Id, 10))
+	}
+	if err = nc.PublishMsg(msg); err != nil {
+		return err
+	}
+	// The round trip makes sure the server has the message.
+	return nc.FlushWithContext(ctx)
+}
+
+// conn connects on first use, so that the service starts while NATS is
+// down; the client reconnects by itself afterwards.
+func (p *NATSPublisher) conn() (*nats.Conn, jetstream.JetStream, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.nc != nil {
+		return p.nc, p.js, nil
+	}
+	nc, err := nats.Connect(p.URL,
+		nats.Name("subscriptions-outbox"),
+		nats.Timeout(p.Timeout),
+		nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, nil, err
+	}
+	var js jetstream.JetStream
+	if p.JetStream {
+		if js, err = jetstream.New(nc); err != nil {
+			nc.Close()
+			return nil, nil, err
+		}
+	}
+	p.nc, p.js = nc, js
+	return nc, js, nil
+}
+
+// Close flushes and closes the connection.
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.nc == nil {
+		return nil
+	}
+	err := p.nc.Drain()
+	p.nc, p.js = nil, nil
+	return err
+}
@@ -0,0 +1,190 @@
+// Package outbox relays the domain events recorded in the outbox table to
+// publishers. Delivery is at least once: an event is retried for the
+// publishers that failed, and published again to all of them when the relay
+// stopped before recording the outcome, so consumers drop events whose id
+// they have seen. Events of one aggregate are published in the order they
+// were recorded.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"testTaskEffectiveMobile/dto"
+	"time"
+)
+
+const (
+	// batchSize is how many events are claimed per round.
+	batchSize = 100
+	// claimLease is how long claimed events are left to this relay. Whatever
+	// it has not published by then is claimed again, here or by a replica.
+	claimLease = 2 * time.Minute
+	// Failed events are retried after retryBase, doubled per failure up to retryMax.
+	retryBase = time.Second
+	retryMax  = 5 * time.Minute
+	// cleanupEvery is how often published events past the retention are removed.
+	cleanupEvery = time.Hour
+	// publishTimeout bounds the publishing of one event to one publisher.
+	publishTimeout = 10 * time.Second
+)
+
+// Publisher hands an event to a downstream system. It must return only once
+// the event is safely accepted.
+type Publisher interface {
+	Publish(ctx context.Context, e dto.OutboxEvent) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, e dto.OutboxEvent) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e dto.OutboxEvent) error {
+	return f(ctx, e)
+}
+
+// LogPublisher writes events to the log.
+type LogPublisher struct {
+	Logger *slog.Logger
+}
+
+func (p LogPublisher) Publish(ctx context.Context, e dto.OutboxEvent) error {
+	p.Logger.InfoContext(ctx, "domain event",
+		"event_id", e.Id,
+		"type", e.Type,
+		"aggregate_type", e.AggregateType,
+		"aggregate_id", e.AggregateID)
+	return nil
+}
+
+// Store is the outbox; repositories.OutboxRepository implements it.
+type Store interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]dto.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, publishedTo []string, reason string, retryIn time.Duration) error
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Relay moves events from the outbox to every publisher. An event counts as
+// published only when all publishers accepted it; a retry skips those that
+// already did.
+type Relay struct {
+	Store Store
+	// Publishers are named in errors and logs.
+	Publishers map[string]Publisher
+	// Retention is how long published events are kept.
+	Retention time.Duration
+	Logger    *slog.Logger
+}
+
+// Run relays events every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var cleaned time.Time
+	for {
+		// Keep going while events are published: the next event of an
+		// aggregate only becomes publishable after the previous one.
+		for {
+			n, err := r.RelayDue(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				r.Logger.Error("outbox relay failed", "error", err)
+			}
+			if n == 0 {
+				break
+			}
+		}
+		if time.Since(cleaned) >= cleanupEvery {
+			cleaned = time.Now()
+			if n, err := r.Store.DeletePublished(ctx, cleaned.Add(-r.Retention)); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				r.Logger.Error("outbox cleanup failed", "error", err)
+			} else if n > 0 {
+				r.Logger.Info("outbox cleaned up", "deleted", n)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayDue publishes one batch of events and returns how many were published.
+// The batch is claimed up front and published without holding a transaction
+// open; each outcome is recorded as soon as it is known.
+func (r *Relay) RelayDue(ctx context.Context) (published int, err error) {
+	leaseEnd := time.Now().Add(claimLease)
+	events, err := r.Store.Claim(ctx, batchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+	// perEvent is the longest publishing one event can take.
+	perEvent := time.Duration(len(r.Publishers)) * publishTimeout
+	for _, e := range events {
+		if time.Now().Add(perEvent).After(leaseEnd) {
+			// The rest is claimed again once the lease has run out.
+			break
+		}
+		publishedTo, pubErr := r.publish(ctx, e)
+		if pubErr != nil && ctx.Err() != nil {
+			// Stopped rather than failed; the lease brings the event back.
+			return published, ctx.Err()
+		}
+		// An outcome that is not recorded only means publishing again.
+		recordCtx := context.WithoutCancel(ctx)
+		if pubErr != nil {
+			err = r.Store.MarkFailed(recordCtx, e.Id, publishedTo, pubErr.Error(), retryIn(e.Attempts+1))
+		} else {
+			published++
+			err = r.Store.MarkPublished(recordCtx, e.Id)
+		}
+		if err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
+
+// publish hands the event to the publishers that have not accepted it yet,
+// in name order, and returns all that have accepted it by now.
+func (r *Relay) publish(ctx context.Context, e dto.OutboxEvent) (publishedTo []string, err error) {
+	publishedTo = slices.Clone(e.PublishedTo)
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(r.Publishers)) {
+		if slices.Contains(e.PublishedTo, name) {
+			continue
+		}
+		pctx, cancel := context.WithTimeout(ctx, publishTimeout)
+		err := r.Publishers[name].Publish(pctx, e)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		publishedTo = append(publishedTo, name)
+	}
+	if err = errors.Join(errs...); err != nil {
+		r.Logger.Warn("publishing event failed, will retry",
+			"event_id", e.Id, "type", e.Type, "aggregate_id", e.AggregateID, "failures", e.Attempts+1, "error", err)
+		return publishedTo, err
+	}
+	return publishedTo, nil
+}
+
+// retryIn is the delay after the given number of failures.
+func retryIn(failures int) time.Duration {
+	delay := retryBase
+	for i := 1; i < failures && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
@@ -0,0 +1,237 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testTaskEffectiveMobile/dto"
+	"testing"
+	"time"
+)
+
+// memStore is an outbox in memory that claims like the SQL one: an event is
+// claimable when it is unpublished, not leased and no older event of its
+// aggregate is unpublished. Failed events are due again right away; retry
+// delays are recorded, not waited for.
+type memStore struct {
+	mu     sync.Mutex
+	events []*memEvent
+}
+
+type memEvent struct {
+	dto.OutboxEvent
+	published bool
+	leased    bool
+	retries   []time.Duration
+	reasons   []string
+}
+
+func newMemStore(aggregates ...int64) *memStore {
+	s := &memStore{}
+	for i, aggregate := range aggregates {
+		s.events = append(s.events, &memEvent{OutboxEvent: dto.OutboxEvent{
+			Id: int64(i + 1), Type: "subscription.updated", AggregateType: "subscription", AggregateID: aggregate,
+		}})
+	}
+	return s
+}
+
+func (s *memStore) Claim(_ context.Context, limit int, _ time.Duration) ([]dto.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []dto.OutboxEvent
+	blocked := map[int64]bool{}
+	for _, e := range s.events {
+		if e.published {
+			continue
+		}
+		if !e.leased && !blocked[e.AggregateID] && len(claimed) < limit {
+			e.leased = true
+			claimed = append(claimed, e.OutboxEvent)
+		}
+		blocked[e.AggregateID] = true
+	}
+	return claimed, nil
+}
+
+func (s *memStore) MarkPublished(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.events[id-1]
+	e.published, e.leased = true, false
+	return nil
+}
+
+func (s *memStore) MarkFailed(_ context.Context, id int64, publishedTo []string, reason string, retryIn time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.events[id-1]
+	e.leased = false
+	e.Attempts++
+	e.PublishedTo = publishedTo
+	e.retries = append(e.retries, retryIn)
+	e.reasons = append(e.reasons, reason)
+	return nil
+}
+
+func (s *memStore) DeletePublished(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// journal records every accepted publishing as "<publisher><event id>".
+type journal struct {
+	mu      sync.Mutex
+	entries []string
+	// failures counts down the attempts still to fail per publisher and event.
+	failures map[string]int
+}
+
+func (j *journal) publisher(name string) Publisher {
+	return PublisherFunc(func(_ context.Context, e dto.OutboxEvent) error {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		key := fmt.Sprintf("%s%d", name, e.Id)
+		if j.failures[key] > 0 {
+			j.failures[key]--
+			return errors.New("unavailable")
+		}
+		j.entries = append(j.entries, key)
+		return nil
+	})
+}
+
+func newRelay(store Store, j *journal, names ...string) *Relay {
+	r := &Relay{Store: store, Publishers: map[string]Publisher{}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	for _, name := range names {
+		r.Publishers[name] = j.publisher(name)
+	}
+	return r
+}
+
+// drain relays until every event is published and returns the rounds'
+// counts.
+func drain(t *testing.T, store *memStore, r *Relay) []int {
+	t.Helper()
+	var rounds []int
+	for range 10 {
+		n, err := r.RelayDue(context.Background())
+		if err != nil {
+			t.Fatalf("RelayDue: %v", err)
+		}
+		rounds = append(rounds, n)
+		if !slices.ContainsFunc(store.events, func(e *memEvent) bool { return !e.published }) {
+			return rounds
+		}
+	}
+	t.Fatal("the relay did not settle")
+	return nil
+}
+
+func TestRelayKeepsAggregateOrder(t *testing.T) {
+	// Events 1 and 3 belong to aggregate 10, event 2 to aggregate 20.
+	store := newMemStore(10, 20, 10)
+	j := &journal{}
+	rounds := drain(t, store, newRelay(store, j, "b", "a"))
+
+	// Event 3 waits for event 1 to be published.
+	if want := []int{2, 1}; !slices.Equal(rounds, want) {
+		t.Errorf("published per round = %v, want %v", rounds, want)
+	}
+	// Publishers are called in name order.
+	if want := []string{"a1", "b1", "a2", "b2", "a3", "b3"}; !slices.Equal(j.entries, want) {
+		t.Errorf("publishings = %q, want %q", j.entries, want)
+	}
+}
+
+func TestRelayRetriesOnlyFailedPublishers(t *testing.T) {
+	store := newMemStore(10, 20, 10)
+	j := &journal{failures: map[string]int{"a1": 2}}
+	rounds := drain(t, store, newRelay(store, j, "a", "b"))
+
+	if want := []int{1, 0, 1, 1}; !slices.Equal(rounds, want) {
+		t.Errorf("published per round = %v, want %v", rounds, want)
+	}
+	// b accepted event 1 on the first attempt and never got it again; event
+	// 3 of the same aggregate only went out after event 1.
+	want := []string{"b1", "a2", "b2", "a1", "a3", "b3"}
+	if !slices.Equal(j.entries, want) {
+		t.Errorf("publishings = %q, want %q", j.entries, want)
+	}
+	first := store.events[0]
+	if first.Attempts != 2 || !first.published {
+		t.Errorf("event 1: %d failed attempts, published %v; want 2, true", first.Attempts, first.published)
+	}
+	if !slices.Equal(first.PublishedTo, []string{"b"}) {
+		t.Errorf("event 1 was recorded as published to %q, want b", first.PublishedTo)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !slices.Equal(first.retries, want) {
+		t.Errorf("retries = %v, want %v", first.retries, want)
+	}
+	for _, reason := range first.reasons {
+		if !strings.HasPrefix(reason, "a: ") {
+			t.Errorf("reason %q does not name the failed publisher", reason)
+		}
+	}
+}
+
+func TestRetryIn(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{9, 256 * time.Second},
+		{10, retryMax},
+		{1000, retryMax},
+	}
+	for _, tt := range tests {
+		if got := retryIn(tt.failures); got != tt.want {
+			t.Errorf("retryIn(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRelayLeavesWhatItCannotFinishToTheLease(t *testing.T) {
+	store := newMemStore(10, 20)
+	j := &journal{}
+	// Publishing one event to this many publishers could outlast the lease.
+	var names []string
+	for i := range int(claimLease/publishTimeout) + 1 {
+		names = append(names, fmt.Sprintf("p%02d", i))
+	}
+	n, err := newRelay(store, j, names...).RelayDue(context.Background())
+	if n != 0 || err != nil || len(j.entries) != 0 {
+		t.Errorf("RelayDue = %d, %v with %d publishings; want nothing published", n, err, len(j.entries))
+	}
+	for _, e := range store.events {
+		if e.Attempts != 0 || e.published || !e.leased {
+			t.Errorf("event %d: %d attempts, published %v, leased %v; want it left leased", e.Id, e.Attempts, e.published, e.leased)
+		}
+	}
+}
+
+func TestRelayStopsWithoutRecordingAFailure(t *testing.T) {
+	store := newMemStore(10, 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newRelay(store, &journal{})
+	r.Publishers["slow"] = PublisherFunc(func(ctx context.Context, e dto.OutboxEvent) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	n, err := r.RelayDue(ctx)
+	if n != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("RelayDue = %d, %v; want 0, context.Canceled", n, err)
+	}
+	if e := store.events[0]; e.Attempts != 0 || e.published {
+		t.Errorf("event 1: %d attempts, published %v; want the outcome left to the lease", e.Attempts, e.published)
+	}
+}
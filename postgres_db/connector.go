@@ -1,24 +1,105 @@
 package postgres_db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"log/slog"
+	"math/rand/v2"
+	"testTaskEffectiveMobile/config"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	initialBackoff = 250 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+	// pingTimeout caps a single connection attempt.
+	pingTimeout = 5 * time.Second
 )
 
-func ConnectPostgres(connectionString string) (*sql.DB, func(), error) {
-	var err error
-	conn, err := sql.Open("postgres", connectionString)
+// ConnectPostgres opens the pool described by cfg and waits until postgres
+// accepts connections, retrying with exponential backoff for at most
+// cfg.ConnectTimeout. Errors that retrying cannot fix, such as a wrong
+// password or a missing database, are returned immediately. Retries are
+// logged to logger.
+func ConnectPostgres(ctx context.Context, cfg config.DB, logger *slog.Logger) (*sql.DB, func(), error) {
+	conn, err := sql.Open("postgres", cfg.ConnectionString())
 	if err != nil {
 		return nil, nil, errors.New("could not connect to postgres: " + err.Error())
 	}
-	err = conn.Ping()
-	if err != nil {
-		conn.Close()
-		return nil, nil, errors.New("could not connect to postgres: " + err.Error())
+	conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	conn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err = ping(ctx, conn)
+		if err == nil {
+			break
+		}
+		if permanent(err) {
+			conn.Close()
+			return nil, nil, errors.New("could not connect to postgres: " + err.Error())
+		}
+		// Full jitter keeps replicas restarted together from retrying in lockstep.
+		delay := rand.N(backoff) + time.Millisecond
+		logger.Warn("postgres is not available yet", "attempt", attempt, "retry_in", delay.Round(time.Millisecond), "error", err)
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return nil, nil, errors.New("could not connect to postgres: gave up after " + cfg.ConnectTimeout.String() + ": " + err.Error())
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, maxBackoff)
 	}
-	slog.Info("connected to postgres")
+	logger.Info("connected to postgres")
 	return conn, func() {
 		conn.Close()
 	}, nil
 }
+
+func ping(ctx context.Context, conn *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return conn.PingContext(ctx)
+}
+
+// permanent reports errors the server answered with that will not go away by
+// waiting: bad credentials (class 28) and a missing database (3D000).
+func permanent(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code.Class() == "28" || pqErr.Code == "3D000"
+}
+
+// LogStats logs the connection pool counters every interval until ctx is done.
+func LogStats(ctx context.Context, db *sql.DB, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s := db.Stats()
+		logger.Info("postgres pool stats",
+			"open", s.OpenConnections,
+			"in_use", s.InUse,
+			"idle", s.Idle,
+			"max_open", s.MaxOpenConnections,
+			"wait_count", s.WaitCount,
+			"wait_duration_ms", s.WaitDuration.Milliseconds(),
+			"max_idle_closed", s.MaxIdleClosed,
+			"max_idle_time_closed", s.MaxIdleTimeClosed,
+			"max_lifetime_closed", s.MaxLifetimeClosed,
+		)
+	}
+}
@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	v1 "testTaskEffectiveMobile/postgres_db/migrations/v1"
+	v10 "testTaskEffectiveMobile/postgres_db/migrations/v10"
+	v11 "testTaskEffectiveMobile/postgres_db/migrations/v11"
+	v12 "testTaskEffectiveMobile/postgres_db/migrations/v12"
+	v13 "testTaskEffectiveMobile/postgres_db/migrations/v13"
+	v2 "testTaskEffectiveMobile/postgres_db/migrations/v2"
+	v3 "testTaskEffectiveMobile/postgres_db/migrations/v3"
+	v4 "testTaskEffectiveMobile/postgres_db/migrations/v4"
+	v5 "testTaskEffectiveMobile/postgres_db/migrations/v5"
+	v6 "testTaskEffectiveMobile/postgres_db/migrations/v6"
+	v7 "testTaskEffectiveMobile/postgres_db/migrations/v7"
+	v8 "testTaskEffectiveMobile/postgres_db/migrations/v8"
+	v9 "testTaskEffectiveMobile/postgres_db/migrations/v9"
+)
+
+// advisoryLockKey serializes migrations between replicas starting at the same time.
+const advisoryLockKey = 727_001
+
+type migration interface {
+	Init() error
+}
+
+// steps lists every schema version in order; the version of a step is its index + 1.
+func steps(db *sql.DB) []migration {
+	return []migration{
+		&v1.SubscriptionMigration{Db: db},
+		&v2.SoftDeleteMigration{Db: db},
+		&v3.IndexesMigration{Db: db},
+		&v4.NoOverlapMigration{Db: db},
+		&v5.ServicesMigration{Db: db},
+		&v6.TagsMigration{Db: db},
+		&v7.PhasesMigration{Db: db},
+		&v8.BillingMigration{Db: db},
+		&v9.CalendarTokensMigration{Db: db},
+		&v10.BudgetsMigration{Db: db},
+		&v11.WebhooksMigration{Db: db},
+		&v12.OutboxMigration{Db: db},
+		&v13.NotificationsMigration{Db: db},
+	}
+}
+
+// Latest is the schema version this build expects.
+func Latest() int {
+	return len(steps(nil))
+}
+
+// Apply brings the schema up to Latest, recording every applied version in schema_migrations.
+func Apply(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+
+	stmt := `create table if not exists schema_migrations
+(
+    version    integer                  primary key,
+    applied_at timestamp with time zone not null default now()
+);`
+	if _, err = conn.ExecContext(ctx, stmt); err != nil {
+		return err
+	}
+
+	current, err := Current(ctx, db)
+	if err != nil {
+		return err
+	}
+	for i, m := range steps(db) {
+		version := i + 1
+		if version <= current {
+			continue
+		}
+		if err = m.Init(); err != nil {
+			return fmt.Errorf("migration v%d: %w", version, err)
+		}
+		_, err = conn.ExecContext(ctx, `INSERT INTO schema_migrations(version) VALUES ($1)`, version)
+		if err != nil {
+			return err
+		}
+		slog.Info("schema migrated", "version", version)
+	}
+	return nil
+}
+
+// Current returns the highest applied schema version, 0 for an empty database.
+func Current(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
@@ -0,0 +1,44 @@
+package v10
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// BudgetsMigration adds monthly spending limits and the record of alerts
+// already raised, one row per budget, month and threshold.
+type BudgetsMigration struct {
+	Db *sql.DB
+}
+
+func (bm *BudgetsMigration) Init() error {
+	stmt := `create table if not exists budgets
+(
+    id            serial
+        primary key,
+    user_id       uuid                     not null,
+    monthly_limit integer                  not null
+        constraint budgets_monthly_limit_check check (monthly_limit > 0),
+    category      varchar(64),
+    service_name  varchar(256),
+    created_at    timestamp with time zone not null default now(),
+    constraint budgets_scope_check check (category is null or service_name is null)
+);
+create index if not exists budgets_user_id_idx
+    on budgets (user_id);
+
+create table if not exists budget_alerts
+(
+    budget_id integer                  not null references budgets (id) on delete cascade,
+    month     timestamp with time zone not null,
+    threshold integer                  not null,
+    raised_at timestamp with time zone not null default now(),
+    primary key (budget_id, month, threshold)
+);`
+	_, err := bm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Budgets migration v10 initialized")
+	return nil
+}
@@ -0,0 +1,53 @@
+package v11
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// WebhooksMigration adds webhook registrations and their delivery queue. The
+// queue is filled by the outbox relay from the events recorded with each
+// subscription change (v12), at least once per event and webhook.
+type WebhooksMigration struct {
+	Db *sql.DB
+}
+
+func (wm *WebhooksMigration) Init() error {
+	stmt := `create table if not exists webhooks
+(
+    id         serial
+        primary key,
+    url        text                     not null,
+    secret     text                     not null,
+    events     text[]                   not null,
+    active     boolean                  not null default true,
+    created_at timestamp with time zone not null default now()
+);
+
+create table if not exists webhook_deliveries
+(
+    id              bigserial
+        primary key,
+    webhook_id      integer                  not null references webhooks (id) on delete cascade,
+    event           varchar(64)              not null,
+    payload         jsonb                    not null,
+    status          varchar(16)              not null default 'pending'
+        constraint webhook_deliveries_status_check check (status in ('pending', 'delivered', 'failed')),
+    attempts        integer                  not null default 0,
+    last_status     integer,
+    last_error      text,
+    created_at      timestamp with time zone not null default now(),
+    next_attempt_at timestamp with time zone not null default now(),
+    delivered_at    timestamp with time zone
+);
+create index if not exists webhook_deliveries_due_idx
+    on webhook_deliveries (next_attempt_at) where status = 'pending';
+create index if not exists webhook_deliveries_webhook_id_idx
+    on webhook_deliveries (webhook_id, id);`
+	_, err := wm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Webhooks migration v11 initialized")
+	return nil
+}
@@ -0,0 +1,47 @@
+package v12
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// OutboxMigration adds the outbox of domain events. Subscription changes now
+// record their events there and the relay fans them out to the webhook
+// deliveries, which become idempotent per event. published_to remembers the
+// publishers that accepted an event which has to be retried for others.
+type OutboxMigration struct {
+	Db *sql.DB
+}
+
+func (om *OutboxMigration) Init() error {
+	stmt := `create table if not exists outbox
+(
+    id              bigserial
+        primary key,
+    aggregate_type  varchar(32)              not null,
+    aggregate_id    bigint                   not null,
+    event_type      varchar(64)              not null,
+    payload         jsonb                    not null,
+    created_at      timestamp with time zone not null default now(),
+    published_at    timestamp with time zone,
+    attempts        integer                  not null default 0,
+    last_error      text,
+    next_attempt_at timestamp with time zone not null default now(),
+    published_to    text[]                   not null default '{}'
+);
+create index if not exists outbox_unpublished_idx
+    on outbox (aggregate_type, aggregate_id, id) where published_at is null;
+create index if not exists outbox_published_at_idx
+    on outbox (published_at) where published_at is not null;
+
+alter table webhook_deliveries
+    add column if not exists event_id bigint;
+create unique index if not exists webhook_deliveries_event_idx
+    on webhook_deliveries (webhook_id, event_id);`
+	_, err := om.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Outbox migration v12 initialized")
+	return nil
+}
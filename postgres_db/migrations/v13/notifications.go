@@ -0,0 +1,45 @@
+package v13
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// NotificationsMigration adds the reminder settings of users and the record
+// of reminders already sent, one row per kind, subscription and date.
+type NotificationsMigration struct {
+	Db *sql.DB
+}
+
+func (nm *NotificationsMigration) Init() error {
+	stmt := `create table if not exists notification_settings
+(
+    user_id          uuid                     not null
+        primary key,
+    email            varchar(254)             not null,
+    locale           varchar(8)               not null default 'ru'
+        constraint notification_settings_locale_check check (locale in ('ru', 'en')),
+    days_before      integer                  not null default 3
+        constraint notification_settings_days_before_check check (days_before between 1 and 30),
+    charge_reminders boolean                  not null default true,
+    trial_reminders  boolean                  not null default true,
+    updated_at       timestamp with time zone not null default now()
+);
+
+create table if not exists sent_notifications
+(
+    kind            varchar(16)              not null,
+    subscription_id integer                  not null references subscriptions (id) on delete cascade,
+    due_date        date                     not null,
+    user_id         uuid                     not null,
+    email           varchar(254)             not null,
+    sent_at         timestamp with time zone not null default now(),
+    primary key (kind, subscription_id, due_date)
+);`
+	_, err := nm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Notifications migration v13 initialized")
+	return nil
+}
@@ -0,0 +1,21 @@
+package v2
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+type SoftDeleteMigration struct {
+	Db *sql.DB
+}
+
+func (sd *SoftDeleteMigration) Init() error {
+	stmt := `alter table subscriptions
+    add column if not exists deleted_at timestamp with time zone;`
+	_, err := sd.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Soft delete migration v2 initialized")
+	return nil
+}
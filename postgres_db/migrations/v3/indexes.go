@@ -0,0 +1,23 @@
+package v3
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+type IndexesMigration struct {
+	Db *sql.DB
+}
+
+func (im *IndexesMigration) Init() error {
+	stmt := `create index if not exists subscriptions_user_id_idx
+    on subscriptions (user_id);
+create index if not exists subscriptions_service_period_idx
+    on subscriptions (service_name, start_date, end_date);`
+	_, err := im.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Indexes migration v3 initialized")
+	return nil
+}
@@ -0,0 +1,93 @@
+package v4
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// NoOverlapMigration forbids a user from holding two live subscriptions to the
+// same service for overlapping months. Dates are first days of months and
+// end_date is the last paid month, so closed ranges compare correctly.
+// Service names are compared by service_key(): trimmed, inner whitespace
+// collapsed and lower-cased, so "Netflix", " netflix " and "NETFLIX" are the
+// same service.
+type NoOverlapMigration struct {
+	Db *sql.DB
+}
+
+func (nm *NoOverlapMigration) Init() error {
+	var exists bool
+	err := nm.Db.QueryRow(`select exists(select 1 from pg_constraint where conname = 'subscriptions_no_overlap')`).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err = nm.Db.Exec(serviceKey); err != nil {
+		return err
+	}
+	conflicts, err := nm.overlapping()
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("overlapping subscriptions must be fixed first (e.g. with subsctl update/delete), conflicting id pairs: %s",
+			strings.Join(conflicts, ", "))
+	}
+
+	stmt := `create extension if not exists btree_gist;
+alter table subscriptions
+    add constraint subscriptions_no_overlap
+        exclude using gist (
+            user_id with =,
+            service_key(service_name) with =,
+            tstzrange(start_date, end_date, '[]') with &&
+        ) where (deleted_at is null);`
+	_, err = nm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("No overlap migration v4 initialized")
+	return nil
+}
+
+// serviceKey defines service_key(), the comparison form of a service name.
+const serviceKey = `create or replace function service_key(name text) returns text
+    language sql
+    immutable
+    parallel safe
+as
+$$
+select lower(regexp_replace(btrim(name), '\s+', ' ', 'g'))
+$$;`
+
+// overlapping lists up to 20 pairs of rows that would violate the constraint.
+func (nm *NoOverlapMigration) overlapping() ([]string, error) {
+	stmt := `select a.id, b.id
+from subscriptions a
+         join subscriptions b
+              on a.user_id = b.user_id
+                  and service_key(a.service_name) = service_key(b.service_name)
+                  and a.id < b.id
+                  and tstzrange(a.start_date, a.end_date, '[]') && tstzrange(b.start_date, b.end_date, '[]')
+where a.deleted_at is null
+  and b.deleted_at is null
+order by a.id, b.id
+limit 20`
+	rows, err := nm.Db.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs []string
+	for rows.Next() {
+		var a, b int
+		if err = rows.Scan(&a, &b); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, fmt.Sprintf("%d/%d", a, b))
+	}
+	return pairs, rows.Err()
+}
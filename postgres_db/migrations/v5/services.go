@@ -0,0 +1,40 @@
+package v5
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// ServicesMigration adds the service catalogue. Names are unique by
+// service_key() (v4), so "Netflix", " netflix " and "NETFLIX" are the same
+// service.
+type ServicesMigration struct {
+	Db *sql.DB
+}
+
+func (sm *ServicesMigration) Init() error {
+	stmt := `create table if not exists services
+(
+    id            serial
+        primary key,
+    name          varchar(256)             not null,
+    aliases       text[]                   not null default '{}',
+    category      varchar(64),
+    default_price integer,
+    website       varchar(512),
+    created_at    timestamp with time zone not null default now()
+);
+create unique index if not exists services_name_key
+    on services (service_key(name));
+
+alter table subscriptions
+    add column if not exists service_id integer references services (id) on delete set null;
+create index if not exists subscriptions_service_id_idx
+    on subscriptions (service_id);`
+	_, err := sm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Services migration v5 initialized")
+	return nil
+}
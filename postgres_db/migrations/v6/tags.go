@@ -0,0 +1,32 @@
+package v6
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// TagsMigration adds a per-subscription category that overrides the one from
+// the service catalogue, and free-form tags stored one row per tag.
+type TagsMigration struct {
+	Db *sql.DB
+}
+
+func (tm *TagsMigration) Init() error {
+	stmt := `alter table subscriptions
+    add column if not exists category varchar(64);
+
+create table if not exists subscription_tags
+(
+    subscription_id integer     not null references subscriptions (id) on delete cascade,
+    tag             varchar(64) not null,
+    primary key (subscription_id, tag)
+);
+create index if not exists subscription_tags_tag_idx
+    on subscription_tags (tag);`
+	_, err := tm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Tags migration v6 initialized")
+	return nil
+}
@@ -0,0 +1,33 @@
+package v7
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// PhasesMigration adds the optional trial and promo phases that precede the
+// regular price. Zero months means no such phase.
+type PhasesMigration struct {
+	Db *sql.DB
+}
+
+func (pm *PhasesMigration) Init() error {
+	stmt := `alter table subscriptions
+    add column if not exists trial_months integer not null default 0
+        constraint subscriptions_trial_months_check check (trial_months >= 0),
+    add column if not exists trial_price  integer not null default 0
+        constraint subscriptions_trial_price_check check (trial_price >= 0),
+    add column if not exists promo_months integer not null default 0
+        constraint subscriptions_promo_months_check check (promo_months >= 0),
+    add column if not exists promo_price  integer not null default 0
+        constraint subscriptions_promo_price_check check (promo_price >= 0);
+create index if not exists subscriptions_trial_start_idx
+    on subscriptions (start_date)
+    where trial_months > 0 and deleted_at is null;`
+	_, err := pm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Phases migration v7 initialized")
+	return nil
+}
@@ -0,0 +1,31 @@
+package v8
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// BillingMigration adds the billing period of the regular price and the
+// price changes scheduled for later months.
+type BillingMigration struct {
+	Db *sql.DB
+}
+
+func (bm *BillingMigration) Init() error {
+	stmt := `alter table subscriptions
+    add column if not exists billing_period varchar(16) not null default 'monthly'
+        constraint subscriptions_billing_period_check check (billing_period in ('monthly', 'quarterly', 'yearly'));
+create table if not exists subscription_price_changes
+(
+    subscription_id integer                  not null references subscriptions (id) on delete cascade,
+    month           timestamp with time zone not null,
+    price           integer                  not null check (price >= 0),
+    primary key (subscription_id, month)
+);`
+	_, err := bm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Billing migration v8 initialized")
+	return nil
+}
@@ -0,0 +1,28 @@
+package v9
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// CalendarTokensMigration stores the secret token of each user's calendar
+// feed. Only its SHA-256 hash is kept, so a database dump does not expose
+// the feed URLs.
+type CalendarTokensMigration struct {
+	Db *sql.DB
+}
+
+func (cm *CalendarTokensMigration) Init() error {
+	stmt := `create table if not exists calendar_tokens
+(
+    user_id    uuid                     primary key,
+    token_hash bytea                    not null unique,
+    created_at timestamp with time zone not null default now()
+);`
+	_, err := cm.Db.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	slog.Info("Calendar tokens migration v9 initialized")
+	return nil
+}
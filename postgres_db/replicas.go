@@ -0,0 +1,130 @@
+package postgres_db
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testTaskEffectiveMobile/config"
+	"time"
+)
+
+// ReplicaSet routes read queries to healthy replicas in round-robin order and
+// falls back to the primary when none is healthy.
+type ReplicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+	logger   *slog.Logger
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// OpenReplicas opens a pool per replica DSN with the primary's pool settings.
+// Replicas start unhealthy and join the rotation after their first successful
+// check, so an unreachable replica never delays startup.
+func OpenReplicas(primary *sql.DB, cfg config.DB, logger *slog.Logger) (*ReplicaSet, error) {
+	rs := &ReplicaSet{primary: primary, logger: logger}
+	for i, dsn := range cfg.ReplicaDSNs {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			rs.Close()
+			return nil, err
+		}
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+		rs.replicas = append(rs.replicas, &replica{name: "replica-" + strconv.Itoa(i+1), db: db})
+	}
+	return rs, nil
+}
+
+// Reader returns the pool to run a read-only query on.
+func (rs *ReplicaSet) Reader() *sql.DB {
+	n := len(rs.replicas)
+	start := rs.next.Add(1)
+	for i := range n {
+		r := rs.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return rs.primary
+}
+
+// Run checks every replica right away and then every interval until ctx is done.
+func (rs *ReplicaSet) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		rs.check(ctx, interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rs *ReplicaSet) check(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, r := range rs.replicas {
+		wg.Go(func() {
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			err := r.db.PingContext(pingCtx)
+			if err != nil && ctx.Err() != nil {
+				// Shutting down, not a replica failure.
+				return
+			}
+			healthy := err == nil
+			if r.healthy.Swap(healthy) != healthy {
+				if healthy {
+					rs.logger.Info("replica is healthy, routing reads to it", "replica", r.name)
+				} else {
+					rs.logger.Warn("replica is unhealthy, reads fail over", "replica", r.name, "error", err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// Healthy returns the number of replicas currently serving reads.
+func (rs *ReplicaSet) Healthy() int {
+	n := 0
+	for _, r := range rs.replicas {
+		if r.healthy.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+func (rs *ReplicaSet) Close() error {
+	for _, r := range rs.replicas {
+		r.db.Close()
+	}
+	return nil
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so that reads made with it go to the primary, e.g. to
+// read a row right after writing it.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// PrimaryRequested reports whether ctx was marked by WithPrimary.
+func PrimaryRequested(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+
+	"github.com/google/uuid"
+)
+
+type BudgetsRepository struct {
+	Db *sql.DB
+}
+
+const budgetColumns = `id, user_id, monthly_limit, category, service_name`
+
+func scanBudget(row scanner) (dto.BudgetDTO, error) {
+	var b dto.BudgetDTO
+	err := row.Scan(&b.Id, &b.UserId, &b.MonthlyLimit, &b.Category, &b.ServiceName)
+	return b, err
+}
+
+// List returns the budgets of a user, or of every user when userID is nil.
+func (br *BudgetsRepository) List(ctx context.Context, userID *uuid.UUID) (budgets []dto.BudgetDTO, err error) {
+	ctx, span := startSpan(ctx, "budgets.List")
+	defer func() { endSpan(span, int64(len(budgets)), err) }()
+
+	query := `SELECT ` + budgetColumns + ` FROM budgets`
+	var args []any
+	if userID != nil {
+		query += ` WHERE user_id = $1`
+		args = append(args, *userID)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := br.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets = []dto.BudgetDTO{}
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (br *BudgetsRepository) GetByID(ctx context.Context, id int) (b dto.BudgetDTO, err error) {
+	ctx, span := startSpan(ctx, "budgets.GetByID")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	b, err = scanBudget(br.Db.QueryRowContext(ctx, `SELECT `+budgetColumns+` FROM budgets WHERE id = $1`, id))
+	if err != nil {
+		return dto.BudgetDTO{}, err
+	}
+	return b, nil
+}
+
+func (br *BudgetsRepository) Insert(ctx context.Context, b models.Budget) (id int, err error) {
+	ctx, span := startSpan(ctx, "budgets.Insert")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	stmt := `INSERT INTO budgets(user_id, monthly_limit, category, service_name)
+    VALUES ($1, $2, $3, $4)
+    RETURNING id`
+	err = br.Db.QueryRowContext(ctx, stmt, b.UserId, b.MonthlyLimit, cleanOptional(b.Category), cleanOptional(b.ServiceName)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update replaces the budget. Alerts already raised this month stay raised,
+// so raising the limit does not repeat them.
+func (br *BudgetsRepository) Update(ctx context.Context, id int, b models.Budget) (err error) {
+	ctx, span := startSpan(ctx, "budgets.Update")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	stmt := `update budgets
+				set user_id = $2,
+					monthly_limit = $3,
+					category = $4,
+					service_name = $5
+				where id = $1`
+	result, err := br.Db.ExecContext(ctx, stmt, id, b.UserId, b.MonthlyLimit, cleanOptional(b.Category), cleanOptional(b.ServiceName))
+	if err != nil {
+		return err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (br *BudgetsRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "budgets.Delete")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	result, err := br.Db.ExecContext(ctx, `DELETE FROM budgets WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RecordAlert marks the threshold of the budget as raised for month and
+// reports whether it was not raised before.
+func (br *BudgetsRepository) RecordAlert(ctx context.Context, budgetID int, month models.MonthYearDate, threshold int) (recorded bool, err error) {
+	ctx, span := startSpan(ctx, "budgets.RecordAlert")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	stmt := `INSERT INTO budget_alerts(budget_id, month, threshold) VALUES ($1, $2, $3)
+			 ON CONFLICT DO NOTHING`
+	result, err := br.Db.ExecContext(ctx, stmt, budgetID, month, threshold)
+	if err != nil {
+		return false, err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ForgetAlert undoes RecordAlert, so that an alert that could not be
+// delivered is raised again on the next evaluation.
+func (br *BudgetsRepository) ForgetAlert(ctx context.Context, budgetID int, month models.MonthYearDate, threshold int) (err error) {
+	ctx, span := startSpan(ctx, "budgets.ForgetAlert")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	_, err = br.Db.ExecContext(ctx, `DELETE FROM budget_alerts WHERE budget_id = $1 AND month = $2 AND threshold = $3`,
+		budgetID, month, threshold)
+	return err
+}
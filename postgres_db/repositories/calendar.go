@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// CalendarRepository keeps the secret tokens of the per-user calendar feeds.
+type CalendarRepository struct {
+	Db *sql.DB
+}
+
+// IssueToken gives the user a new random feed token and returns it. A
+// previous token stops working.
+func (cr *CalendarRepository) IssueToken(ctx context.Context, userID uuid.UUID) (token string, err error) {
+	ctx, span := startSpan(ctx, "calendar.IssueToken")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	// 128 random bits in base32, safe to put in a URL as is.
+	token = rand.Text()
+	stmt := `INSERT INTO calendar_tokens(user_id, token_hash) VALUES ($1, $2)
+			 ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = now()`
+	if _, err = cr.Db.ExecContext(ctx, stmt, userID, hashToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeToken disables the user's feed; sql.ErrNoRows means there was none.
+func (cr *CalendarRepository) RevokeToken(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "calendar.RevokeToken")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	result, err := cr.Db.ExecContext(ctx, `DELETE FROM calendar_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UserByToken returns the owner of a feed token, or sql.ErrNoRows.
+func (cr *CalendarRepository) UserByToken(ctx context.Context, token string) (userID uuid.UUID, err error) {
+	ctx, span := startSpan(ctx, "calendar.UserByToken")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	err = cr.Db.QueryRowContext(ctx, `SELECT user_id FROM calendar_tokens WHERE token_hash = $1`, hashToken(token)).Scan(&userID)
+	return userID, err
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testTaskEffectiveMobile/models"
+
+	"github.com/lib/pq"
+)
+
+const exclusionViolation = "23P01"
+
+// OverlapError is returned when a write would give a user two subscriptions to
+// the same service for overlapping months.
+type OverlapError struct {
+	// ConflictingID is the existing subscription, 0 if it could not be found
+	// (e.g. it was inserted by the same batch); responses then leave it out.
+	ConflictingID int
+}
+
+func (e *OverlapError) Error() string {
+	if e.ConflictingID == 0 {
+		return "subscription overlaps another subscription to the same service"
+	}
+	return fmt.Sprintf("subscription overlaps subscription %d to the same service", e.ConflictingID)
+}
+
+// overlapError turns an exclusion violation caused by writing s into an
+// *OverlapError naming the conflicting row; other errors are returned as is.
+// selfID is the id of the updated row, 0 for inserts.
+func (sr *SubscriptionsRepository) overlapError(ctx context.Context, err error, s models.Subscription, selfID int) error {
+	return asOverlap(err, func() (id int, err error) {
+		stmt := `SELECT id
+			 FROM subscriptions
+			 WHERE user_id = $1
+			 AND service_key(service_name) = service_key($2)
+			 AND id <> $3
+			 AND deleted_at IS NULL
+			 AND tstzrange(start_date, end_date, '[]') && tstzrange($4, $5, '[]')
+			 ORDER BY id
+			 LIMIT 1`
+		err = sr.Db.QueryRowContext(ctx, stmt, s.UserId, s.ServiceName, selfID, s.StartDate, s.EndDate).Scan(&id)
+		return id, err
+	})
+}
+
+// asOverlap turns an exclusion violation into an *OverlapError naming the
+// subscription found by lookup; other errors are returned as is. A failed
+// lookup still leaves a meaningful error for the client, without the id.
+func asOverlap(err error, lookup func() (int, error)) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != exclusionViolation {
+		return err
+	}
+	overlap := &OverlapError{}
+	if id, lookupErr := lookup(); lookupErr == nil {
+		overlap.ConflictingID = id
+	}
+	return overlap
+}
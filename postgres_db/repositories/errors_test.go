@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestAsOverlap(t *testing.T) {
+	exclusion := &pq.Error{Code: exclusionViolation, Constraint: "subscriptions_no_overlap"}
+	other := errors.New("connection reset")
+	tests := []struct {
+		name      string
+		err       error
+		lookupID  int
+		lookupErr error
+		wantID    int
+		wantAs    bool
+	}{
+		{"exclusion violation", exclusion, 42, nil, 42, true},
+		{"wrapped violation", fmt.Errorf("rename: %w", exclusion), 7, nil, 7, true},
+		{"conflicting row not found", exclusion, 0, errors.New("sql: no rows in result set"), 0, true},
+		{"unique violation", &pq.Error{Code: "23505"}, 1, nil, 0, false},
+		{"other error", other, 1, nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			looked := false
+			err := asOverlap(tt.err, func() (int, error) {
+				looked = true
+				return tt.lookupID, tt.lookupErr
+			})
+			var overlap *OverlapError
+			if errors.As(err, &overlap) != tt.wantAs {
+				t.Fatalf("asOverlap(%v) = %v, want an OverlapError: %v", tt.err, err, tt.wantAs)
+			}
+			if !tt.wantAs {
+				if err != tt.err || looked {
+					t.Errorf("asOverlap(%v) = %v, looked up %v; want the error unchanged and no lookup", tt.err, err, looked)
+				}
+				return
+			}
+			if overlap.ConflictingID != tt.wantID {
+				t.Errorf("ConflictingID = %d, want %d", overlap.ConflictingID, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+
+	"github.com/google/uuid"
+)
+
+// NotificationsRepository keeps the reminder settings of users and the
+// record of reminders already sent.
+type NotificationsRepository struct {
+	Db *sql.DB
+}
+
+const notificationSettingsColumns = `user_id, email, locale, days_before, charge_reminders, trial_reminders`
+
+func scanNotificationSettings(row scanner) (dto.NotificationSettingsDTO, error) {
+	var s dto.NotificationSettingsDTO
+	var charge, trial bool
+	err := row.Scan(&s.UserId, &s.Email, &s.Locale, &s.DaysBefore, &charge, &trial)
+	s.ChargeReminders, s.TrialReminders = &charge, &trial
+	return s, err
+}
+
+// List returns the settings of every user.
+func (nr *NotificationsRepository) List(ctx context.Context) (settings []dto.NotificationSettingsDTO, err error) {
+	ctx, span := startSpan(ctx, "notifications.List")
+	defer func() { endSpan(span, int64(len(settings)), err) }()
+
+	rows, err := nr.Db.QueryContext(ctx, `SELECT `+notificationSettingsColumns+` FROM notification_settings ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanNotificationSettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Get returns the settings of a user, or sql.ErrNoRows.
+func (nr *NotificationsRepository) Get(ctx context.Context, userID uuid.UUID) (s dto.NotificationSettingsDTO, err error) {
+	ctx, span := startSpan(ctx, "notifications.Get")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	s, err = scanNotificationSettings(nr.Db.QueryRowContext(ctx,
+		`SELECT `+notificationSettingsColumns+` FROM notification_settings WHERE user_id = $1`, userID))
+	if err != nil {
+		return dto.NotificationSettingsDTO{}, err
+	}
+	return s, nil
+}
+
+// Put creates or replaces the settings of a user; omitted switches are on.
+func (nr *NotificationsRepository) Put(ctx context.Context, userID uuid.UUID, s models.NotificationSettings) (err error) {
+	ctx, span := startSpan(ctx, "notifications.Put")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	stmt := `INSERT INTO notification_settings(user_id, email, locale, days_before, charge_reminders, trial_reminders)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 ON CONFLICT (user_id) DO UPDATE
+			 SET email = excluded.email,
+			     locale = excluded.locale,
+			     days_before = excluded.days_before,
+			     charge_reminders = excluded.charge_reminders,
+			     trial_reminders = excluded.trial_reminders,
+			     updated_at = now()`
+	_, err = nr.Db.ExecContext(ctx, stmt, userID, s.Email, s.Locale, s.DaysBefore,
+		s.ChargeReminders == nil || *s.ChargeReminders, s.TrialReminders == nil || *s.TrialReminders)
+	return err
+}
+
+// Delete stops all reminders of a user; sql.ErrNoRows means there were no
+// settings. Sent reminders stay recorded.
+func (nr *NotificationsRepository) Delete(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "notifications.Delete")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	result, err := nr.Db.ExecContext(ctx, `DELETE FROM notification_settings WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RecordSent marks the reminder as sent and reports whether it was not
+// sent before.
+func (nr *NotificationsRepository) RecordSent(ctx context.Context, r dto.Reminder) (recorded bool, err error) {
+	ctx, span := startSpan(ctx, "notifications.RecordSent")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	stmt := `INSERT INTO sent_notifications(kind, subscription_id, due_date, user_id, email) VALUES ($1, $2, $3, $4, $5)
+			 ON CONFLICT DO NOTHING`
+	result, err := nr.Db.ExecContext(ctx, stmt, r.Kind, r.SubscriptionID, r.Date, r.UserID, r.Email)
+	if err != nil {
+		return false, err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ForgetSent undoes RecordSent, so that a reminder that could not be sent
+// is sent on the next run.
+func (nr *NotificationsRepository) ForgetSent(ctx context.Context, r dto.Reminder) (err error) {
+	ctx, span := startSpan(ctx, "notifications.ForgetSent")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	_, err = nr.Db.ExecContext(ctx, `DELETE FROM sent_notifications WHERE kind = $1 AND subscription_id = $2 AND due_date = $3`,
+		r.Kind, r.SubscriptionID, r.Date)
+	return err
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"testTaskEffectiveMobile/dto"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// aggregateSubscription is the aggregate type of subscription events.
+const aggregateSubscription = "subscription"
+
+// EventsChannel is the notification channel that carries the id of every
+// recorded subscription event once its transaction commits.
+const EventsChannel = "subscription_events"
+
+const outboxColumns = `id, event_type, aggregate_type, aggregate_id, created_at, payload, attempts, published_to`
+
+func scanOutboxEvent(row scanner) (dto.OutboxEvent, error) {
+	var e dto.OutboxEvent
+	err := row.Scan(&e.Id, &e.Type, &e.AggregateType, &e.AggregateID, &e.CreatedAt, (*[]byte)(&e.Data), &e.Attempts, pq.Array(&e.PublishedTo))
+	return e, err
+}
+
+// OutboxRepository reads the outbox for the relay. Events are written by the
+// repositories of the aggregates, in the transaction of the change.
+type OutboxRepository struct {
+	Db *sql.DB
+}
+
+// Claim leases up to limit publishable events to the caller and returns them
+// in id order. An event is publishable when it is due and no older event of
+// its aggregate is still unpublished, which keeps the events of an aggregate
+// in order. A claimed event is not due again until the lease has run out, so
+// replicas do not claim each other's events and one whose outcome was never
+// recorded is claimed again later. Only the claim itself holds row locks.
+func (ob *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) (events []dto.OutboxEvent, err error) {
+	ctx, span := startSpan(ctx, "outbox.Claim")
+	defer func() { endSpan(span, int64(len(events)), err) }()
+
+	query := `UPDATE outbox SET next_attempt_at = now() + make_interval(secs => $2)
+			 WHERE id IN (SELECT id FROM outbox o
+			              WHERE published_at IS NULL
+			              AND next_attempt_at <= now()
+			              AND NOT EXISTS (SELECT 1 FROM outbox p
+			                              WHERE p.published_at IS NULL
+			                              AND p.aggregate_type = o.aggregate_type
+			                              AND p.aggregate_id = o.aggregate_id
+			                              AND p.id < o.id)
+			              ORDER BY id
+			              LIMIT $1
+			              FOR UPDATE SKIP LOCKED)
+			 RETURNING ` + outboxColumns
+	events, err = ob.query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(events, func(a, b dto.OutboxEvent) int { return cmp.Compare(a.Id, b.Id) })
+	return events, nil
+}
+
+// MarkPublished records that every publisher accepted the event.
+func (ob *OutboxRepository) MarkPublished(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "outbox.MarkPublished")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	_, err = ob.Db.ExecContext(ctx, `UPDATE outbox SET published_at = now(), last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+// MarkFailed records a failed publishing of the event, which becomes due
+// again after retryIn and holds back the later events of its aggregate until
+// then. publishedTo names the publishers that accepted it so far.
+func (ob *OutboxRepository) MarkFailed(ctx context.Context, id int64, publishedTo []string, reason string, retryIn time.Duration) (err error) {
+	ctx, span := startSpan(ctx, "outbox.MarkFailed")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	stmt := `UPDATE outbox
+			 SET attempts = attempts + 1, last_error = $2, next_attempt_at = now() + make_interval(secs => $3), published_to = $4
+			 WHERE id = $1 AND published_at IS NULL`
+	_, err = ob.Db.ExecContext(ctx, stmt, id, reason, retryIn.Seconds(), pq.Array(publishedTo))
+	return err
+}
+
+// DeletePublished removes the events published before the cutoff and returns how many were removed.
+func (ob *OutboxRepository) DeletePublished(ctx context.Context, before time.Time) (affected int64, err error) {
+	ctx, span := startSpan(ctx, "outbox.DeletePublished")
+	defer func() { endSpan(span, affected, err) }()
+
+	result, err := ob.Db.ExecContext(ctx, `DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ByIDs returns the events with the given ids in id order, published or not.
+func (ob *OutboxRepository) ByIDs(ctx context.Context, ids []int64) (events []dto.OutboxEvent, err error) {
+	ctx, span := startSpan(ctx, "outbox.ByIDs")
+	defer func() { endSpan(span, int64(len(events)), err) }()
+
+	return ob.query(ctx, `SELECT `+outboxColumns+` FROM outbox WHERE id = ANY($1) ORDER BY id`, pq.Array(ids))
+}
+
+// Since returns up to limit events recorded at or after since with an id
+// above afterID, in id order.
+func (ob *OutboxRepository) Since(ctx context.Context, since time.Time, afterID int64, limit int) (events []dto.OutboxEvent, err error) {
+	ctx, span := startSpan(ctx, "outbox.Since")
+	defer func() { endSpan(span, int64(len(events)), err) }()
+
+	return ob.query(ctx, `SELECT `+outboxColumns+` FROM outbox WHERE created_at >= $1 AND id > $2 ORDER BY id LIMIT $3`,
+		since, afterID, limit)
+}
+
+// LastID returns the id of the newest event, 0 when there is none.
+func (ob *OutboxRepository) LastID(ctx context.Context) (id int64, err error) {
+	ctx, span := startSpan(ctx, "outbox.LastID")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	err = ob.Db.QueryRowContext(ctx, `SELECT COALESCE(max(id), 0) FROM outbox`).Scan(&id)
+	return id, err
+}
+
+func (ob *OutboxRepository) query(ctx context.Context, query string, args ...any) ([]dto.OutboxEvent, error) {
+	rows, err := ob.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []dto.OutboxEvent
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// recordSubscriptionEvents adds the events to the outbox with the
+// subscription as it is now in tx as payload, and notifies EventsChannel of
+// them on commit.
+func recordSubscriptionEvents(ctx context.Context, tx *sql.Tx, id int, events ...string) error {
+	s, err := scanSubscription(tx.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = $1`, id))
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(&s)
+	if err != nil {
+		return err
+	}
+	stmt := `WITH e AS (
+				INSERT INTO outbox(aggregate_type, aggregate_id, event_type, payload) VALUES ($1, $2, $3, $4) RETURNING id
+			 )
+			 SELECT pg_notify($5, id::text) FROM e`
+	for _, event := range events {
+		// Sent as text: lib/pq would encode []byte as bytea.
+		if _, err = tx.ExecContext(ctx, stmt, aggregateSubscription, id, event, string(payload), EventsChannel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testTaskEffectiveMobile/models"
+)
+
+// priceChangesColumn selects the price changes of a subscriptions row as a
+// JSON array, NULL without any, for priceChanges to scan.
+const priceChangesColumn = `(SELECT json_agg(json_build_object(
+		'month', to_char(c.month AT TIME ZONE 'UTC', 'MM-YYYY'), 'price', c.price) ORDER BY c.month)
+	FROM subscription_price_changes c WHERE c.subscription_id = subscriptions.id)`
+
+// priceChanges scans priceChangesColumn.
+type priceChanges []models.PriceChange
+
+func (pc *priceChanges) Scan(value any) error {
+	*pc = nil
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]models.PriceChange)(pc))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]models.PriceChange)(pc))
+	default:
+		return fmt.Errorf("incompatible type %T for price changes", value)
+	}
+}
+
+// setPriceChanges replaces the price changes of the subscription.
+func setPriceChanges(ctx context.Context, e execer, id int, changes []models.PriceChange) error {
+	if _, err := e.ExecContext(ctx, `DELETE FROM subscription_price_changes WHERE subscription_id = $1`, id); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		_, err := e.ExecContext(ctx, `INSERT INTO subscription_price_changes(subscription_id, month, price) VALUES ($1, $2, $3)`,
+			id, c.Month, c.Price)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// billingPeriod is the stored billing period, monthly when none is given.
+func billingPeriod(s models.Subscription) string {
+	if s.BillingPeriod == "" {
+		return models.BillingMonthly
+	}
+	return s.BillingPeriod
+}
@@ -0,0 +1,436 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrUnknownService is returned for a subscription referencing a service_id
+// that is not in the catalogue.
+var ErrUnknownService = errors.New("unknown service_id")
+
+// DuplicateServiceError is returned when a service name or alias already
+// names another catalogue entry.
+type DuplicateServiceError struct {
+	ExistingID int
+}
+
+func (e *DuplicateServiceError) Error() string {
+	return "service name or alias is already used by another service"
+}
+
+// serviceLookup finds the catalogue entry for $1 by name or alias, preferring
+// an exact name match.
+const serviceLookup = `SELECT id, name
+			 FROM services
+			 WHERE service_key(name) = service_key($1)
+			 OR EXISTS (SELECT 1 FROM unnest(aliases) a WHERE service_key(a) = service_key($1))
+			 ORDER BY service_key(name) = service_key($1) DESC, id
+			 LIMIT 1`
+
+type ServicesRepository struct {
+	Db *sql.DB
+}
+
+func (sr *ServicesRepository) List(ctx context.Context) (services []dto.ServiceDTO, err error) {
+	ctx, span := startSpan(ctx, "services.List")
+	defer func() { endSpan(span, int64(len(services)), err) }()
+
+	rows, err := sr.Db.QueryContext(ctx, `SELECT `+serviceColumns+` FROM services ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	services = []dto.ServiceDTO{}
+	for rows.Next() {
+		s, err := scanService(rows)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, s)
+	}
+	return services, rows.Err()
+}
+
+func (sr *ServicesRepository) GetByID(ctx context.Context, id int) (s dto.ServiceDTO, err error) {
+	ctx, span := startSpan(ctx, "services.GetByID")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	s, err = scanService(sr.Db.QueryRowContext(ctx, `SELECT `+serviceColumns+` FROM services WHERE id = $1`, id))
+	if err != nil {
+		return dto.ServiceDTO{}, err
+	}
+	return s, nil
+}
+
+// Resolve finds the catalogue entry a free-text service name refers to.
+func (sr *ServicesRepository) Resolve(ctx context.Context, name string) (s dto.ServiceDTO, err error) {
+	ctx, span := startSpan(ctx, "services.Resolve")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	var id int
+	if err = sr.Db.QueryRowContext(ctx, serviceLookup, name).Scan(&id, new(string)); err != nil {
+		return dto.ServiceDTO{}, err
+	}
+	return sr.GetByID(ctx, id)
+}
+
+func (sr *ServicesRepository) Insert(ctx context.Context, s models.Service) (id int, err error) {
+	ctx, span := startSpan(ctx, "services.Insert")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	s.Aliases = cleanAliases(s.Name, s.Aliases)
+	if err = sr.checkDuplicate(ctx, s, 0); err != nil {
+		return 0, err
+	}
+	stmt := `INSERT INTO services(name, aliases, category, default_price, website)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id`
+	err = sr.Db.QueryRowContext(ctx, stmt, strings.TrimSpace(s.Name), pq.Array(s.Aliases), s.Category, s.DefaultPrice, s.Website).Scan(&id)
+	if err != nil {
+		return 0, duplicateError(err)
+	}
+	return id, nil
+}
+
+// Update replaces the catalogue entry and renames the live subscriptions
+// linked to it, recording an update event for each of them.
+func (sr *ServicesRepository) Update(ctx context.Context, id int, s models.Service) (err error) {
+	ctx, span := startSpan(ctx, "services.Update")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	s.Aliases = cleanAliases(s.Name, s.Aliases)
+	if err = sr.checkDuplicate(ctx, s, id); err != nil {
+		return err
+	}
+	tx, err := sr.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE services
+				SET name = $2,
+					aliases = $3,
+					category = $4,
+					default_price = $5,
+					website = $6
+				WHERE id = $1`
+	result, err := tx.ExecContext(ctx, stmt, id, strings.TrimSpace(s.Name), pq.Array(s.Aliases), s.Category, s.DefaultPrice, s.Website)
+	if err != nil {
+		return duplicateError(err)
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	renamed, err := renameSubscriptions(ctx, tx, id, strings.TrimSpace(s.Name))
+	if err != nil {
+		return sr.renameOverlapError(ctx, err, id, strings.TrimSpace(s.Name))
+	}
+	for _, subscriptionID := range renamed {
+		if err = recordSubscriptionEvents(ctx, tx, subscriptionID, models.EventSubscriptionUpdated); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// renameSubscriptions gives the live subscriptions of the service its new
+// name and returns the ids of those that changed.
+func renameSubscriptions(ctx context.Context, tx *sql.Tx, serviceID int, name string) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `UPDATE subscriptions SET service_name = $2
+			 WHERE service_id = $1 AND deleted_at IS NULL AND service_name <> $2
+			 RETURNING id`, serviceID, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// renameOverlapError maps an exclusion violation of renaming the service's
+// subscriptions to name into an *OverlapError naming a live subscription of
+// the same user the rename would collide with.
+func (sr *ServicesRepository) renameOverlapError(ctx context.Context, err error, serviceID int, name string) error {
+	return asOverlap(err, func() (id int, err error) {
+		stmt := `SELECT other.id
+			 FROM subscriptions renamed
+			 JOIN subscriptions other
+			   ON other.user_id = renamed.user_id
+			   AND other.id <> renamed.id
+			   AND other.deleted_at IS NULL
+			   AND service_key(other.service_name) = service_key($2)
+			   AND tstzrange(other.start_date, other.end_date, '[]') && tstzrange(renamed.start_date, renamed.end_date, '[]')
+			 WHERE renamed.service_id = $1
+			 AND renamed.deleted_at IS NULL
+			 ORDER BY other.id
+			 LIMIT 1`
+		err = sr.Db.QueryRowContext(ctx, stmt, serviceID, name).Scan(&id)
+		return id, err
+	})
+}
+
+// Delete removes the catalogue entry; its subscriptions keep their service_name
+// and lose the link.
+func (sr *ServicesRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "services.Delete")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	result, err := sr.Db.ExecContext(ctx, `DELETE FROM services WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// checkDuplicate rejects a name or alias already resolving to another service.
+func (sr *ServicesRepository) checkDuplicate(ctx context.Context, s models.Service, selfID int) error {
+	stmt := `SELECT id
+			 FROM services
+			 WHERE id <> $2
+			 AND (service_key(name) IN (SELECT service_key(n) FROM unnest($1::text[]) n)
+			   OR EXISTS (SELECT 1 FROM unnest(aliases) a
+			              WHERE service_key(a) IN (SELECT service_key(n) FROM unnest($1::text[]) n)))
+			 LIMIT 1`
+	names := append([]string{s.Name}, s.Aliases...)
+	var existing int
+	err := sr.Db.QueryRowContext(ctx, stmt, pq.Array(names), selfID).Scan(&existing)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &DuplicateServiceError{ExistingID: existing}
+}
+
+// duplicateError maps a unique violation of services_name_key, left by a
+// concurrent insert, to a DuplicateServiceError.
+func duplicateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return &DuplicateServiceError{}
+	}
+	return err
+}
+
+// ServiceKey is the Go counterpart of the service_key() SQL function.
+func ServiceKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// cleanAliases trims aliases and drops empty ones and those equal to the name
+// or to each other.
+func cleanAliases(name string, aliases []string) []string {
+	seen := map[string]bool{ServiceKey(name): true}
+	cleaned := []string{}
+	for _, a := range aliases {
+		key := ServiceKey(a)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, strings.TrimSpace(a))
+	}
+	return cleaned
+}
+
+const serviceColumns = `id, name, aliases, category, default_price, website`
+
+func scanService(row scanner) (dto.ServiceDTO, error) {
+	var s dto.ServiceDTO
+	err := row.Scan(&s.Id, &s.Name, pq.Array(&s.Aliases), &s.Category, &s.DefaultPrice, &s.Website)
+	return s, err
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// resolveService links s to the catalogue: an explicit ServiceID must exist and
+// sets the canonical name, otherwise ServiceName is looked up by name and
+// alias. Names matching nothing are kept as free text.
+func resolveService(ctx context.Context, q querier, s *models.Subscription) error {
+	if s.ServiceID != nil {
+		err := q.QueryRowContext(ctx, `SELECT name FROM services WHERE id = $1`, *s.ServiceID).Scan(&s.ServiceName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUnknownService
+		}
+		return err
+	}
+	var id int
+	err := q.QueryRowContext(ctx, serviceLookup, s.ServiceName).Scan(&id, &s.ServiceName)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.ServiceName = strings.TrimSpace(s.ServiceName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.ServiceID = &id
+	return nil
+}
+
+// Normalize links free-text subscriptions to the catalogue by name and alias,
+// renaming them to the canonical name. With createMissing every unknown name
+// first gets a catalogue entry of its most frequent spelling. Rows are updated
+// one by one, so the job can be interrupted and rerun. With dryRun nothing is
+// written: Created counts the names that would be added and Linked the rows
+// matching the current catalogue.
+func (sr *ServicesRepository) Normalize(ctx context.Context, createMissing, dryRun bool) (report dto.NormalizeReport, err error) {
+	ctx, span := startSpan(ctx, "services.Normalize")
+	defer func() { endSpan(span, int64(report.Linked), err) }()
+
+	noMatch := `NOT EXISTS (SELECT 1 FROM services sv
+			     WHERE service_key(sv.name) = sp.key
+			     OR EXISTS (SELECT 1 FROM unnest(sv.aliases) a WHERE service_key(a) = sp.key))`
+	spellings := `WITH spellings AS (
+			     SELECT service_key(service_name) AS key, btrim(service_name) AS name, count(*) AS n
+			     FROM subscriptions
+			     WHERE service_id IS NULL
+			     GROUP BY 1, 2)`
+
+	if createMissing {
+		if dryRun {
+			err = sr.Db.QueryRowContext(ctx, spellings+`
+				SELECT count(DISTINCT key) FROM spellings sp WHERE key <> '' AND `+noMatch).Scan(&report.Created)
+		} else {
+			var result sql.Result
+			result, err = sr.Db.ExecContext(ctx, spellings+`
+				INSERT INTO services(name)
+				SELECT DISTINCT ON (key) name
+				FROM spellings sp
+				WHERE key <> '' AND `+noMatch+`
+				ORDER BY key, n DESC, name
+				ON CONFLICT DO NOTHING`)
+			if err == nil {
+				report.Created, err = result.RowsAffected()
+			}
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+
+	stmt := `SELECT sub.id, found.id, found.name
+			 FROM subscriptions sub
+			 JOIN LATERAL (` + strings.ReplaceAll(serviceLookup, "$1", "sub.service_name") + `) found ON true
+			 WHERE sub.service_id IS NULL
+			 ORDER BY sub.id`
+	rows, err := sr.Db.QueryContext(ctx, stmt)
+	if err != nil {
+		return report, err
+	}
+	type link struct {
+		subscription, service int
+		name                  string
+	}
+	var links []link
+	for rows.Next() {
+		var l link
+		if err = rows.Scan(&l.subscription, &l.service, &l.name); err != nil {
+			rows.Close()
+			return report, err
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return report, err
+	}
+
+	for _, l := range links {
+		if dryRun {
+			report.Linked++
+			continue
+		}
+		err = sr.link(ctx, l.subscription, l.service, l.name)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == exclusionViolation {
+			report.Conflicts = append(report.Conflicts, l.subscription)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Linked++
+	}
+
+	rows, err = sr.Db.QueryContext(ctx, spellings+`
+		SELECT name, n FROM spellings sp
+		WHERE `+noMatch+`
+		ORDER BY n DESC, name
+		LIMIT 20`)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var nc dto.NameCount
+		if err = rows.Scan(&nc.Name, &nc.Count); err != nil {
+			return report, err
+		}
+		report.Unmatched = append(report.Unmatched, nc)
+	}
+	return report, rows.Err()
+}
+
+// link ties a subscription to its catalogue entry under the canonical name.
+// A live subscription whose name changes gets an update event.
+func (sr *ServicesRepository) link(ctx context.Context, subscriptionID, serviceID int, name string) error {
+	tx, err := sr.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `WITH old AS (
+				SELECT service_name, deleted_at FROM subscriptions WHERE id = $1 AND service_id IS NULL FOR UPDATE
+			 )
+			 UPDATE subscriptions s SET service_id = $2, service_name = $3
+			 FROM old
+			 WHERE s.id = $1
+			 RETURNING old.deleted_at IS NULL AND old.service_name <> $3`
+	var renamed bool
+	err = tx.QueryRowContext(ctx, stmt, subscriptionID, serviceID, name).Scan(&renamed)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Linked meanwhile.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if renamed {
+		if err = recordSubscriptionEvents(ctx, tx, subscriptionID, models.EventSubscriptionUpdated); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
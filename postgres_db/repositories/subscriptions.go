@@ -1,24 +1,164 @@
 package repositories
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
+	"testTaskEffectiveMobile/billing"
 	"testTaskEffectiveMobile/dto"
 	"testTaskEffectiveMobile/models"
+	"testTaskEffectiveMobile/postgres_db"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SubscriptionsRepository struct {
 	Db *sql.DB
+	// Replicas, when set, serve the read-only queries.
+	Replicas *postgres_db.ReplicaSet
 }
 
-func (sr *SubscriptionsRepository) CalculateSum(calcDto dto.CalculationRequestDTO) (int64, error) {
-	query := `
-        SELECT COALESCE(SUM(price), 0) 
-        FROM subscriptions 
-        WHERE 1=1`
+// reader picks the pool for a read-only query: a healthy replica unless the
+// caller asked for the primary with postgres_db.WithPrimary.
+func (sr *SubscriptionsRepository) reader(ctx context.Context) *sql.DB {
+	if sr.Replicas == nil || postgres_db.PrimaryRequested(ctx) {
+		return sr.Db
+	}
+	db := sr.Replicas.Reader()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("db.replica", db != sr.Db))
+	return db
+}
+
+// CalculateSum adds up what the matching subscriptions cost month by month
+// over the period, charging trial and promo months at their own prices.
+func (sr *SubscriptionsRepository) CalculateSum(ctx context.Context, calcDto dto.CalculationRequestDTO) (totalCost int64, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.CalculateSum")
+	defer func() { endSpan(span, 1, err) }()
+
+	calcDto.GroupBy = ""
+	err = sr.periodCosts(ctx, calcDto, func(_ *string, cost int64) {
+		totalCost += cost
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate total cost: %w", err)
+	}
+	return totalCost, nil
+}
+
+// CalculateGroups breaks the CalculateSum total down by calcDto.GroupBy,
+// "category" or "tag", most expensive first.
+func (sr *SubscriptionsRepository) CalculateGroups(ctx context.Context, calcDto dto.CalculationRequestDTO) (groups []dto.CalculationGroup, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.CalculateGroups")
+	defer func() { endSpan(span, int64(len(groups)), err) }()
+
+	if calcDto.GroupBy != "category" && calcDto.GroupBy != "tag" {
+		return nil, fmt.Errorf("unknown grouping %q", calcDto.GroupBy)
+	}
+	costs := map[string]int64{}
+	var ungrouped *int64
+	err = sr.periodCosts(ctx, calcDto, func(key *string, cost int64) {
+		if key != nil {
+			costs[*key] += cost
+			return
+		}
+		if ungrouped == nil {
+			ungrouped = new(int64)
+		}
+		*ungrouped += cost
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate group costs: %w", err)
+	}
+
+	groups = make([]dto.CalculationGroup, 0, len(costs)+1)
+	for key, cost := range costs {
+		groups = append(groups, dto.CalculationGroup{Key: &key, Price: cost})
+	}
+	if ungrouped != nil {
+		groups = append(groups, dto.CalculationGroup{Price: *ungrouped})
+	}
+	slices.SortFunc(groups, func(a, b dto.CalculationGroup) int {
+		if c := cmp.Compare(b.Price, a.Price); c != 0 {
+			return c
+		}
+		switch {
+		case a.Key == nil:
+			return 1
+		case b.Key == nil:
+			return -1
+		}
+		return strings.Compare(*a.Key, *b.Key)
+	})
+	return groups, nil
+}
+
+// periodCosts calls fn with the cost over the period of every subscription a
+// calculation covers, keyed by calcDto.GroupBy. With tag grouping a
+// subscription is reported once per tag.
+func (sr *SubscriptionsRepository) periodCosts(ctx context.Context, calcDto dto.CalculationRequestDTO, fn func(key *string, cost int64)) error {
+	where, args := calculationFilter(calcDto)
+	matching := `SELECT id, ` + effectiveCategory + ` AS category, ` + planColumns + `
+		FROM subscriptions
+		WHERE deleted_at IS NULL` + where
+
+	var query string
+	switch calcDto.GroupBy {
+	case "":
+		query = `SELECT NULL::text, ` + planFields + ` FROM (` + matching + `) s`
+	case "category":
+		query = `SELECT s.category, ` + planFields + ` FROM (` + matching + `) s`
+	case "tag":
+		query = `SELECT t.tag, ` + planFields + ` FROM (` + matching + `) s
+			LEFT JOIN subscription_tags t ON t.subscription_id = s.id`
+	}
+
+	rows, err := sr.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	from, to := calcDto.StartDate.Time, calcDto.EndDate.Time
+	for rows.Next() {
+		var key *string
+		var plan billing.Plan
+		if err = scanPlan(rows, &plan, &key); err != nil {
+			return err
+		}
+		fn(key, plan.Cost(from, to))
+	}
+	return rows.Err()
+}
 
+// planColumns select a billing.Plan from subscriptions for scanPlan;
+// planFields read the same columns back from a subquery over planColumns.
+const (
+	planColumns = `price, start_date, end_date, trial_months, trial_price, promo_months, promo_price,
+	billing_period, ` + priceChangesColumn + ` AS price_changes`
+	planFields = `price, start_date, end_date, trial_months, trial_price, promo_months, promo_price,
+	billing_period, price_changes`
+)
+
+// scanPlan scans planColumns, following the leading columns, into plan.
+func scanPlan(row scanner, plan *billing.Plan, leading ...any) error {
+	var period string
+	err := row.Scan(append(leading, &plan.Price, &plan.Start, &plan.End, &plan.TrialMonths, &plan.TrialPrice,
+		&plan.PromoMonths, &plan.PromoPrice, &period, (*priceChanges)(&plan.PriceChanges))...)
+	plan.PeriodMonths = models.BillingPeriodMonths(period)
+	return err
+}
+
+// calculationFilter returns the conditions selecting the subscriptions a
+// calculation covers, to be appended to "WHERE deleted_at IS NULL".
+func calculationFilter(calcDto dto.CalculationRequestDTO) (string, []any) {
+	var query string
 	var args []any
 	argIndex := 1
 
@@ -29,7 +169,11 @@ func (sr *SubscriptionsRepository) CalculateSum(calcDto dto.CalculationRequestDT
 	}
 
 	if calcDto.ServiceName != nil {
-		query += fmt.Sprintf(" AND service_name = $%d", argIndex)
+		// Linked rows match through the catalogue (aliases included), the
+		// rest by name ignoring case and whitespace.
+		query += fmt.Sprintf(` AND (service_id = (SELECT id FROM (%s) found)
+			OR (service_id IS NULL AND service_key(service_name) = service_key($%d)))`,
+			strings.ReplaceAll(serviceLookup, "$1", fmt.Sprintf("$%d", argIndex)), argIndex)
 		args = append(args, *calcDto.ServiceName)
 		argIndex++
 	}
@@ -40,40 +184,64 @@ func (sr *SubscriptionsRepository) CalculateSum(calcDto dto.CalculationRequestDT
 		argIndex, argIndex+1)
 	args = append(args, calcDto.EndDate, calcDto.StartDate)
 
-	var totalCost int64
-	err := sr.Db.QueryRow(query, args...).Scan(&totalCost)
-	if err != nil {
-		return 0, fmt.Errorf("failed to calculate total cost: %w", err)
-	}
-
-	return totalCost, nil
+	cond, args := taxonomyFilter(args, calcDto.Category, calcDto.Tags, calcDto.TagMode == "all")
+	return query + cond, args
 }
 
-func (sr *SubscriptionsRepository) GetByUserID(userId uuid.UUID) ([]dto.SubscriptionDTO, error) {
-	existsStmt := `SELECT EXISTS(SELECT 1 FROM subscriptions WHERE user_id = $1)`
-	var userExists bool
-	err := sr.Db.QueryRow(existsStmt, userId).Scan(&userExists)
+// StreamByUserID calls fn for every subscription of the user straight from the
+// result set, without materializing the list. Iteration stops at the first error.
+func (sr *SubscriptionsRepository) StreamByUserID(ctx context.Context, userId uuid.UUID, fn func(dto.SubscriptionDTO) error) (err error) {
+	ctx, span := startSpan(ctx, "subscriptions.StreamByUserID")
+	var count int64
+	defer func() { endSpan(span, count, err) }()
+
+	stmt := `SELECT ` + subscriptionColumns + `
+			 FROM subscriptions
+			 WHERE user_id = $1
+			 AND deleted_at IS NULL
+			 ORDER BY id`
+
+	rows, err := sr.reader(ctx).QueryContext(ctx, stmt, userId)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if !userExists {
-		return nil, sql.ErrNoRows
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return err
+		}
+		count++
+		if err = fn(s); err != nil {
+			return err
+		}
 	}
+	return rows.Err()
+}
+
+// ActiveBetween returns the user's subscriptions active in at least one month
+// of from..to, ordered by id.
+func (sr *SubscriptionsRepository) ActiveBetween(ctx context.Context, userId uuid.UUID, from, to models.MonthYearDate) (subscriptions []dto.SubscriptionDTO, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.ActiveBetween")
+	defer func() { endSpan(span, int64(len(subscriptions)), err) }()
 
-	stmt := `SELECT id, service_name, price, user_id, start_date, end_date
+	stmt := `SELECT ` + subscriptionColumns + `
 			 FROM subscriptions
-			 WHERE user_id = $1`
+			 WHERE user_id = $1
+			 AND deleted_at IS NULL
+			 AND start_date <= $3
+			 AND (end_date IS NULL OR end_date >= $2)
+			 ORDER BY id`
 
-	rows, err := sr.Db.Query(stmt, userId)
+	rows, err := sr.reader(ctx).QueryContext(ctx, stmt, userId, from, to)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var subscriptions []dto.SubscriptionDTO
 	for rows.Next() {
-		var s dto.SubscriptionDTO
-		err = rows.Scan(&s.Id, &s.ServiceName, &s.Price, &s.UserId, &s.StartDate, &s.EndDate)
+		s, err := scanSubscription(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -85,65 +253,472 @@ func (sr *SubscriptionsRepository) GetByUserID(userId uuid.UUID) ([]dto.Subscrip
 	return subscriptions, nil
 }
 
-func (sr *SubscriptionsRepository) GetByUserIDAndID(userId uuid.UUID, id int) (dto.SubscriptionDTO, error) {
-	stmt := `SELECT id, service_name, price, user_id, start_date, end_date
+func (sr *SubscriptionsRepository) GetByUserIDAndID(ctx context.Context, userId uuid.UUID, id int) (s dto.SubscriptionDTO, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.GetByUserIDAndID")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	stmt := `SELECT ` + subscriptionColumns + `
 			 FROM subscriptions
 			 WHERE user_id = $1
-			 AND id = $2`
+			 AND id = $2
+			 AND deleted_at IS NULL`
 
-	var s dto.SubscriptionDTO
+	s, err = scanSubscription(sr.reader(ctx).QueryRowContext(ctx, stmt, userId, id))
+	if err != nil {
+		return dto.SubscriptionDTO{}, err
+	}
+	return s, nil
+}
+
+func (sr *SubscriptionsRepository) GetByID(ctx context.Context, id int) (s dto.SubscriptionDTO, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.GetByID")
+	defer func() { endSpan(span, rowCount(err), err) }()
 
-	err := sr.Db.QueryRow(stmt, userId, id).Scan(&s.Id, &s.ServiceName, &s.Price, &s.UserId, &s.StartDate, &s.EndDate)
+	stmt := `SELECT ` + subscriptionColumns + `
+			 FROM subscriptions
+			 WHERE id = $1
+			 AND deleted_at IS NULL`
+
+	s, err = scanSubscription(sr.reader(ctx).QueryRowContext(ctx, stmt, id))
 	if err != nil {
 		return dto.SubscriptionDTO{}, err
 	}
 	return s, nil
 }
 
-func (sr *SubscriptionsRepository) Insert(s models.Subscription) error {
-	stmt := `INSERT INTO subscriptions(user_id, service_name, price, start_date, end_date)
-    VALUES ($1, $2, $3, $4, $5)`
-	_, err := sr.Db.Exec(stmt, s.UserId, s.ServiceName, s.Price, s.StartDate, s.EndDate)
+// Search streams the subscriptions matching the filter, ordered by id, to fn.
+func (sr *SubscriptionsRepository) Search(ctx context.Context, filter dto.SubscriptionFilter, fn func(dto.SubscriptionDTO) error) (err error) {
+	ctx, span := startSpan(ctx, "subscriptions.Search")
+	var count int64
+	defer func() { endSpan(span, count, err) }()
+
+	query := `SELECT ` + subscriptionColumns + `
+			 FROM subscriptions
+			 WHERE deleted_at IS NULL`
+
+	var args []any
+	argIndex := 1
+
+	if filter.UserID != nil {
+		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
+		args = append(args, *filter.UserID)
+		argIndex++
+	}
+
+	if filter.ServiceName != "" {
+		query += fmt.Sprintf(" AND service_name ILIKE '%%' || $%d || '%%'", argIndex)
+		args = append(args, escapeLike(filter.ServiceName))
+		argIndex++
+	}
+
+	if filter.ActiveIn != nil {
+		query += fmt.Sprintf(" AND start_date <= $%d AND (end_date IS NULL OR end_date >= $%d)", argIndex, argIndex)
+		args = append(args, *filter.ActiveIn)
+		argIndex++
+	}
+
+	var category *string
+	if filter.Category != "" {
+		category = &filter.Category
+	}
+	cond, args := taxonomyFilter(args, category, filter.Tags, filter.MatchAllTags)
+	query += cond
+	argIndex = len(args) + 1
+
+	query += " ORDER BY id"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, filter.Limit)
+		argIndex++
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := sr.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return err
+		}
+		count++
+		if err = fn(s); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (sr *SubscriptionsRepository) Insert(ctx context.Context, s models.Subscription) (id int, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.Insert")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	tx, err := sr.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err = resolveService(ctx, tx, &s); err != nil {
+		return 0, err
+	}
+	stmt := `INSERT INTO subscriptions(user_id, service_name, price, start_date, end_date, service_id, category,
+                          trial_months, trial_price, promo_months, promo_price, billing_period)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    RETURNING id`
+	err = tx.QueryRowContext(ctx, stmt, s.UserId, s.ServiceName, s.Price, s.StartDate, s.EndDate, s.ServiceID, cleanOptional(s.Category),
+		s.TrialMonths, s.TrialPrice, s.PromoMonths, s.PromoPrice, billingPeriod(s)).Scan(&id)
+	if err != nil {
+		return 0, sr.overlapError(ctx, err, s, 0)
+	}
+	if err = setTags(ctx, tx, id, cleanTags(s.Tags)); err != nil {
+		return 0, err
+	}
+	if err = setPriceChanges(ctx, tx, id, s.PriceChanges); err != nil {
+		return 0, err
+	}
+	if err = recordSubscriptionEvents(ctx, tx, id, models.EventSubscriptionCreated); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// InsertBatch inserts all subscriptions in one transaction: either every row is stored or none.
+func (sr *SubscriptionsRepository) InsertBatch(ctx context.Context, subs []models.Subscription) (err error) {
+	ctx, span := startSpan(ctx, "subscriptions.InsertBatch")
+	defer func() { endSpan(span, int64(len(subs)), err) }()
+
+	tx, err := sr.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO subscriptions(user_id, service_name, price, start_date, end_date, service_id, category,
+                          trial_months, trial_price, promo_months, promo_price, billing_period)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    RETURNING id`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, s := range subs {
+		if err = resolveService(ctx, tx, &s); err != nil {
+			return fmt.Errorf("row %d: %w", i+1, err)
+		}
+		var id int
+		err = stmt.QueryRowContext(ctx, s.UserId, s.ServiceName, s.Price, s.StartDate, s.EndDate, s.ServiceID, cleanOptional(s.Category),
+			s.TrialMonths, s.TrialPrice, s.PromoMonths, s.PromoPrice, billingPeriod(s)).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", i+1, sr.overlapError(ctx, err, s, 0))
+		}
+		if tags := cleanTags(s.Tags); len(tags) > 0 {
+			if err = setTags(ctx, tx, id, tags); err != nil {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+		}
+		if len(s.PriceChanges) > 0 {
+			if err = setPriceChanges(ctx, tx, id, s.PriceChanges); err != nil {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+		}
+		if err = recordSubscriptionEvents(ctx, tx, id, models.EventSubscriptionCreated); err != nil {
+			return fmt.Errorf("row %d: %w", i+1, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// CopyFrom bulk-loads every subscription passed to add with COPY, in one
+// transaction, and returns how many rows were loaded. Service names are stored
+// as given; NormalizeServices links them to the catalogue afterwards. Category
+// overrides, tags and price changes are not loaded.
+func (sr *SubscriptionsRepository) CopyFrom(ctx context.Context, produce func(add func(models.Subscription) error) error) (count int64, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.CopyFrom")
+	defer func() { endSpan(span, count, err) }()
+
+	tx, err := sr.Db.BeginTx(ctx, nil)
 	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("subscriptions", "user_id", "service_name", "price", "start_date", "end_date", "service_id",
+		"trial_months", "trial_price", "promo_months", "promo_price", "billing_period"))
+	if err != nil {
+		return 0, err
+	}
+	err = produce(func(s models.Subscription) error {
+		_, err := stmt.ExecContext(ctx, s.UserId, s.ServiceName, s.Price, s.StartDate, s.EndDate, s.ServiceID,
+			s.TrialMonths, s.TrialPrice, s.PromoMonths, s.PromoPrice, billingPeriod(s))
+		if err == nil {
+			count++
+		}
 		return err
+	})
+	if err != nil {
+		stmt.Close()
+		return 0, err
+	}
+	// An Exec without arguments flushes the buffered rows.
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return 0, err
+	}
+	if err = stmt.Close(); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Empty reports whether the table has no rows at all, soft-deleted ones included.
+func (sr *SubscriptionsRepository) Empty(ctx context.Context) (empty bool, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.Empty")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	var exists bool
+	err = sr.Db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM subscriptions)`).Scan(&exists)
+	if err != nil {
+		return false, err
 	}
-	return nil
+	return !exists, nil
 }
 
-func (sr *SubscriptionsRepository) Update(id int, s models.Subscription) error {
+// Update replaces the subscription. Its tags and price changes are only
+// replaced when s.Tags and s.PriceChanges are not nil.
+func (sr *SubscriptionsRepository) Update(ctx context.Context, id int, s models.Subscription) (err error) {
+	ctx, span := startSpan(ctx, "subscriptions.Update")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	tx, err := sr.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = resolveService(ctx, tx, &s); err != nil {
+		return err
+	}
+	var openEnded bool
+	err = tx.QueryRowContext(ctx, `SELECT end_date IS NULL FROM subscriptions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).
+		Scan(&openEnded)
+	if err != nil {
+		return err
+	}
 	stmt := `update subscriptions
 				set service_name = $2,
 					user_id = $3,
 					price = $4,
 					start_date = $5,
-					end_date = $6
-				where id = $1`
-	result, err := sr.Db.Exec(stmt, id, s.ServiceName, s.UserId, s.Price, s.StartDate, s.EndDate)
+					end_date = $6,
+					service_id = $7,
+					category = $8,
+					trial_months = $9,
+					trial_price = $10,
+					promo_months = $11,
+					promo_price = $12,
+					billing_period = $13
+				where id = $1
+				and deleted_at is null`
+	result, err := tx.ExecContext(ctx, stmt, id, s.ServiceName, s.UserId, s.Price, s.StartDate, s.EndDate, s.ServiceID, cleanOptional(s.Category),
+		s.TrialMonths, s.TrialPrice, s.PromoMonths, s.PromoPrice, billingPeriod(s))
 	if err != nil {
-		return err
+		return sr.overlapError(ctx, err, s, id)
 	}
-	affected, err := result.RowsAffected()
+	affected, err = result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if affected == 0 {
 		return sql.ErrNoRows
 	}
-	return nil
-
+	if s.Tags != nil {
+		if err = setTags(ctx, tx, id, cleanTags(s.Tags)); err != nil {
+			return err
+		}
+	}
+	if s.PriceChanges != nil {
+		if err = setPriceChanges(ctx, tx, id, s.PriceChanges); err != nil {
+			return err
+		}
+	}
+	events := []string{models.EventSubscriptionUpdated}
+	if openEnded && s.EndDate != nil {
+		events = append(events, models.EventSubscriptionEnded)
+	}
+	if err = recordSubscriptionEvents(ctx, tx, id, events...); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (sr *SubscriptionsRepository) Delete(id int) error {
-	result, err := sr.Db.Exec("DELETE FROM subscriptions WHERE id = $1", id)
+// Delete soft-deletes the subscription: it disappears from every read but
+// stays in the table until Purge removes it.
+func (sr *SubscriptionsRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "subscriptions.Delete")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	tx, err := sr.Db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	affected, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE subscriptions SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	affected, err = result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if affected == 0 {
 		return sql.ErrNoRows
 	}
-	return nil
+	if err = recordSubscriptionEvents(ctx, tx, id, models.EventSubscriptionDeleted); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Purge permanently removes subscriptions soft-deleted before the cutoff and returns how many were removed.
+func (sr *SubscriptionsRepository) Purge(ctx context.Context, deletedBefore time.Time) (affected int64, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.Purge")
+	defer func() { endSpan(span, affected, err) }()
+
+	result, err := sr.Db.ExecContext(ctx, "DELETE FROM subscriptions WHERE deleted_at IS NOT NULL AND deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// TrialsEnding streams the subscriptions, optionally of one user, whose last
+// trial month is month, leaving out those that end by then anyway.
+func (sr *SubscriptionsRepository) TrialsEnding(ctx context.Context, month models.MonthYearDate, userID *uuid.UUID, fn func(dto.SubscriptionDTO) error) (err error) {
+	ctx, span := startSpan(ctx, "subscriptions.TrialsEnding")
+	var count int64
+	defer func() { endSpan(span, count, err) }()
+
+	query := `SELECT ` + subscriptionColumns + `
+			 FROM subscriptions
+			 WHERE deleted_at IS NULL
+			 AND trial_months > 0
+			 AND start_date <= $1
+			 AND ` + utcStart + ` + make_interval(months => trial_months - 1) = ` + utcMonth + `
+			 AND (end_date IS NULL OR end_date > $1)`
+	args := []any{month}
+	if userID != nil {
+		query += " AND user_id = $2"
+		args = append(args, *userID)
+	}
+	query += " ORDER BY user_id, id"
+
+	rows, err := sr.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return err
+		}
+		count++
+		if err = fn(s); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Stats aggregates the subscriptions active in the month containing at. The
+// revenue is what they are charged for that month, priced by billing.Plan
+// like CalculateSum.
+func (sr *SubscriptionsRepository) Stats(ctx context.Context, at time.Time) (stats dto.SubscriptionStats, err error) {
+	ctx, span := startSpan(ctx, "subscriptions.Stats")
+	defer func() { endSpan(span, int64(len(stats.ByService)), err) }()
+
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	stmt := `SELECT service_name, ` + planColumns + `
+			 FROM subscriptions
+			 WHERE deleted_at IS NULL
+			 AND start_date <= $1
+			 AND (end_date IS NULL OR end_date >= $1)`
+
+	rows, err := sr.reader(ctx).QueryContext(ctx, stmt, month)
+	if err != nil {
+		return dto.SubscriptionStats{}, fmt.Errorf("failed to collect stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats = dto.SubscriptionStats{ByService: make(map[string]int)}
+	for rows.Next() {
+		var (
+			service string
+			plan    billing.Plan
+		)
+		if err = scanPlan(rows, &plan, &service); err != nil {
+			return dto.SubscriptionStats{}, err
+		}
+		stats.ByService[service]++
+		stats.Active++
+		stats.MonthlyRecurringRevenue += plan.Cost(month, month)
+	}
+	if err = rows.Err(); err != nil {
+		return dto.SubscriptionStats{}, err
+	}
+	return stats, nil
+}
+
+// Trial boundaries are compared as UTC wall-clock times, where adding months
+// does not depend on the session time zone. utcMonth is the month in $1.
+const (
+	utcStart = `(start_date AT TIME ZONE 'UTC')`
+	utcMonth = `($1::timestamptz AT TIME ZONE 'UTC')`
+)
+
+// subscriptionColumns is the select list read by scanSubscription.
+const subscriptionColumns = `id, service_name, price, user_id, start_date, end_date,
+	trial_months, trial_price, promo_months, promo_price, billing_period, ` + priceChangesColumn + `, service_id, category,
+	` + effectiveCategory + `,
+	ARRAY(SELECT t.tag FROM subscription_tags t WHERE t.subscription_id = subscriptions.id ORDER BY t.tag)`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubscription(row scanner) (dto.SubscriptionDTO, error) {
+	var s dto.SubscriptionDTO
+	err := row.Scan(&s.Id, &s.ServiceName, &s.Price, &s.UserId, &s.StartDate, &s.EndDate,
+		&s.TrialMonths, &s.TrialPrice, &s.PromoMonths, &s.PromoPrice, &s.BillingPeriod, (*priceChanges)(&s.PriceChanges), &s.ServiceID, &s.Category, &s.EffectiveCategory, pq.Array(&s.Tags))
+	return s, err
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// rowCount is the row count of a single-row statement.
+func rowCount(err error) int64 {
+	if err != nil {
+		return 0
+	}
+	return 1
 }
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// effectiveCategory is the per-subscription override, falling back to the
+// category of the linked catalogue entry.
+const effectiveCategory = `COALESCE(subscriptions.category,
+	(SELECT sv.category FROM services sv WHERE sv.id = subscriptions.service_id))`
+
+// cleanTags lower-cases tags, collapses their whitespace and drops empty and
+// repeated ones. A nil slice stays nil so that callers can tell "not given"
+// from "none".
+func cleanTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	cleaned := []string{}
+	for _, t := range tags {
+		key := ServiceKey(t)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, key)
+	}
+	return cleaned
+}
+
+// cleanOptional trims an optional text value; a blank one becomes NULL.
+func cleanOptional(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*value)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// setTags replaces the tags of the subscription.
+func setTags(ctx context.Context, e execer, id int, tags []string) error {
+	if _, err := e.ExecContext(ctx, `DELETE FROM subscription_tags WHERE subscription_id = $1`, id); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err := e.ExecContext(ctx, `INSERT INTO subscription_tags(subscription_id, tag)
+		SELECT $1, unnest($2::text[])`, id, pq.Array(tags))
+	return err
+}
+
+// taxonomyFilter returns the conditions for a category and a tag filter,
+// numbering its placeholders after the args already collected.
+func taxonomyFilter(args []any, category *string, tags []string, matchAll bool) (string, []any) {
+	var cond string
+	if category != nil {
+		args = append(args, strings.TrimSpace(*category))
+		cond += fmt.Sprintf(" AND lower(%s) = lower($%d)", effectiveCategory, len(args))
+	}
+	tags = cleanTags(tags)
+	if len(tags) > 0 {
+		args = append(args, pq.Array(tags))
+		if matchAll {
+			cond += fmt.Sprintf(` AND (SELECT count(*) FROM subscription_tags t
+				WHERE t.subscription_id = subscriptions.id AND t.tag = ANY($%d)) = %d`, len(args), len(tags))
+		} else {
+			cond += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM subscription_tags t
+				WHERE t.subscription_id = subscriptions.id AND t.tag = ANY($%d))`, len(args))
+		}
+	}
+	return cond, args
+}
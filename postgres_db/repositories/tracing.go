@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("testTaskEffectiveMobile/postgres_db/repositories")
+
+// startSpan opens a client span for one repository method; statement names the query it runs.
+func startSpan(ctx context.Context, statement string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, statement,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.statement.name", statement),
+		))
+}
+
+// endSpan records the row count and, except for sql.ErrNoRows, the error.
+func endSpan(span trace.Span, rows int64, err error) {
+	span.SetAttributes(attribute.Int64("db.response.rows", rows))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
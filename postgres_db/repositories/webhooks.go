@@ -0,0 +1,269 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"testTaskEffectiveMobile/dto"
+	"testTaskEffectiveMobile/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhooksRepository keeps webhook registrations and their delivery queue.
+// The outbox relay queues deliveries with Enqueue, the dispatcher claims and
+// settles them.
+type WebhooksRepository struct {
+	Db *sql.DB
+}
+
+const webhookColumns = `id, url, events, active`
+
+func scanWebhook(row scanner) (dto.WebhookDTO, error) {
+	var w dto.WebhookDTO
+	active := true
+	err := row.Scan(&w.Id, &w.URL, pq.Array(&w.Events), &active)
+	w.Active = &active
+	return w, err
+}
+
+func (wr *WebhooksRepository) List(ctx context.Context) (webhooks []dto.WebhookDTO, err error) {
+	ctx, span := startSpan(ctx, "webhooks.List")
+	defer func() { endSpan(span, int64(len(webhooks)), err) }()
+
+	rows, err := wr.Db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks = []dto.WebhookDTO{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (wr *WebhooksRepository) GetByID(ctx context.Context, id int) (w dto.WebhookDTO, err error) {
+	ctx, span := startSpan(ctx, "webhooks.GetByID")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	w, err = scanWebhook(wr.Db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+	if err != nil {
+		return dto.WebhookDTO{}, err
+	}
+	return w, nil
+}
+
+// Insert registers the webhook and returns its id and signing secret, which
+// is generated when w.Secret is empty.
+func (wr *WebhooksRepository) Insert(ctx context.Context, w models.Webhook) (id int, secret string, err error) {
+	ctx, span := startSpan(ctx, "webhooks.Insert")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	secret = w.Secret
+	if secret == "" {
+		secret = rand.Text()
+	}
+	stmt := `INSERT INTO webhooks(url, secret, events, active) VALUES ($1, $2, $3, $4) RETURNING id`
+	err = wr.Db.QueryRowContext(ctx, stmt, w.URL, secret, pq.Array(w.Events), w.Active == nil || *w.Active).Scan(&id)
+	if err != nil {
+		return 0, "", err
+	}
+	return id, secret, nil
+}
+
+// Update replaces the webhook; an empty w.Secret keeps the current one.
+func (wr *WebhooksRepository) Update(ctx context.Context, id int, w models.Webhook) (err error) {
+	ctx, span := startSpan(ctx, "webhooks.Update")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	stmt := `update webhooks
+				set url = $2,
+					secret = COALESCE(NULLIF($3, ''), secret),
+					events = $4,
+					active = $5
+				where id = $1`
+	result, err := wr.Db.ExecContext(ctx, stmt, id, w.URL, w.Secret, pq.Array(w.Events), w.Active == nil || *w.Active)
+	if err != nil {
+		return err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete removes the webhook together with its deliveries.
+func (wr *WebhooksRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "webhooks.Delete")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	result, err := wr.Db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Deliveries returns the newest deliveries first, optionally of one webhook
+// and in one status.
+func (wr *WebhooksRepository) Deliveries(ctx context.Context, webhookID *int, status string, limit int) (deliveries []dto.DeliveryDTO, err error) {
+	ctx, span := startSpan(ctx, "webhooks.Deliveries")
+	defer func() { endSpan(span, int64(len(deliveries)), err) }()
+
+	query := `SELECT id, webhook_id, event, status, attempts, last_status, last_error, created_at,
+			 CASE WHEN status = 'pending' THEN next_attempt_at END, delivered_at, payload
+			 FROM webhook_deliveries
+			 WHERE ($1::integer IS NULL OR webhook_id = $1)
+			 AND ($2 = '' OR status = $2)
+			 ORDER BY id DESC
+			 LIMIT $3`
+	rows, err := wr.Db.QueryContext(ctx, query, webhookID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries = []dto.DeliveryDTO{}
+	for rows.Next() {
+		var d dto.DeliveryDTO
+		err = rows.Scan(&d.Id, &d.WebhookID, &d.Event, &d.Status, &d.Attempts, &d.LastStatus, &d.LastError, &d.CreatedAt,
+			&d.NextAttemptAt, &d.DeliveredAt, (*[]byte)(&d.Payload))
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Redeliver queues the delivery again with a fresh attempt budget, whatever
+// its status; sql.ErrNoRows means there is no such delivery.
+func (wr *WebhooksRepository) Redeliver(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "webhooks.Redeliver")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	stmt := `UPDATE webhook_deliveries
+			 SET status = 'pending', attempts = 0, next_attempt_at = now(), last_status = NULL, last_error = NULL
+			 WHERE id = $1`
+	result, err := wr.Db.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+	if affected, err = result.RowsAffected(); err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ClaimDue takes up to limit pending deliveries of active webhooks that are
+// due and hides them from other claims for lease, so that replicas polling
+// the same queue do not send them twice.
+func (wr *WebhooksRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) (due []dto.DueDelivery, err error) {
+	ctx, span := startSpan(ctx, "webhooks.ClaimDue")
+	defer func() { endSpan(span, int64(len(due)), err) }()
+
+	stmt := `UPDATE webhook_deliveries d
+			 SET next_attempt_at = now() + make_interval(secs => $2)
+			 FROM webhooks w
+			 WHERE w.id = d.webhook_id
+			 AND d.id IN (SELECT q.id FROM webhook_deliveries q
+			              JOIN webhooks qw ON qw.id = q.webhook_id AND qw.active
+			              WHERE q.status = 'pending' AND q.next_attempt_at <= now()
+			              ORDER BY q.next_attempt_at, q.id
+			              LIMIT $1
+			              FOR UPDATE OF q SKIP LOCKED)
+			 RETURNING d.id, w.url, w.secret, d.event, d.payload, d.attempts, d.created_at`
+	rows, err := wr.Db.QueryContext(ctx, stmt, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d dto.DueDelivery
+		if err = rows.Scan(&d.Id, &d.URL, &d.Secret, &d.Event, (*[]byte)(&d.Payload), &d.Attempts, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+func (wr *WebhooksRepository) MarkDelivered(ctx context.Context, id int64, status int) (err error) {
+	ctx, span := startSpan(ctx, "webhooks.MarkDelivered")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	stmt := `UPDATE webhook_deliveries
+			 SET status = 'delivered', attempts = attempts + 1, last_status = $2, last_error = NULL, delivered_at = now()
+			 WHERE id = $1`
+	_, err = wr.Db.ExecContext(ctx, stmt, id, status)
+	return err
+}
+
+// MarkFailed records a failed attempt. The delivery is retried at retryAt,
+// or moves to the dead-letter queue when retryAt is nil. status is 0 when no
+// response was received.
+func (wr *WebhooksRepository) MarkFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) (err error) {
+	ctx, span := startSpan(ctx, "webhooks.MarkFailed")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	stmt := `UPDATE webhook_deliveries
+			 SET status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			     attempts = attempts + 1,
+			     last_status = NULLIF($2, 0),
+			     last_error = $3,
+			     next_attempt_at = COALESCE($4, next_attempt_at)
+			 WHERE id = $1`
+	_, err = wr.Db.ExecContext(ctx, stmt, id, status, reason, retryAt)
+	return err
+}
+
+// Enqueue queues the outbox event for every active webhook subscribed to its
+// type. Enqueueing the same event again adds nothing, so the relay may retry.
+func (wr *WebhooksRepository) Enqueue(ctx context.Context, e dto.OutboxEvent) (err error) {
+	ctx, span := startSpan(ctx, "webhooks.Enqueue")
+	var affected int64
+	defer func() { endSpan(span, affected, err) }()
+
+	stmt := `INSERT INTO webhook_deliveries(webhook_id, event_id, event, payload, created_at)
+			 SELECT id, $1, $2::text, $3::jsonb, $4 FROM webhooks WHERE active AND $2::text = ANY(events)
+			 ON CONFLICT (webhook_id, event_id) DO NOTHING`
+	// Sent as text: lib/pq would encode []byte as bytea.
+	result, err := wr.Db.ExecContext(ctx, stmt, e.Id, e.Type, string(e.Data), e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	affected, err = result.RowsAffected()
+	return err
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"net/http"
+	"testTaskEffectiveMobile/compress"
+	"testTaskEffectiveMobile/cors"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 )
@@ -11,12 +13,70 @@ func (app *application) routes() http.Handler {
 	router.HandleFunc("POST /calculate", app.calculateSum)
 	router.HandleFunc("GET /subscriptions/{user_id}", app.getSubscriptions)
 	router.HandleFunc("GET /subscriptions/{user_id}/{subscription_id}", app.getSubscriptionByID)
+	router.HandleFunc("GET /subscriptions/{user_id}/schedule", app.getSchedule)
+	router.HandleFunc("GET /subscriptions/{user_id}/events", app.getSubscriptionEvents)
+	router.HandleFunc("GET /subscriptions/{user_id}/notifications", app.getNotificationSettings)
+	router.HandleFunc("PUT /subscriptions/{user_id}/notifications", app.putNotificationSettings)
+	router.HandleFunc("DELETE /subscriptions/{user_id}/notifications", app.deleteNotificationSettings)
+	router.HandleFunc("POST /subscriptions/{user_id}/calendar", app.createCalendarToken)
+	router.HandleFunc("DELETE /subscriptions/{user_id}/calendar", app.revokeCalendarToken)
+	router.HandleFunc("GET "+calendarFeedRoute, app.getCalendarFeed)
 	router.HandleFunc("POST /subscriptions", app.postSubscription)
 	router.HandleFunc("PUT /subscriptions/{subscription_id}", app.updateSubscription)
 	router.HandleFunc("DELETE /subscriptions/{subscription_id}", app.deleteSubscription)
+	router.HandleFunc("GET /trials", app.getEndingTrials)
+	router.HandleFunc("GET /budgets", app.listBudgets)
+	router.HandleFunc("GET /budgets/{budget_id}", app.getBudget)
+	router.HandleFunc("POST /budgets", app.postBudget)
+	router.HandleFunc("PUT /budgets/{budget_id}", app.updateBudget)
+	router.HandleFunc("DELETE /budgets/{budget_id}", app.deleteBudget)
+	router.HandleFunc("GET /webhooks", app.listWebhooks)
+	router.HandleFunc("GET /webhooks/{webhook_id}", app.getWebhook)
+	router.HandleFunc("POST /webhooks", app.postWebhook)
+	router.HandleFunc("PUT /webhooks/{webhook_id}", app.updateWebhook)
+	router.HandleFunc("DELETE /webhooks/{webhook_id}", app.deleteWebhook)
+	router.HandleFunc("GET /webhooks/deliveries", app.listDeliveries)
+	router.HandleFunc("POST /webhooks/deliveries/{delivery_id}/redeliver", app.redeliver)
+	router.HandleFunc("GET /services", app.listServices)
+	router.HandleFunc("GET /services/{service_id}", app.getService)
+	router.HandleFunc("POST /services", app.postService)
+	router.HandleFunc("PUT /services/{service_id}", app.updateService)
+	router.HandleFunc("DELETE /services/{service_id}", app.deleteService)
 
 	mux := http.NewServeMux()
-	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", app.LogMiddleware(router)))
-	mux.Handle("/swagger/", httpSwagger.WrapHandler)
+	mux.HandleFunc("GET /healthz", app.healthz)
+	mux.HandleFunc("GET /readyz", app.readyz)
+	if app.metrics != nil {
+		mux.Handle("GET /metrics", app.metrics.Handler())
+	}
+	var policy *cors.CORS
+	if len(app.config.CORS.AllowedOrigins) > 0 {
+		policy = cors.New(cors.Options{
+			AllowedOrigins:   app.config.CORS.AllowedOrigins,
+			AllowedMethods:   app.config.CORS.AllowedMethods,
+			AllowedHeaders:   app.config.CORS.AllowedHeaders,
+			ExposedHeaders:   app.config.CORS.ExposedHeaders,
+			AllowCredentials: app.config.CORS.AllowCredentials,
+			MaxAge:           app.config.CORS.MaxAge,
+		})
+	}
+
+	var api http.Handler = readYourWrites(recordRoute(router))
+	if policy != nil {
+		api = policy.Handler(api)
+	}
+	api = app.RecoverMiddleware(api)
+	if app.config.Features.Compression {
+		api = compress.Middleware(app.config.HTTP.CompressionMinSize)(api)
+	}
+	api = trackRoute(app.TracingMiddleware(app.LogMiddleware(app.MetricsMiddleware(api))))
+	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", api))
+	if app.config.Features.Swagger {
+		var swagger http.Handler = httpSwagger.WrapHandler
+		if policy != nil && app.config.CORS.Swagger {
+			swagger = policy.Handler(swagger)
+		}
+		mux.Handle("/swagger/", swagger)
+	}
 	return mux
 }
@@ -0,0 +1,82 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Catalogue is the set of services the generator draws from.
+type Catalogue struct {
+	Services []Service `yaml:"services"`
+}
+
+// Service is a subscription product with its relative popularity and price tiers.
+type Service struct {
+	Name string `yaml:"name"`
+	// Weight is the relative share of subscriptions to this service.
+	Weight int     `yaml:"weight"`
+	Prices []Price `yaml:"prices"`
+}
+
+// Price is a monthly price tier chosen with probability proportional to Weight.
+type Price struct {
+	Amount int `yaml:"amount"`
+	Weight int `yaml:"weight"`
+}
+
+// DefaultCatalogue resembles the subscriptions of a typical russian user.
+func DefaultCatalogue() Catalogue {
+	return Catalogue{Services: []Service{
+		{Name: "Yandex Plus", Weight: 30, Prices: []Price{{Amount: 299, Weight: 6}, {Amount: 399, Weight: 3}, {Amount: 649, Weight: 1}}},
+		{Name: "Netflix", Weight: 15, Prices: []Price{{Amount: 599, Weight: 4}, {Amount: 999, Weight: 5}, {Amount: 1299, Weight: 1}}},
+		{Name: "Spotify", Weight: 12, Prices: []Price{{Amount: 169, Weight: 2}, {Amount: 299, Weight: 6}, {Amount: 459, Weight: 2}}},
+		{Name: "Kinopoisk", Weight: 10, Prices: []Price{{Amount: 269, Weight: 7}, {Amount: 449, Weight: 3}}},
+		{Name: "VK Music", Weight: 10, Prices: []Price{{Amount: 169, Weight: 8}, {Amount: 249, Weight: 2}}},
+		{Name: "Okko", Weight: 6, Prices: []Price{{Amount: 199, Weight: 5}, {Amount: 399, Weight: 5}}},
+		{Name: "YouTube Premium", Weight: 8, Prices: []Price{{Amount: 299, Weight: 7}, {Amount: 449, Weight: 3}}},
+		{Name: "iCloud+", Weight: 9, Prices: []Price{{Amount: 59, Weight: 5}, {Amount: 149, Weight: 4}, {Amount: 599, Weight: 1}}},
+	}}
+}
+
+// LoadCatalogue reads a catalogue from a YAML or JSON file.
+func LoadCatalogue(path string) (Catalogue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Catalogue{}, fmt.Errorf("read catalogue: %w", err)
+	}
+	var c Catalogue
+	if err = yaml.UnmarshalStrict(data, &c); err != nil {
+		return Catalogue{}, fmt.Errorf("parse catalogue %s: %w", path, err)
+	}
+	if err = c.Validate(); err != nil {
+		return Catalogue{}, fmt.Errorf("catalogue %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c Catalogue) Validate() error {
+	var errs []error
+	if len(c.Services) == 0 {
+		errs = append(errs, errors.New("no services"))
+	}
+	for i, s := range c.Services {
+		if s.Name == "" {
+			errs = append(errs, fmt.Errorf("services[%d]: name is required", i))
+		}
+		if s.Weight <= 0 {
+			errs = append(errs, fmt.Errorf("services[%d]: weight must be positive", i))
+		}
+		if len(s.Prices) == 0 {
+			errs = append(errs, fmt.Errorf("services[%d]: at least one price is required", i))
+		}
+		for j, p := range s.Prices {
+			if p.Amount < 0 || p.Weight <= 0 {
+				errs = append(errs, fmt.Errorf("services[%d].prices[%d]: amount must not be negative and weight must be positive", i, j))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+// Package seed generates realistic, reproducible subscription data for demos
+// and load tests. The same Options always produce the same subscriptions,
+// user ids included.
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testTaskEffectiveMobile/config"
+	"testTaskEffectiveMobile/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Options struct {
+	Users   int
+	PerUser int
+	Seed    uint64
+	// Catalogue defaults to DefaultCatalogue.
+	Catalogue Catalogue
+	// Until is the latest start month; start months spread over SpanMonths
+	// before it, skewed towards recent ones. It is part of the seed: pass a
+	// fixed month to get identical data on different days.
+	Until      models.MonthYearDate
+	SpanMonths int
+	// OpenEnded is the share of subscriptions without an end month.
+	OpenEnded float64
+}
+
+const (
+	DefaultSpanMonths = 36
+	DefaultOpenEnded  = 0.4
+)
+
+func (o Options) Validate() error {
+	var errs []error
+	if o.Users < 0 {
+		errs = append(errs, errors.New("users must not be negative"))
+	}
+	if o.PerUser <= 0 {
+		errs = append(errs, errors.New("subscriptions per user must be positive"))
+	}
+	if o.SpanMonths < 0 {
+		errs = append(errs, errors.New("span must not be negative"))
+	}
+	if o.OpenEnded < 0 || o.OpenEnded > 1 {
+		errs = append(errs, errors.New("open-ended share must be in 0..1"))
+	}
+	if o.Until.IsZero() {
+		errs = append(errs, errors.New("until month is required"))
+	}
+	if len(o.Catalogue.Services) > 0 {
+		errs = append(errs, o.Catalogue.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+// Generate calls fn for Users*PerUser subscriptions, user by user. Each user
+// gets distinct services, so PerUser cannot exceed the catalogue size.
+func Generate(o Options, fn func(models.Subscription) error) error {
+	if len(o.Catalogue.Services) == 0 {
+		o.Catalogue = DefaultCatalogue()
+	}
+	if o.SpanMonths == 0 {
+		o.SpanMonths = DefaultSpanMonths
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if o.PerUser > len(o.Catalogue.Services) {
+		return fmt.Errorf("%d subscriptions per user need at least as many services, the catalogue has %d", o.PerUser, len(o.Catalogue.Services))
+	}
+
+	rng := rand.New(rand.NewPCG(o.Seed, o.Seed^0x9e3779b97f4a7c15))
+	ids := &randReader{rng: rng}
+	until := time.Date(o.Until.Year(), o.Until.Month(), 1, 0, 0, 0, 0, time.UTC)
+	services := o.Catalogue.Services
+
+	weights := make([]int, len(services))
+	for u := 0; u < o.Users; u++ {
+		userID, err := uuid.NewRandomFromReader(ids)
+		if err != nil {
+			return err
+		}
+		for i, s := range services {
+			weights[i] = s.Weight
+		}
+		for n := 0; n < o.PerUser; n++ {
+			i := pick(rng, weights)
+			// Without replacement: a service twice could overlap itself.
+			weights[i] = 0
+			service := services[i]
+
+			// Squaring a uniform draw makes recent start months more likely.
+			back := int(math.Floor(math.Pow(rng.Float64(), 2) * float64(o.SpanMonths+1)))
+			start := until.AddDate(0, -back, 0)
+			s := models.Subscription{
+				ServiceName: service.Name,
+				Price:       service.Prices[pick(rng, priceWeights(service.Prices))].Amount,
+				UserId:      userID,
+				StartDate:   models.MonthYearDate{Time: start},
+			}
+			if rng.Float64() >= o.OpenEnded {
+				// Finite subscriptions last 1..24 months, short ones being more common.
+				months := 1 + int(math.Floor(math.Pow(rng.Float64(), 1.5)*24))
+				end := models.MonthYearDate{Time: start.AddDate(0, months-1, 0)}
+				s.EndDate = &end
+			}
+			if err = fn(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pick returns an index with probability proportional to its weight.
+func pick(rng *rand.Rand, weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	n := rng.IntN(total)
+	for i, w := range weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return len(weights) - 1
+}
+
+func priceWeights(prices []Price) []int {
+	weights := make([]int, len(prices))
+	for i, p := range prices {
+		weights[i] = p.Weight
+	}
+	return weights
+}
+
+// randReader feeds uuid generation from the seeded generator.
+type randReader struct {
+	rng *rand.Rand
+}
+
+func (r *randReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r.rng.Uint32())
+	}
+	return len(p), nil
+}
+
+// FromConfig turns the seed section of the service configuration into Options
+// with start months up to until.
+func FromConfig(cfg config.Seed, until time.Time) (Options, error) {
+	o := Options{
+		Users:      cfg.Users,
+		PerUser:    cfg.PerUser,
+		Seed:       uint64(cfg.Seed),
+		Until:      models.MonthYearDate{Time: until},
+		SpanMonths: DefaultSpanMonths,
+		OpenEnded:  DefaultOpenEnded,
+	}
+	if cfg.Catalogue != "" {
+		catalogue, err := LoadCatalogue(cfg.Catalogue)
+		if err != nil {
+			return Options{}, err
+		}
+		o.Catalogue = catalogue
+	}
+	return o, nil
+}
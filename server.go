@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testTaskEffectiveMobile/certs"
+)
+
+// serve registers the API server, and with TLS the optional plaintext
+// redirect listener, then runs until shutdown.
+func (app *application) serve() error {
+	cfg := app.config
+	errorLog := slog.NewLogLogger(app.logger.Handler(), slog.LevelError)
+
+	s := &http.Server{
+		Addr:         cfg.HTTP.Addr,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+		Handler:      app.routes(),
+		ErrorLog:     errorLog,
+	}
+	// Event streams never go idle on their own.
+	s.RegisterOnShutdown(app.events.Close)
+
+	if !cfg.TLS.Enabled() {
+		app.lifecycle.Serve(s, nil)
+		return app.lifecycle.Run()
+	}
+
+	minVersion, err := certs.ParseVersion(cfg.TLS.MinVersion)
+	if err != nil {
+		return app.lifecycle.Abort(err)
+	}
+	reloader, err := certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile, app.logger)
+	if err != nil {
+		return app.lifecycle.Abort(err)
+	}
+	app.lifecycle.Go("tls-reloader", func(ctx context.Context) {
+		reloader.Run(ctx, cfg.TLS.ReloadInterval)
+	})
+	s.TLSConfig = reloader.TLSConfig(minVersion)
+	s.Protocols = new(http.Protocols)
+	s.Protocols.SetHTTP1(true)
+	s.Protocols.SetHTTP2(true)
+	app.lifecycle.Serve(s, func(ln net.Listener) error {
+		// Certificates come from TLSConfig.GetCertificate.
+		return s.ServeTLS(ln, "", "")
+	})
+
+	if cfg.TLS.RedirectAddr != "" {
+		_, httpsPort, _ := net.SplitHostPort(cfg.HTTP.Addr)
+		app.lifecycle.Serve(&http.Server{
+			Addr:         cfg.TLS.RedirectAddr,
+			ReadTimeout:  cfg.HTTP.ReadTimeout,
+			WriteTimeout: cfg.HTTP.WriteTimeout,
+			IdleTimeout:  cfg.HTTP.IdleTimeout,
+			Handler:      redirectToHTTPS(httpsPort),
+			ErrorLog:     errorLog,
+		}, nil)
+	}
+	return app.lifecycle.Run()
+}
+
+// redirectToHTTPS sends every request to the same host and path on the HTTPS port.
+func redirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,315 @@
+// Package webhooks sends queued subscription events to the registered URLs,
+// signed with HMAC-SHA256, and retries failed deliveries with exponential
+// backoff until they succeed or end up in the dead-letter queue.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testTaskEffectiveMobile/dto"
+	"time"
+)
+
+// Request headers of a delivery.
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// batchSize is how many deliveries are claimed per poll.
+	batchSize = 50
+	// workers is how many deliveries of a batch are sent at the same time.
+	workers = 4
+	// maxBackoff caps the delay between two attempts.
+	maxBackoff = 6 * time.Hour
+	// errorSnippet is how much of a failed response is kept for the delivery log.
+	errorSnippet = 256
+)
+
+// Envelope is the JSON body of a delivery. ID stays the same across retries
+// and redeliveries, so receivers can drop duplicates.
+type Envelope struct {
+	ID        int64           `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sign returns the X-Webhook-Signature of body sent at timestamp (Unix
+// seconds): "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	ErrBadSignature = errors.New("webhook signature mismatch")
+	ErrStale        = errors.New("webhook timestamp outside the tolerance")
+)
+
+// Verify checks the signature headers of a received delivery. Deliveries
+// signed more than tolerance away from now are rejected to limit replays.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if d := now.Sub(time.Unix(timestamp, 0)); d > tolerance || d < -tolerance {
+		return ErrStale
+	}
+	if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(Sign(secret, timestamp, body))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Store is the delivery queue; repositories.WebhooksRepository implements it.
+type Store interface {
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]dto.DueDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, status int) error
+	MarkFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error
+}
+
+type Dispatcher struct {
+	Store Store
+	// Client sends the requests; it should have a timeout and not follow
+	// redirects, as NewClient does.
+	Client *http.Client
+	// MaxAttempts is how many attempts a delivery gets before it is moved
+	// to the dead-letter queue.
+	MaxAttempts int
+	// Backoff is the delay after the first failed attempt; it doubles with
+	// every further one.
+	Backoff time.Duration
+	Logger  *slog.Logger
+}
+
+// NewClient returns a client for deliveries: redirects are not followed, a
+// 3xx answer counts as a failure. Unless allowPrivate is set, it only
+// connects to public addresses, checked after name resolution so that a
+// registered name cannot be pointed at the internal network later, and it
+// ignores proxy settings, as the proxy would connect on its behalf.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer := &net.Dialer{
+			Timeout: 30 * time.Second,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip, err := netip.ParseAddr(host); err != nil || !PublicAddr(ip) {
+					return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+				}
+				return nil
+			},
+		}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// ErrForbiddenAddress is the error of a delivery to an address that is not public.
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// nonPublic are the special-purpose ranges netip has no predicate for.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// PublicAddr reports whether deliveries may go to ip: it is not loopback,
+// private, link-local, multicast, unspecified or otherwise reserved.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublic {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// PublicHost reports whether a URL host may be registered: a name, which is
+// checked again on every connection, or a public address. localhost is not.
+func PublicHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return PublicAddr(ip)
+	}
+	return true
+}
+
+// Run sends due deliveries every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// A full batch means more may be due, poll again right away.
+		for {
+			n, err := d.DispatchDue(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				d.Logger.Error("webhook dispatch failed", "error", err)
+			}
+			if n < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue claims one batch of due deliveries, attempts each of them once
+// and returns how many were claimed.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	lease := 2*d.Client.Timeout + time.Minute
+	due, err := d.Store.ClaimDue(ctx, batchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		jobs = make(chan dto.DueDelivery)
+	)
+	for range min(workers, len(due)) {
+		wg.Go(func() {
+			for delivery := range jobs {
+				if err := d.attempt(ctx, delivery); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	for _, delivery := range due {
+		jobs <- delivery
+	}
+	close(jobs)
+	wg.Wait()
+	return len(due), errors.Join(errs...)
+}
+
+// attempt sends the delivery and records the outcome; the returned error is
+// about recording it, a failed send is not an error here.
+func (d *Dispatcher) attempt(ctx context.Context, delivery dto.DueDelivery) error {
+	status, sendErr := d.Send(ctx, delivery)
+	// The outcome must be recorded even when shutdown interrupts the send.
+	ctx = context.WithoutCancel(ctx)
+	if sendErr == nil {
+		return d.Store.MarkDelivered(ctx, delivery.Id, status)
+	}
+	attempt := delivery.Attempts + 1
+	logger := d.Logger.With("delivery_id", delivery.Id, "event", delivery.Event, "attempt", attempt, "error", sendErr)
+	var retryAt *time.Time
+	if attempt < d.MaxAttempts {
+		at := time.Now().Add(d.backoff(attempt))
+		retryAt = &at
+		logger.Warn("webhook delivery failed, will retry", "retry_at", at)
+	} else {
+		logger.Error("webhook delivery failed, moved to the dead-letter queue")
+	}
+	return d.Store.MarkFailed(ctx, delivery.Id, status, sendErr.Error(), retryAt)
+}
+
+// Send makes one signed delivery attempt and returns the response status, 0
+// when there was no response. Any status outside 2xx is an error.
+func (d *Dispatcher) Send(ctx context.Context, delivery dto.DueDelivery) (int, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.Id,
+		Event:     delivery.Event,
+		CreatedAt: delivery.CreatedAt.UTC(),
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "subscriptions-webhooks/1")
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, errorSnippet))
+	// Drain a little more so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := fmt.Sprintf("unexpected status %s", resp.Status)
+		if text := strings.TrimSpace(string(snippet)); text != "" {
+			msg += ": " + text
+		}
+		return resp.StatusCode, errors.New(msg)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the delay before the attempt after the given failed one:
+// Backoff doubled per failure up to maxBackoff, plus up to 10% jitter.
+func (d *Dispatcher) backoff(failed int) time.Duration {
+	delay := d.Backoff
+	for i := 1; i < failed && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxBackoff)
+	return delay + rand.N(delay/10+1)
+}
@@ -0,0 +1,332 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testTaskEffectiveMobile/dto"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+// memStore is a delivery queue in memory. A delivery stays due until it is
+// delivered or dead-lettered; retry times are recorded, not waited for.
+type memStore struct {
+	mu         sync.Mutex
+	deliveries []*dto.DueDelivery
+	// retryAt is zero for dead-lettered deliveries.
+	retryAt   map[int64]time.Time
+	delivered map[int64]int
+	failures  []failure
+}
+
+type failure struct {
+	id      int64
+	status  int
+	reason  string
+	retryAt *time.Time
+}
+
+func newMemStore(deliveries ...dto.DueDelivery) *memStore {
+	s := &memStore{retryAt: make(map[int64]time.Time), delivered: make(map[int64]int)}
+	for _, d := range deliveries {
+		s.deliveries = append(s.deliveries, &d)
+	}
+	return s
+}
+
+func (s *memStore) ClaimDue(_ context.Context, limit int, _ time.Duration) ([]dto.DueDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []dto.DueDelivery
+	for _, d := range s.deliveries {
+		if len(due) == limit {
+			break
+		}
+		if _, ok := s.delivered[d.Id]; ok {
+			continue
+		}
+		if at, ok := s.retryAt[d.Id]; ok && at.IsZero() {
+			continue // dead-lettered
+		}
+		due = append(due, *d)
+	}
+	return due, nil
+}
+
+func (s *memStore) MarkDelivered(_ context.Context, id int64, status int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delivered[id] = status
+	return nil
+}
+
+func (s *memStore) MarkFailed(_ context.Context, id int64, status int, reason string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{id: id, status: status, reason: reason, retryAt: retryAt})
+	for _, d := range s.deliveries {
+		if d.Id == id {
+			d.Attempts++
+		}
+	}
+	if retryAt == nil {
+		s.retryAt[id] = time.Time{}
+	} else {
+		s.retryAt[id] = *retryAt
+	}
+	return nil
+}
+
+// receiver is a webhook endpoint answering with the given statuses in turn,
+// the last one from then on, after checking every request with Verify.
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	got      []Envelope
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Errorf("read body: %v", err)
+	}
+	if err = Verify(testSecret, r.Header, body, time.Minute, time.Now()); err != nil {
+		rc.t.Errorf("Verify: %v", err)
+	}
+	var e Envelope
+	if err = json.Unmarshal(body, &e); err != nil {
+		rc.t.Errorf("decode body: %v", err)
+	}
+	if got, want := r.Header.Get(HeaderID), strconv.FormatInt(e.ID, 10); got != want {
+		rc.t.Errorf("%s = %q, want %q", HeaderID, got, want)
+	}
+	if got := r.Header.Get(HeaderEvent); got != e.Event {
+		rc.t.Errorf("%s = %q, want %q", HeaderEvent, got, e.Event)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.got = append(rc.got, e)
+	status := rc.statuses[0]
+	if len(rc.statuses) > 1 {
+		rc.statuses = rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+	io.WriteString(w, http.StatusText(status))
+}
+
+func startReceiver(t *testing.T, statuses ...int) (*receiver, *httptest.Server) {
+	rc := &receiver{t: t, statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	return rc, srv
+}
+
+func testDispatcher(store Store, maxAttempts int, backoff time.Duration) *Dispatcher {
+	return &Dispatcher{
+		Store: store,
+		// httptest listens on loopback.
+		Client:      NewClient(5*time.Second, true),
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func testDelivery(url string) dto.DueDelivery {
+	return dto.DueDelivery{
+		Id:        42,
+		URL:       url,
+		Secret:    testSecret,
+		Event:     "subscription.created",
+		Payload:   json.RawMessage(`{"id":7}`),
+		CreatedAt: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestSendIsSignedAndVerifies(t *testing.T) {
+	rc, srv := startReceiver(t, http.StatusNoContent)
+	d := testDispatcher(newMemStore(), 3, time.Second)
+
+	status, err := d.Send(context.Background(), testDelivery(srv.URL))
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", status, http.StatusNoContent)
+	}
+	if len(rc.got) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(rc.got))
+	}
+	if e := rc.got[0]; e.ID != 42 || e.Event != "subscription.created" || string(e.Data) != `{"id":7}` {
+		t.Errorf("envelope = %+v", e)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_750_000_000, 0)
+	body := []byte(`{"id":1}`)
+	signed := func(secret string, at time.Time) http.Header {
+		h := http.Header{}
+		h.Set(HeaderTimestamp, strconv.FormatInt(at.Unix(), 10))
+		h.Set(HeaderSignature, Sign(secret, at.Unix(), body))
+		return h
+	}
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"valid", signed(testSecret, now), body, nil},
+		{"within tolerance", signed(testSecret, now.Add(-4*time.Minute)), body, nil},
+		{"other secret", signed("fedcba9876543210", now), body, ErrBadSignature},
+		{"tampered body", signed(testSecret, now), []byte(`{"id":2}`), ErrBadSignature},
+		{"stale", signed(testSecret, now.Add(-6*time.Minute)), body, ErrStale},
+		{"from the future", signed(testSecret, now.Add(6*time.Minute)), body, ErrStale},
+		{"no timestamp", http.Header{HeaderSignature: {Sign(testSecret, now.Unix(), body)}}, body, ErrBadSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(testSecret, tt.header, tt.body, 5*time.Minute, now); !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatchRetriesServerErrors(t *testing.T) {
+	rc, srv := startReceiver(t, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+	store := newMemStore(testDelivery(srv.URL))
+	d := testDispatcher(store, 5, time.Second)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		start := time.Now()
+		if _, err := d.DispatchDue(context.Background()); err != nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+		if attempt == 3 {
+			break
+		}
+		f := store.failures[attempt-1]
+		if f.status != []int{http.StatusServiceUnavailable, http.StatusBadGateway}[attempt-1] || !strings.Contains(f.reason, strconv.Itoa(f.status)) {
+			t.Errorf("attempt %d recorded as %+v", attempt, f)
+		}
+		// Backoff doubles per failure, plus up to 10% jitter.
+		base := time.Second << (attempt - 1)
+		if f.retryAt == nil {
+			t.Fatalf("attempt %d: no retry scheduled", attempt)
+		}
+		if delay := f.retryAt.Sub(start); delay < base || delay > base+base/10+time.Second {
+			t.Errorf("attempt %d: retry after %s, want about %s", attempt, delay, base)
+		}
+	}
+	if got := store.delivered[42]; got != http.StatusOK {
+		t.Errorf("delivered with status %d, want %d", got, http.StatusOK)
+	}
+	if len(rc.got) != 3 {
+		t.Errorf("receiver got %d requests, want 3", len(rc.got))
+	}
+	for _, e := range rc.got {
+		if e.ID != 42 {
+			t.Errorf("retry sent id %d, want the same id 42", e.ID)
+		}
+	}
+}
+
+func TestDispatchDeadLettersAfterMaxAttempts(t *testing.T) {
+	rc, srv := startReceiver(t, http.StatusInternalServerError)
+	store := newMemStore(testDelivery(srv.URL))
+	d := testDispatcher(store, 3, time.Second)
+
+	for range 5 {
+		if _, err := d.DispatchDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(rc.got) != 3 {
+		t.Errorf("receiver got %d requests, want 3", len(rc.got))
+	}
+	if len(store.failures) != 3 {
+		t.Fatalf("recorded %d failures, want 3", len(store.failures))
+	}
+	for i, f := range store.failures {
+		last := i == len(store.failures)-1
+		if (f.retryAt == nil) != last {
+			t.Errorf("failure %d: retryAt = %v, want a retry only before the last attempt", i+1, f.retryAt)
+		}
+	}
+	if _, ok := store.delivered[42]; ok {
+		t.Error("dead-lettered delivery was marked delivered")
+	}
+}
+
+func TestRedirectIsFailure(t *testing.T) {
+	_, srv := startReceiver(t, http.StatusFound)
+	d := testDispatcher(newMemStore(), 3, time.Second)
+
+	status, err := d.Send(context.Background(), testDelivery(srv.URL))
+	if err == nil || status != http.StatusFound {
+		t.Errorf("Send = %d, %v; want %d and an error", status, err, http.StatusFound)
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	rc, srv := startReceiver(t, http.StatusOK)
+	d := testDispatcher(newMemStore(), 3, time.Second)
+	d.Client = NewClient(5*time.Second, false)
+
+	_, err := d.Send(context.Background(), testDelivery(srv.URL))
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Send to %s = %v, want %v", srv.URL, err, ErrForbiddenAddress)
+	}
+	if len(rc.got) != 0 {
+		t.Errorf("receiver got %d requests, want none", len(rc.got))
+	}
+}
+
+func TestPublicHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"localhost", false},
+		{"api.localhost", false},
+		{"LOCALHOST.", false},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicHost(tt.host); got != tt.want {
+			t.Errorf("PublicHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+	if PublicAddr(netip.MustParseAddr("::ffff:10.0.0.1")) {
+		t.Error("PublicAddr accepts an IPv4-mapped private address")
+	}
+}